@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "alert_rule"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.AlertRuleRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+// Upsert keeps a single grouping rule per user.
+func (m *mongoRepository) Upsert(ctx context.Context, rule *domain.AlertGroupingRule) (*domain.AlertGroupingRule, error) {
+	var (
+		err error
+	)
+
+	filter := bson.M{"user_id": rule.UserID}
+	update := bson.M{
+		"$set": bson.M{
+			"group_by":            rule.GroupBy,
+			"window_seconds":      rule.Window,
+			"rate_limit_per_hour": rule.RateLimit,
+			"renotify_minutes":    rule.Renotify,
+			"updated_at":          rule.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        rule.ID,
+			"created_at": rule.CreatedAt,
+		},
+	}
+
+	_, err = m.Collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return rule, err
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(rule)
+	if err != nil {
+		return rule, err
+	}
+
+	return rule, nil
+}
+
+func (m *mongoRepository) FindByUserID(ctx context.Context, userID string) (*domain.AlertGroupingRule, error) {
+	var (
+		rule domain.AlertGroupingRule
+		err  error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return &rule, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"user_id": idHex}).Decode(&rule)
+	if err != nil {
+		return &rule, err
+	}
+
+	return &rule, nil
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
+)
+
+type AlertHandler struct {
+	AlertUsecase domain.AlertUsecase
+	config       *bootstrap.Config
+}
+
+func NewAlertHandler(cfg *bootstrap.Config, r *gin.RouterGroup, au domain.AlertUsecase) {
+	handler := &AlertHandler{
+		AlertUsecase: au,
+		config:       cfg,
+	}
+	r.POST("/alert/event", handler.IngestEvent)
+
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.PUT("/alert/rule", handler.UpsertRule)
+	protected.GET("/alert/rule", handler.GetRule)
+}
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IngestEvent takes a probe event. It must carry the ingest token of the
+// event's config in the X-Ingest-Token header.
+func (h *AlertHandler) IngestEvent(c *gin.Context) {
+	var event domain.AlertEvent
+	if err := c.ShouldBindJSON(&event); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&event); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err := h.AlertUsecase.Ingest(c, &event, c.GetHeader("X-Ingest-Token"))
+	if errors.Is(err, domain.ErrInvalidIngestToken) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Event accepted"})
+}
+
+func (h *AlertHandler) UpsertRule(c *gin.Context) {
+	var rule domain.AlertGroupingRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&rule); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+	rule.UserID = userID
+	res, err := h.AlertUsecase.UpsertRule(c, &rule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *AlertHandler) GetRule(c *gin.Context) {
+	rule, err := h.AlertUsecase.GetRule(c, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rule)
+}
@@ -0,0 +1,366 @@
+package usecase
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/rabbitmq"
+)
+
+type alertUsecase struct {
+	ruleRepo        domain.AlertRuleRepository
+	configRepo      domain.ConfigRepository
+	onCallUsecase   domain.OnCallUsecase
+	templateUsecase domain.AlertTemplateUsecase
+	incidentUsecase domain.IncidentUsecase
+	notifier        rabbitmq.MQPublisher
+	contextTimeout  time.Duration
+	pipeline        *pipeline
+}
+
+func NewAlertUsecase(r domain.AlertRuleRepository, c domain.ConfigRepository, o domain.OnCallUsecase, t domain.AlertTemplateUsecase, i domain.IncidentUsecase, notifier rabbitmq.MQPublisher, to time.Duration) domain.AlertUsecase {
+	a := &alertUsecase{
+		ruleRepo:        r,
+		configRepo:      c,
+		onCallUsecase:   o,
+		templateUsecase: t,
+		incidentUsecase: i,
+		notifier:        notifier,
+		contextTimeout:  to,
+	}
+	a.pipeline = newPipeline(a.deliver)
+	return a
+}
+
+// Ingest takes a probe event for one region of a site. The token must be
+// the config's ingest token. The event is stored as the region's latest
+// result, along with the certificate expiry it carries, and the site's
+// status is down while any region of it is down, as recorded on its open
+// incident.
+func (a *alertUsecase) Ingest(c context.Context, event *domain.AlertEvent, token string) error {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	config, err := a.configRepo.FindOne(ctx, event.ConfigID.Hex())
+	if err != nil {
+		return errors.New("config not found")
+	}
+	if config.IngestToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(config.IngestToken)) != 1 {
+		return domain.ErrInvalidIngestToken
+	}
+
+	var site *domain.SiteConfig
+	for i := range config.SiteConfig {
+		if config.SiteConfig[i].SiteUrl == event.SiteUrl {
+			site = &config.SiteConfig[i]
+			break
+		}
+	}
+	if site == nil {
+		return errors.New("no site config found with the given site url")
+	}
+
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+
+	previous, err := a.configRepo.SetSiteRegion(ctx, config.ID, site.ID, domain.RegionDetails{
+		Status:       event.Status == domain.AlertStatusUp,
+		Region:       event.Region,
+		ResponseTime: event.OccurredAt,
+		Latency:      event.Latency,
+		Since:        event.OccurredAt,
+	})
+	if err != nil {
+		return err
+	}
+	expires := event.CertificateExpiresAt
+	if expires != nil && (site.CertificateExpiresAt == nil || !expires.Equal(*site.CertificateExpiresAt)) {
+		err = a.configRepo.SetCertificateExpiry(ctx, config.ID, site.ID, *expires)
+		if err != nil {
+			return err
+		}
+	}
+
+	rule, err := a.GetRule(ctx, config.UserID.Hex())
+	if err != nil {
+		return err
+	}
+
+	pending := pendingEvent{event: *event, config: config, site: *site}
+	changed := transition(&pending, previous)
+	if !changed && event.Status == domain.AlertStatusDown {
+		return nil
+	}
+
+	// Up events always reach the incident: the region may have no earlier
+	// result, such as after the site's url changed, while its incident is
+	// still open.
+	pending.incident, err = a.incidentUsecase.Track(ctx, config, event)
+	if err != nil {
+		return err
+	}
+
+	status := domain.SiteUp
+	if pending.incident != nil && pending.incident.Status == domain.IncidentOpen {
+		status = domain.SiteDown
+	}
+	if site.Status != status {
+		err = a.configRepo.SetSiteStatus(ctx, config.ID, site.SiteUrl, status)
+		if err != nil {
+			return err
+		}
+	}
+
+	resolved := pending.incident != nil && pending.incident.Status == domain.IncidentResolved
+	if !changed && !resolved {
+		return nil
+	}
+	if !changed {
+		pending.duration = event.OccurredAt.Sub(pending.incident.StartedAt)
+	}
+
+	a.pipeline.enqueue(pending, rule)
+
+	return nil
+}
+
+func (a *alertUsecase) UpsertRule(c context.Context, rule *domain.AlertGroupingRule) (*domain.AlertGroupingRule, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	rule.ID = primitive.NewObjectID()
+	rule.CreatedAt = time.Now()
+	rule.UpdatedAt = time.Now()
+
+	res, err := a.ruleRepo.Upsert(ctx, rule)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// GetRule falls back to the default rule for users who never configured one.
+func (a *alertUsecase) GetRule(c context.Context, userID string) (*domain.AlertGroupingRule, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.ruleRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return domain.DefaultAlertGroupingRule(idHex), nil
+	}
+
+	return res, nil
+}
+
+// deliver sends one summarized notification per recipient for a flushed
+// group. Failures of acknowledged or snoozed incidents are left out.
+func (a *alertUsecase) deliver(group *alertGroup) {
+	ctx, cancel := context.WithTimeout(context.Background(), a.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	events := []pendingEvent{}
+	for _, e := range group.events {
+		if e.event.Status == domain.AlertStatusDown && e.incident != nil && e.incident.Silenced(now) {
+			continue
+		}
+		events = append(events, e)
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	data := templateData(&events[0])
+	if len(events) > 1 {
+		for i := range events {
+			data.Events = append(data.Events, templateData(&events[i]))
+		}
+	}
+
+	configs := []*domain.ConfigDetails{}
+	seen := map[primitive.ObjectID]bool{}
+	for _, e := range events {
+		if !seen[e.config.ID] {
+			seen[e.config.ID] = true
+			configs = append(configs, e.config)
+		}
+	}
+
+	a.notify(ctx, group.userID, group.rule, configs, events[0].incident, data, len(events))
+}
+
+// Renotify reminds recipients about open incidents nobody has acknowledged,
+// at the interval configured in the owner's grouping rule.
+func (a *alertUsecase) Renotify(c context.Context) error {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	incidents, err := a.incidentUsecase.GetOpenUnacknowledged(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for i := range incidents {
+		incident := &incidents[i]
+		if incident.Silenced(now) {
+			continue
+		}
+
+		rule, err := a.GetRule(ctx, incident.UserID.Hex())
+		if err != nil || rule.Renotify == 0 {
+			continue
+		}
+		if now.Sub(incident.LastNotifiedAt) < time.Duration(rule.Renotify)*time.Minute {
+			continue
+		}
+
+		config, err := a.configRepo.FindOne(ctx, incident.ConfigID.Hex())
+		if err != nil {
+			continue
+		}
+
+		data := domain.AlertTemplateData{
+			Status:     domain.AlertStatusDown,
+			Site:       domain.AlertSite{URL: incident.SiteUrl},
+			Config:     domain.AlertConfig{ID: config.ID.Hex(), Name: config.Name},
+			Error:      incident.Error,
+			Duration:   now.Sub(incident.StartedAt),
+			OccurredAt: incident.StartedAt,
+		}
+		for _, site := range config.SiteConfig {
+			if site.SiteUrl == incident.SiteUrl {
+				data.Regions = regionData(&site, incident.Regions)
+			}
+		}
+
+		a.notify(ctx, incident.UserID, rule, []*domain.ConfigDetails{config}, incident, data, 1)
+
+		err = a.incidentUsecase.MarkNotified(ctx, incident.ID, now)
+		if err != nil {
+			log.Println("alert: marking incident notified:", err)
+		}
+	}
+
+	return nil
+}
+
+// notify resolves the configs' notification targets and publishes one
+// rendered message per distinct recipient, honouring the channel rate limit.
+func (a *alertUsecase) notify(ctx context.Context, userID primitive.ObjectID, rule *domain.AlertGroupingRule, configs []*domain.ConfigDetails, incident *domain.Incident, data domain.AlertTemplateData, eventCount int) {
+	now := time.Now()
+	seen := map[string]bool{}
+	for _, config := range configs {
+		for _, target := range config.NotificationTargets {
+			recipient, err := a.onCallUsecase.ResolveTarget(ctx, &target, config.UserID.Hex(), now)
+			if err != nil {
+				log.Println("alert: resolving notification target:", err)
+				continue
+			}
+
+			key := string(recipient.Channel) + "|" + recipient.Address
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			ok, suppressed := a.pipeline.allow(userID.Hex()+"|"+string(recipient.Channel), rule.RateLimit)
+			if !ok {
+				continue
+			}
+
+			recipientData := data
+			if incident != nil {
+				links := a.incidentUsecase.Links(incident, recipient.UserID)
+				recipientData.IncidentLink = links.Incident
+				recipientData.AcknowledgeLink = links.Acknowledge
+				recipientData.SnoozeLink = links.Snooze
+			}
+
+			message, err := a.templateUsecase.Render(ctx, userID.Hex(), recipient.Channel, &recipientData)
+			if err != nil {
+				log.Println("alert: rendering notification:", err)
+				continue
+			}
+
+			notification := domain.Notification{
+				ID:         primitive.NewObjectID(),
+				UserID:     userID,
+				ConfigID:   config.ID,
+				Recipient:  *recipient,
+				Subject:    message.Subject,
+				Body:       message.Body,
+				EventCount: eventCount,
+				Suppressed: suppressed,
+				CreatedAt:  now,
+			}
+
+			notificationJson, err := json.Marshal(notification)
+			if err != nil {
+				log.Println("alert: encoding notification:", err)
+				continue
+			}
+
+			err = a.notifier.Publish(notificationJson)
+			if err != nil {
+				log.Println("alert: publishing notification:", err)
+			}
+		}
+	}
+}
+
+func templateData(e *pendingEvent) domain.AlertTemplateData {
+	data := domain.AlertTemplateData{
+		Status:     e.event.Status,
+		Site:       domain.AlertSite{URL: e.event.SiteUrl},
+		Config:     domain.AlertConfig{ID: e.config.ID.Hex(), Name: e.config.Name},
+		Error:      e.event.Error,
+		Duration:   e.duration,
+		OccurredAt: e.event.OccurredAt,
+	}
+
+	down := []string{}
+	if e.incident != nil {
+		down = e.incident.Regions
+	} else if e.event.Status == domain.AlertStatusDown {
+		down = []string{e.event.Region}
+	}
+	data.Regions = regionData(&e.site, down)
+
+	return data
+}
+
+func regionData(site *domain.SiteConfig, down []string) []domain.AlertRegion {
+	regions := []domain.AlertRegion{}
+	for _, region := range site.RegionDetails {
+		status := true
+		for _, d := range down {
+			if d == region.Region {
+				status = false
+			}
+		}
+		regions = append(regions, domain.AlertRegion{
+			Region:    region.Region,
+			Status:    status,
+			CheckedAt: region.ResponseTime,
+		})
+	}
+	return regions
+}
@@ -0,0 +1,147 @@
+package usecase
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+)
+
+type pendingEvent struct {
+	event    domain.AlertEvent
+	config   *domain.ConfigDetails
+	site     domain.SiteConfig
+	incident *domain.Incident
+	duration time.Duration
+}
+
+type alertGroup struct {
+	userID primitive.ObjectID
+	rule   *domain.AlertGroupingRule
+	events []pendingEvent
+}
+
+type rateWindow struct {
+	start      time.Time
+	sent       int
+	suppressed int
+}
+
+// pipeline is the in-memory stage between event ingestion and delivery. It
+// batches events into groups that are flushed once their window closes, and
+// keeps the per-channel hourly send counters.
+type pipeline struct {
+	mu     sync.Mutex
+	groups map[string]*alertGroup
+	limits map[string]*rateWindow
+	flush  func(group *alertGroup)
+}
+
+func newPipeline(flush func(group *alertGroup)) *pipeline {
+	return &pipeline{
+		groups: map[string]*alertGroup{},
+		limits: map[string]*rateWindow{},
+		flush:  flush,
+	}
+}
+
+// transition compares the event with the result its region reported
+// before, and returns false when it repeats that result's status or is an
+// up event for a region with no earlier result. The results are stored on
+// the site, so this holds across restarts and servers, and goes away with
+// the site.
+func transition(e *pendingEvent, previous *domain.RegionDetails) bool {
+	up := e.event.Status == domain.AlertStatusUp
+	if previous == nil {
+		return !up
+	}
+	if previous.Status == up {
+		return false
+	}
+	if !previous.Since.IsZero() {
+		e.duration = e.event.OccurredAt.Sub(previous.Since)
+	}
+
+	return true
+}
+
+// enqueue adds the event to its group, opening the group's window if needed.
+func (p *pipeline) enqueue(e pendingEvent, rule *domain.AlertGroupingRule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	groupKey := groupKey(rule, &e)
+	group, ok := p.groups[groupKey]
+	if !ok {
+		group = &alertGroup{userID: e.config.UserID, rule: rule}
+		p.groups[groupKey] = group
+		time.AfterFunc(time.Duration(rule.Window)*time.Second, func() {
+			p.mu.Lock()
+			delete(p.groups, groupKey)
+			p.mu.Unlock()
+			p.flush(group)
+		})
+	}
+
+	for i, queued := range group.events {
+		if queued.config.ID == e.config.ID && queued.event.SiteUrl == e.event.SiteUrl && queued.event.Region == e.event.Region {
+			group.events[i] = e
+			return
+		}
+	}
+	group.events = append(group.events, e)
+}
+
+// allow counts a send against the hourly limit for key and reports how many
+// sends were suppressed since the last one that went out.
+func (p *pipeline) allow(key string, limit int) (bool, int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	window, ok := p.limits[key]
+	if !ok || time.Since(window.start) >= time.Hour {
+		suppressed := 0
+		if ok {
+			suppressed = window.suppressed
+		}
+		window = &rateWindow{start: time.Now(), suppressed: suppressed}
+		p.limits[key] = window
+	}
+
+	if limit > 0 && window.sent >= limit {
+		window.suppressed++
+		return false, 0
+	}
+
+	window.sent++
+	suppressed := window.suppressed
+	window.suppressed = 0
+	return true, suppressed
+}
+
+func groupKey(rule *domain.AlertGroupingRule, e *pendingEvent) string {
+	parts := []string{e.config.UserID.Hex(), e.event.Status}
+	for _, by := range rule.GroupBy {
+		switch by {
+		case domain.GroupByConfig:
+			parts = append(parts, "config="+e.config.ID.Hex())
+		case domain.GroupByTag:
+			tags := append([]string{}, e.site.Tags...)
+			sort.Strings(tags)
+			parts = append(parts, "tag="+strings.Join(tags, ","))
+		case domain.GroupByHost:
+			host := e.event.SiteUrl
+			if u, err := url.Parse(e.event.SiteUrl); err == nil && u.Hostname() != "" {
+				host = u.Hostname()
+			}
+			parts = append(parts, "host="+host)
+		case domain.GroupByRegion:
+			parts = append(parts, "region="+e.event.Region)
+		}
+	}
+	return strings.Join(parts, "|")
+}
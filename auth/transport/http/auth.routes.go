@@ -1,6 +1,7 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -105,6 +106,10 @@ func (ah *AuthHandler) SignUp(ctx *gin.Context) {
 	}
 
 	err = ah.AuthUsecase.CreateUser(ctx, &user)
+	if errors.Is(err, domain.ErrEmailTaken) {
+		ctx.JSON(http.StatusConflict, domain.ErrorResponse{Message: "user already exists "})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, domain.ErrorResponse{Message: err.Error()})
 		return
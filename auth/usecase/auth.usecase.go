@@ -2,6 +2,7 @@ package usecase
 
 import (
 	"context"
+	"log"
 	"time"
 
 	"spectator.main/domain"
@@ -10,21 +11,33 @@ import (
 
 type authUsecase struct {
 	userRepo       domain.UserRepository
+	userUsecase    domain.UserUsecase
 	contextTimeout time.Duration
 }
 
-func NewAuthUsecase(userRepository domain.UserRepository, timeout time.Duration) domain.AuthUsecase {
+func NewAuthUsecase(userRepository domain.UserRepository, userUsecase domain.UserUsecase, timeout time.Duration) domain.AuthUsecase {
 	return &authUsecase{
 		userRepo:       userRepository,
+		userUsecase:    userUsecase,
 		contextTimeout: timeout,
 	}
 }
 
+// CreateUser stores the new account and mails it a link to verify its
+// email. A link that could not be sent can be asked for again, so that
+// does not fail the sign-up.
 func (au *authUsecase) CreateUser(c context.Context, user *domain.User) error {
 	ctx, cancel := context.WithTimeout(c, au.contextTimeout)
 	defer cancel()
 	_, err := au.userRepo.InsertOne(ctx, user)
-	return err
+	if err != nil {
+		return err
+	}
+	err = au.userUsecase.SendVerification(ctx, user.ID.Hex())
+	if err != nil {
+		log.Println("sending the verification email of user", user.ID.Hex(), "failed:", err)
+	}
+	return nil
 }
 
 func (au *authUsecase) GetUserByEmail(c context.Context, email string) (*domain.User, error) {
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"spectator.main/domain"
+	"spectator.main/internals/badge"
+	"spectator.main/internals/bootstrap"
+)
+
+const (
+	defaultPeriod = 30 * 24 * time.Hour
+	maxPeriod     = 90 * 24 * time.Hour
+	maxLabel      = 64
+)
+
+type BadgeHandler struct {
+	BadgeUsecase domain.BadgeUsecase
+	config       *bootstrap.Config
+}
+
+// NewBadgeHandler mounts the badge endpoints on r without authentication; the
+// badge token in the path is the only credential.
+func NewBadgeHandler(cfg *bootstrap.Config, r *gin.RouterGroup, bu domain.BadgeUsecase) {
+	handler := &BadgeHandler{
+		BadgeUsecase: bu,
+		config:       cfg,
+	}
+	r.GET("/:token/uptime.svg", handler.UptimeBadge)
+	r.GET("/:token/status.svg", handler.StatusBadge)
+}
+
+// parsePeriod accepts Go durations ("24h") as well as whole days ("30d").
+func parsePeriod(s string) (time.Duration, error) {
+	if s == "" {
+		return defaultPeriod, nil
+	}
+	var (
+		period time.Duration
+		err    error
+	)
+	if strings.HasSuffix(s, "d") {
+		var days int
+		days, err = strconv.Atoi(strings.TrimSuffix(s, "d"))
+		period = time.Duration(days) * 24 * time.Hour
+	} else {
+		period, err = time.ParseDuration(s)
+	}
+	if err != nil || period <= 0 || period > maxPeriod {
+		return 0, fmt.Errorf("period must be between 1h and %dd", int(maxPeriod.Hours()/24))
+	}
+	return period, nil
+}
+
+func (h *BadgeHandler) UptimeBadge(c *gin.Context) {
+	period, err := parsePeriod(c.Query("period"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.BadgeUsecase.Uptime(c, c.Param("token"), period)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("period") != "" {
+		res.Label += " " + c.Query("period")
+	}
+	h.render(c, res, 5*time.Minute)
+}
+
+func (h *BadgeHandler) StatusBadge(c *gin.Context) {
+	res, err := h.BadgeUsecase.Status(c, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	h.render(c, res, time.Minute)
+}
+
+func (h *BadgeHandler) render(c *gin.Context, b *domain.Badge, maxAge time.Duration) {
+	if label, ok := c.GetQuery("label"); ok {
+		if len(label) > maxLabel {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("label must be at most %d characters", maxLabel)})
+			return
+		}
+		b.Label = label
+	}
+	style := c.DefaultQuery("style", badge.Styles[0])
+	if !badge.IsStyle(style) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "style must be one of " + strings.Join(badge.Styles, ", ")})
+		return
+	}
+
+	svg := badge.SVG(b, style)
+	sum := sha1.Sum(svg)
+	etag := `"` + hex.EncodeToString(sum[:]) + `"`
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d, s-maxage=%d", int(maxAge.Seconds()), int(maxAge.Seconds())))
+	c.Header("ETag", etag)
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			if strings.TrimSpace(candidate) == etag || strings.TrimSpace(candidate) == "*" {
+				c.Status(http.StatusNotModified)
+				return
+			}
+		}
+	}
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", svg)
+}
@@ -0,0 +1,94 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"spectator.main/domain"
+	"spectator.main/internals/badge"
+	"spectator.main/internals/uptime"
+)
+
+type badgeUsecase struct {
+	configRepo     domain.ConfigRepository
+	incidentRepo   domain.IncidentRepository
+	contextTimeout time.Duration
+}
+
+func NewBadgeUsecase(c domain.ConfigRepository, i domain.IncidentRepository, to time.Duration) domain.BadgeUsecase {
+	return &badgeUsecase{
+		configRepo:     c,
+		incidentRepo:   i,
+		contextTimeout: to,
+	}
+}
+
+func (b *badgeUsecase) site(ctx context.Context, token string) (*domain.ConfigDetails, *domain.SiteConfig, error) {
+	config, err := b.configRepo.FindByBadgeToken(ctx, token)
+	if err != nil {
+		return nil, nil, errors.New("badge not found")
+	}
+	for i := range config.SiteConfig {
+		if config.SiteConfig[i].BadgeToken == token {
+			return config, &config.SiteConfig[i], nil
+		}
+	}
+	return nil, nil, errors.New("badge not found")
+}
+
+func (b *badgeUsecase) Uptime(c context.Context, token string, period time.Duration) (*domain.Badge, error) {
+
+	ctx, cancel := context.WithTimeout(c, b.contextTimeout)
+	defer cancel()
+
+	config, site, err := b.site(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	from := now.Add(-period)
+	if config.CreatedAt.After(from) {
+		from = config.CreatedAt
+	}
+
+	incidents, err := b.incidentRepo.GetOverlapping(ctx, config.UserID, from, now)
+	if err != nil {
+		return nil, err
+	}
+
+	// Round down so a single failed check never shows as 100%.
+	percent := math.Floor(uptime.Percent(uptime.ForSite(incidents, config.ID.Hex(), site.SiteUrl), from, now)*100) / 100
+
+	return &domain.Badge{
+		Label:   "uptime",
+		Message: strconv.FormatFloat(percent, 'f', -1, 64) + "%",
+		Color:   badge.UptimeColor(percent),
+	}, nil
+}
+
+func (b *badgeUsecase) Status(c context.Context, token string) (*domain.Badge, error) {
+
+	ctx, cancel := context.WithTimeout(c, b.contextTimeout)
+	defer cancel()
+
+	_, site, err := b.site(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	// The status is the one alert ingest stores for the site; a site the
+	// probes have not reported on yet is unknown.
+	res := &domain.Badge{Label: "status", Message: "unknown", Color: badge.ColorGrey}
+	switch site.Status {
+	case domain.SiteUp:
+		res.Message, res.Color = "up", badge.ColorBrightGreen
+	case domain.SiteDown:
+		res.Message, res.Color = "down", badge.ColorRed
+	}
+
+	return res, nil
+}
@@ -0,0 +1,63 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/ical"
+	"spectator.main/internals/middleware"
+)
+
+type CalendarHandler struct {
+	CalendarUsecase domain.CalendarUsecase
+	config          *bootstrap.Config
+}
+
+// NewCalendarHandler mounts the token management API on r and the .ics
+// feeds, keyed by each user's calendar token, on public so calendar apps
+// can subscribe without a login.
+func NewCalendarHandler(cfg *bootstrap.Config, r *gin.RouterGroup, public *gin.RouterGroup, cu domain.CalendarUsecase) {
+	handler := &CalendarHandler{
+		CalendarUsecase: cu,
+		config:          cfg,
+	}
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.POST("/calendar/token", handler.RotateToken)
+
+	public.GET("/:token/calendar.ics", handler.Calendar)
+}
+
+func (h *CalendarHandler) Calendar(c *gin.Context) {
+	cal, err := h.CalendarUsecase.Calendar(c, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Header("Content-Disposition", `inline; filename="spectator.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", ical.Encode(cal, time.Now()))
+}
+
+func (h *CalendarHandler) RotateToken(c *gin.Context) {
+	token, err := h.CalendarUsecase.RotateToken(c, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	url := strings.TrimSuffix(h.config.PublicURL, "/") + "/calendar/" + token + "/calendar.ics"
+	webcal := url
+	if i := strings.Index(url, "://"); i >= 0 {
+		webcal = "webcal" + url[i:]
+	}
+	c.JSON(http.StatusOK, domain.CalendarTokenResponse{
+		CalendarToken: token,
+		URL:           url,
+		WebcalURL:     webcal,
+	})
+}
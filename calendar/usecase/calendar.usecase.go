@@ -0,0 +1,161 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"time"
+
+	"spectator.main/domain"
+	tokenutil "spectator.main/internals/util"
+)
+
+const (
+	// pastDays is how long finished maintenance stays on the calendar.
+	pastDays = 30
+
+	maintenanceAlarm = time.Hour
+	certificateAlarm = 7 * 24 * time.Hour
+)
+
+type calendarUsecase struct {
+	userRepo        domain.UserRepository
+	configRepo      domain.ConfigRepository
+	maintenanceRepo domain.MaintenanceRepository
+	contextTimeout  time.Duration
+	publicURL       string
+}
+
+func NewCalendarUsecase(u domain.UserRepository, c domain.ConfigRepository, m domain.MaintenanceRepository, to time.Duration, publicURL string) domain.CalendarUsecase {
+	return &calendarUsecase{
+		userRepo:        u,
+		configRepo:      c,
+		maintenanceRepo: m,
+		contextTimeout:  to,
+		publicURL:       strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+// Calendar lists the maintenance windows and upcoming certificate expiries
+// of the user holding the calendar token.
+func (cu *calendarUsecase) Calendar(c context.Context, token string) (*domain.Calendar, error) {
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	if token == "" {
+		return nil, errors.New("calendar not found")
+	}
+	user, err := cu.userRepo.FindByCalendarToken(ctx, token)
+	if err != nil {
+		return nil, errors.New("calendar not found")
+	}
+
+	maintenances, err := cu.maintenanceRepo.GetByUserID(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	configs, err := cu.configRepo.GetAllByUserID(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	userLoc, err := time.LoadLocation(user.TimeZone)
+	if err != nil {
+		userLoc = time.UTC
+	}
+
+	cal := &domain.Calendar{
+		Name:        "Spectator: " + user.Name,
+		Description: "Scheduled maintenance and TLS certificate expiries",
+		TimeZone:    userLoc.String(),
+		Events:      []domain.CalendarEvent{},
+	}
+
+	for i := range maintenances {
+		m := &maintenances[i]
+		if _, until := m.Window(now); until.Before(now.AddDate(0, 0, -pastDays)) {
+			continue
+		}
+		cal.Events = append(cal.Events, domain.CalendarEvent{
+			UID:         "maintenance-" + m.ID.Hex() + "@" + cu.host(),
+			Summary:     "Maintenance: " + m.Title,
+			Description: maintenanceDescription(m),
+			URL:         cu.publicURL + "/api/v1/maintenance/" + m.ID.Hex(),
+			Start:       m.ScheduledFor,
+			End:         m.ScheduledUntil,
+			TimeZone:    m.Location().String(),
+			Recurrence:  m.Recurrence,
+			Updated:     m.UpdatedAt,
+			Alarm:       maintenanceAlarm,
+		})
+	}
+
+	for _, config := range configs {
+		for _, site := range config.SiteConfig {
+			expires := site.CertificateExpiresAt
+			if expires == nil || expires.Before(now) {
+				continue
+			}
+			// Keyed by site, not expiry, so a renewed certificate moves the
+			// event instead of adding another.
+			day := expires.In(userLoc)
+			cal.Events = append(cal.Events, domain.CalendarEvent{
+				UID:         "certificate-" + site.ID.Hex() + "@" + cu.host(),
+				Summary:     "TLS certificate expires: " + site.SiteUrl,
+				Description: "The TLS certificate of " + site.SiteUrl + " (" + config.Name + ") expires at " + expires.UTC().Format(time.RFC1123) + ".",
+				URL:         site.SiteUrl,
+				Start:       time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+				AllDay:      true,
+				Updated:     config.UpdatedAt,
+				Alarm:       certificateAlarm,
+			})
+		}
+	}
+
+	return cal, nil
+}
+
+func (cu *calendarUsecase) RotateToken(c context.Context, userID string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(c, cu.contextTimeout)
+	defer cancel()
+
+	token, err := tokenutil.CreateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = cu.userRepo.SetCalendarToken(ctx, token, userID)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// host names the UID domain, keeping UIDs globally unique as RFC 5545 asks.
+func (cu *calendarUsecase) host() string {
+	u, err := url.Parse(cu.publicURL)
+	if err != nil || u.Host == "" {
+		return "spectator"
+	}
+	return u.Host
+}
+
+func maintenanceDescription(m *domain.Maintenance) string {
+	if len(m.Sites) == 0 {
+		return m.Description
+	}
+	var b strings.Builder
+	if m.Description != "" {
+		b.WriteString(m.Description + "\n\n")
+	}
+	b.WriteString("Affected sites:")
+	for _, site := range m.Sites {
+		b.WriteString("\n- " + site.SiteUrl)
+	}
+	return b.String()
+}
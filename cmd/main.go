@@ -1,15 +1,47 @@
 package main
 
 import (
+	"context"
+	"log"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	_alertRepo "spectator.main/alert/repository/mongo_repository"
+	_alertHandler "spectator.main/alert/transport/http"
+	_alertUsecase "spectator.main/alert/usecase"
 	_authHandler "spectator.main/auth/transport/http"
 	_authUsecase "spectator.main/auth/usecase"
+	_badgeHandler "spectator.main/badge/transport/http"
+	_badgeUsecase "spectator.main/badge/usecase"
+	_calendarHandler "spectator.main/calendar/transport/http"
+	_calendarUsecase "spectator.main/calendar/usecase"
 	_configRepo "spectator.main/config/repository/mongo_repository"
 	_configHandler "spectator.main/config/transport/http"
 	_configUsecase "spectator.main/config/usecase"
+	_feedHandler "spectator.main/feed/transport/http"
+	_feedUsecase "spectator.main/feed/usecase"
+	_incidentRepo "spectator.main/incident/repository/mongo_repository"
+	_incidentHandler "spectator.main/incident/transport/http"
+	_incidentUsecase "spectator.main/incident/usecase"
 	"spectator.main/internals/bootstrap"
+	_maintenanceRepo "spectator.main/maintenance/repository/mongo_repository"
+	_maintenanceHandler "spectator.main/maintenance/transport/http"
+	_maintenanceUsecase "spectator.main/maintenance/usecase"
+	_onCallRepo "spectator.main/oncall/repository/mongo_repository"
+	_onCallHandler "spectator.main/oncall/transport/http"
+	_onCallUsecase "spectator.main/oncall/usecase"
+	_reportRepo "spectator.main/report/repository/mongo_repository"
+	_reportHandler "spectator.main/report/transport/http"
+	_reportUsecase "spectator.main/report/usecase"
+	_statusPageRepo "spectator.main/statuspage/repository/mongo_repository"
+	_statusPageHandler "spectator.main/statuspage/transport/http"
+	_statusPageUsecase "spectator.main/statuspage/usecase"
+	_subscriptionRepo "spectator.main/subscription/repository/mongo_repository"
+	_subscriptionHandler "spectator.main/subscription/transport/http"
+	_subscriptionUsecase "spectator.main/subscription/usecase"
+	_templateRepo "spectator.main/template/repository/mongo_repository"
+	_templateHandler "spectator.main/template/transport/http"
+	_templateUsecase "spectator.main/template/usecase"
 	_userRepo "spectator.main/user/repository/mongo_repository"
 	_userHandler "spectator.main/user/transport/http"
 	_userUsecase "spectator.main/user/usecase"
@@ -31,17 +63,85 @@ func main() {
 
 	rabbitMQ := app.RabbitMQ
 
+	notifier := app.Notifier
+
 	ginRouter := router.Group("api/v1")
 
+	publicRouter := router.Group("status")
+	badgeRouter := router.Group("badge")
+	feedRouter := router.Group("feed")
+	calendarRouter := router.Group("calendar")
+
 	userRepo := _userRepo.NewMongoRepository(database)
-	userUseCase := _userUsecase.NewUserUsecase(userRepo, timeoutContext)
-	_userHandler.NewUserHandler(ginRouter, userUseCase)
-	authUseCase := _authUsecase.NewAuthUsecase(userRepo, timeoutContext)
+	if err := userRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("creating user indexes: %v", err)
+	}
+	userUseCase := _userUsecase.NewUserUsecase(userRepo, notifier, timeoutContext, config.PublicURL, config.LinkSecret, config.LinkExpiryHour)
+	_userHandler.NewUserHandler(config, ginRouter, userUseCase)
+	authUseCase := _authUsecase.NewAuthUsecase(userRepo, userUseCase, timeoutContext)
 	_authHandler.NewAuthHandler(config, ginRouter, authUseCase)
 
+	onCallRepo := _onCallRepo.NewMongoRepository(database)
+	onCallUseCase := _onCallUsecase.NewOnCallUsecase(onCallRepo, userRepo, timeoutContext)
+	_onCallHandler.NewOnCallHandler(config, ginRouter, onCallUseCase)
+
 	configRepo := _configRepo.NewMongoRepository(database)
-	configUseCase := _configUsecase.NewConfigUsecase(configRepo, userRepo, timeoutContext, rabbitMQ)
+	incidentRepo := _incidentRepo.NewMongoRepository(database)
+	maintenanceRepo := _maintenanceRepo.NewMongoRepository(database)
+	statusPageRepo := _statusPageRepo.NewMongoRepository(database)
+	configRevisionRepo := _configRepo.NewRevisionMongoRepository(database)
+	configOutboxRepo := _configRepo.NewOutboxMongoRepository(database)
+	configUseCase := _configUsecase.NewConfigUsecase(configRepo, configRevisionRepo, userRepo, incidentRepo, maintenanceRepo, statusPageRepo, configOutboxRepo, onCallUseCase, timeoutContext, rabbitMQ)
 	_configHandler.NewConfigHandler(config, ginRouter, configUseCase)
+	if err := configRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("creating config and site indexes: %v", err)
+	}
+	if err := configRevisionRepo.EnsureIndexes(context.Background()); err != nil {
+		log.Printf("creating config revision indexes: %v", err)
+	}
+
+	templateRepo := _templateRepo.NewMongoRepository(database)
+	templateUseCase := _templateUsecase.NewAlertTemplateUsecase(templateRepo, userRepo, timeoutContext)
+	_templateHandler.NewAlertTemplateHandler(config, ginRouter, templateUseCase)
+
+	// The status page handler guards publicRouter, so it is wired before
+	// anything else registers public routes.
+	statusPageVisitRepo := _statusPageRepo.NewVisitMongoRepository(database)
+	statusPageUseCase := _statusPageUsecase.NewStatusPageUsecase(statusPageRepo, configRepo, incidentRepo, maintenanceRepo, userRepo, statusPageVisitRepo, timeoutContext)
+	_statusPageHandler.NewStatusPageHandler(config, ginRouter, publicRouter, statusPageUseCase)
+
+	subscriptionRepo := _subscriptionRepo.NewMongoRepository(database)
+	subscriptionDeliveryRepo := _subscriptionRepo.NewDeliveryMongoRepository(database)
+	subscriptionUseCase := _subscriptionUsecase.NewSubscriptionUsecase(subscriptionRepo, subscriptionDeliveryRepo, statusPageRepo, notifier, timeoutContext, config.PublicURL, config.LinkSecret, config.LinkExpiryHour, config.SubscriptionRatePerMinute)
+	_subscriptionHandler.NewSubscriptionHandler(config, ginRouter, publicRouter, subscriptionUseCase)
+
+	incidentUseCase := _incidentUsecase.NewIncidentUsecase(incidentRepo, configRepo, statusPageRepo, subscriptionUseCase, timeoutContext, config.PublicURL, config.LinkSecret, config.LinkExpiryHour)
+	_incidentHandler.NewIncidentHandler(config, ginRouter, incidentUseCase)
+
+	alertRepo := _alertRepo.NewMongoRepository(database)
+	alertUseCase := _alertUsecase.NewAlertUsecase(alertRepo, configRepo, onCallUseCase, templateUseCase, incidentUseCase, notifier, timeoutContext)
+	_alertHandler.NewAlertHandler(config, ginRouter, alertUseCase)
+
+	reportRepo := _reportRepo.NewMongoRepository(database)
+	reportUseCase := _reportUsecase.NewReportUsecase(reportRepo, configRepo, incidentRepo, userRepo, onCallUseCase, notifier, timeoutContext)
+	_reportHandler.NewReportHandler(config, ginRouter, reportUseCase)
+
+	maintenanceUseCase := _maintenanceUsecase.NewMaintenanceUsecase(maintenanceRepo, configRepo, userRepo, timeoutContext)
+	_maintenanceHandler.NewMaintenanceHandler(config, ginRouter, maintenanceUseCase)
+
+	feedUseCase := _feedUsecase.NewFeedUsecase(statusPageUseCase, configRepo, incidentRepo, maintenanceRepo, timeoutContext, config.PublicURL)
+	_feedHandler.NewFeedHandler(config, feedRouter, publicRouter, feedUseCase)
+
+	badgeUseCase := _badgeUsecase.NewBadgeUsecase(configRepo, incidentRepo, timeoutContext)
+	_badgeHandler.NewBadgeHandler(config, badgeRouter, badgeUseCase)
+
+	calendarUseCase := _calendarUsecase.NewCalendarUsecase(userRepo, configRepo, maintenanceRepo, timeoutContext, config.PublicURL)
+	_calendarHandler.NewCalendarHandler(config, ginRouter, calendarRouter, calendarUseCase)
+
+	bootstrap.RunEvery("renotify", time.Minute, alertUseCase.Renotify)
+	bootstrap.RunEvery("reports", time.Minute, reportUseCase.SendDue)
+	bootstrap.RunEvery("subscriptions", 10*time.Second, subscriptionUseCase.SendQueued)
+	bootstrap.RunEvery("config events", 10*time.Second, configUseCase.PublishPending)
 
 	router.Run(":8080")
 }
@@ -0,0 +1,58 @@
+// Command migrate moves the sites embedded in config documents into the
+// sites collection and gives an ingest token to configs created without
+// one. Run it once, before starting a server that keeps sites in their own
+// collection:
+//
+//	go run ./cmd/migrate
+//
+// It reads the same environment as the server and can be run again if it
+// is interrupted.
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	_configRepo "spectator.main/config/repository/mongo_repository"
+	_configUsecase "spectator.main/config/usecase"
+	"spectator.main/internals/bootstrap"
+)
+
+func main() {
+
+	config := bootstrap.InitConfig()
+
+	client := bootstrap.NewMongoDatabase(config)
+	defer bootstrap.CloseMongoDBConnection(client)
+
+	database := client.Database(config.DBname)
+
+	configRepo := _configRepo.NewMongoRepository(database)
+
+	ctx := context.Background()
+
+	err := configRepo.EnsureIndexes(ctx)
+	if err != nil {
+		log.Fatal("Creating site indexes: ", err)
+	}
+
+	moved, err := configRepo.MigrateSites(ctx)
+	if err != nil {
+		log.Fatalf("Migrating sites (%d moved before the failure): %v", moved, err)
+	}
+
+	log.Printf("Moved %d sites into the sites collection", moved)
+
+	// The backfill only reads configs and their revisions and fills the
+	// outbox; the server publishes the events once it starts.
+	timeoutContext := time.Duration(config.ContextTimeout) * time.Second
+	configUseCase := _configUsecase.NewConfigUsecase(configRepo, _configRepo.NewRevisionMongoRepository(database), nil, nil, nil, nil, _configRepo.NewOutboxMongoRepository(database), nil, timeoutContext, nil)
+
+	issued, err := configUseCase.BackfillIngestTokens(ctx)
+	if err != nil {
+		log.Fatalf("Issuing ingest tokens (%d issued before the failure): %v", issued, err)
+	}
+
+	log.Printf("Issued ingest tokens to %d configs", issued)
+}
@@ -4,9 +4,12 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"spectator.main/domain"
 	"spectator.main/internals/mongo"
 )
@@ -14,15 +17,17 @@ import (
 type mongoRepository struct {
 	DB         mongo.Database
 	Collection mongo.Collection
+	Sites      mongo.Collection
 }
 
 const (
-	timeFormat     = "2006-01-02T15:04:05.999Z07:00" // reduce precision from RFC3339Nano as date format
-	collectionName = "config"
+	timeFormat         = "2006-01-02T15:04:05.999Z07:00" // reduce precision from RFC3339Nano as date format
+	collectionName     = "config"
+	siteCollectionName = "sites"
 )
 
 func NewMongoRepository(DB mongo.Database) domain.ConfigRepository {
-	return &mongoRepository{DB, DB.Collection(collectionName)}
+	return &mongoRepository{DB, DB.Collection(collectionName), DB.Collection(siteCollectionName)}
 }
 
 func (m *mongoRepository) InsertOne(ctx context.Context, config *domain.ConfigDetails) (*domain.ConfigDetails, error) {
@@ -35,21 +40,38 @@ func (m *mongoRepository) InsertOne(ctx context.Context, config *domain.ConfigDe
 		return config, err
 	}
 
+	if len(config.SiteConfig) == 0 {
+		return config, nil
+	}
+	sites := []interface{}{}
+	for i := range config.SiteConfig {
+		sites = append(sites, &config.SiteConfig[i])
+	}
+	_, err = m.Sites.InsertMany(ctx, sites)
+	if err != nil {
+		return config, err
+	}
+
 	return config, nil
 }
 
-func (m *mongoRepository) GetByUserID(ctx context.Context, userID string) (*domain.ConfigDetails, error) {
+func (m *mongoRepository) FindOne(ctx context.Context, id string) (*domain.ConfigDetails, error) {
 	var (
 		config domain.ConfigDetails
 		err    error
 	)
 
-	idHex, err := primitive.ObjectIDFromHex(userID)
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &config, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&config)
 	if err != nil {
 		return &config, err
 	}
 
-	err = m.Collection.FindOne(ctx, bson.M{"user_id": idHex}).Decode(&config)
+	err = m.withSites(ctx, &config)
 	if err != nil {
 		return &config, err
 	}
@@ -57,7 +79,86 @@ func (m *mongoRepository) GetByUserID(ctx context.Context, userID string) (*doma
 	return &config, nil
 }
 
-func (m *mongoRepository) AddSiteConfig(ctx context.Context, site_config *domain.SiteConfig, id string) error {
+func (m *mongoRepository) GetAllByUserID(ctx context.Context, userID string) ([]domain.ConfigDetails, error) {
+	var (
+		configs []domain.ConfigDetails
+		err     error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return configs, err
+	}
+
+	cursor, err := m.Collection.Find(ctx, bson.M{"user_id": idHex})
+	if err != nil {
+		return configs, err
+	}
+	if cursor == nil {
+		return configs, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &configs)
+	if err != nil {
+		return configs, err
+	}
+
+	err = m.withSites(ctx, pointers(configs)...)
+	if err != nil {
+		return configs, err
+	}
+
+	return configs, nil
+}
+
+func (m *mongoRepository) GetAllWithPage(ctx context.Context, rp int64, p int64, filter interface{}, setsort interface{}) ([]domain.ConfigDetails, int64, error) {
+
+	var (
+		configs []domain.ConfigDetails
+		skip    int64
+		opts    *options.FindOptions
+	)
+
+	skip = (p * rp) - rp
+
+	opts = options.Find().SetLimit(rp).SetSkip(skip)
+
+	if setsort != nil {
+		opts.SetSort(setsort)
+	}
+
+	cursor, err := m.Collection.Find(
+		ctx,
+		filter,
+		opts,
+	)
+
+	if err != nil {
+		return nil, 0, err
+	}
+	if cursor == nil {
+		return nil, 0, fmt.Errorf("nil cursor value")
+	}
+	err = cursor.All(ctx, &configs)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	err = m.withSites(ctx, pointers(configs)...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := m.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return configs, 0, err
+	}
+
+	return configs, count, err
+}
+
+func (m *mongoRepository) SetNotificationTargets(ctx context.Context, targets []domain.NotificationTarget, id string, version int64) error {
+
 	var (
 		err error
 	)
@@ -67,24 +168,30 @@ func (m *mongoRepository) AddSiteConfig(ctx context.Context, site_config *domain
 		return err
 	}
 
-	filter := bson.M{"_id": idHex}
+	filter := atVersion(bson.M{"_id": idHex}, version)
 
 	update := bson.M{
-		"$push": bson.M{
-			"site_configs": site_config,
+		"$set": bson.M{
+			"notification_targets": targets,
+			"updated_at":           time.Now(),
+		},
+		"$inc": bson.M{
+			"version": 1,
 		},
 	}
 
-	_, err = m.Collection.UpdateOne(ctx, filter, update)
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
 	if err != nil {
 		return err
-
+	}
+	if result.MatchedCount == 0 {
+		return missing(ctx, m.Collection, idHex, errors.New("no config found with the given id"))
 	}
 
 	return nil
 }
 
-func (m *mongoRepository) RemoveSiteConfig(ctx context.Context, site_url string, id string) error {
+func (m *mongoRepository) SetLabels(ctx context.Context, labels map[string]string, id string, version int64) error {
 
 	var (
 		err error
@@ -94,14 +201,16 @@ func (m *mongoRepository) RemoveSiteConfig(ctx context.Context, site_url string,
 	if err != nil {
 		return err
 	}
-	fmt.Println(site_url)
-	filter := bson.M{"_id": idHex}
+
+	filter := atVersion(bson.M{"_id": idHex}, version)
 
 	update := bson.M{
-		"$pull": bson.M{
-			"site_configs": bson.M{
-				"site_url": site_url,
-			},
+		"$set": bson.M{
+			"labels":     labels,
+			"updated_at": time.Now(),
+		},
+		"$inc": bson.M{
+			"version": 1,
 		},
 	}
 
@@ -109,14 +218,113 @@ func (m *mongoRepository) RemoveSiteConfig(ctx context.Context, site_url string,
 	if err != nil {
 		return err
 	}
-	if result.ModifiedCount == 0 {
-		return errors.New("no site config found with the given site url")
+	if result.MatchedCount == 0 {
+		return missing(ctx, m.Collection, idHex, errors.New("no config found with the given id"))
+	}
+
+	return nil
+}
+
+func (m *mongoRepository) FindByBadgeToken(ctx context.Context, token string) (*domain.ConfigDetails, error) {
+	var (
+		config domain.ConfigDetails
+		err    error
+	)
+
+	site, err := m.findSiteByBadgeToken(ctx, token)
+	if err != nil {
+		return &config, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": site.ConfigID}).Decode(&config)
+	if err != nil {
+		return &config, err
+	}
+
+	err = m.withSites(ctx, &config)
+	if err != nil {
+		return &config, err
+	}
+
+	return &config, nil
+}
+
+func (m *mongoRepository) FindByFeedToken(ctx context.Context, token string) (*domain.ConfigDetails, error) {
+	var (
+		config domain.ConfigDetails
+		err    error
+	)
+
+	err = m.Collection.FindOne(ctx, bson.M{"feed_token": token}).Decode(&config)
+	if err != nil {
+		return &config, err
+	}
+
+	err = m.withSites(ctx, &config)
+	if err != nil {
+		return &config, err
+	}
+
+	return &config, nil
+}
+
+// SetFeedToken replaces the config's feed token and, like the ingest token,
+// leaves the version alone.
+func (m *mongoRepository) SetFeedToken(ctx context.Context, token string, id string) error {
+
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"feed_token": token,
+		},
+	}
+
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": idHex}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no config found with the given id")
 	}
 
 	return nil
 }
 
-func (m *mongoRepository) UpdateSiteConfig(ctx context.Context, site_config *domain.SiteConfig, id string) error {
+// GetWithoutIngestToken returns the configs created before they were given
+// an ingest token, without their sites.
+func (m *mongoRepository) GetWithoutIngestToken(ctx context.Context) ([]domain.ConfigDetails, error) {
+
+	var (
+		configs []domain.ConfigDetails
+		err     error
+	)
+
+	cursor, err := m.Collection.Find(ctx, bson.M{"ingest_token": bson.M{"$in": bson.A{nil, ""}}})
+	if err != nil {
+		return nil, err
+	}
+	if cursor == nil {
+		return nil, fmt.Errorf("nil cursor value")
+	}
+	err = cursor.All(ctx, &configs)
+	if err != nil {
+		return nil, err
+	}
+
+	return configs, nil
+}
+
+// SetIngestToken replaces the config's ingest token. Tokens are not
+// settings, so the version stays where it is.
+func (m *mongoRepository) SetIngestToken(ctx context.Context, token string, id string) error {
 
 	var (
 		err error
@@ -126,25 +334,129 @@ func (m *mongoRepository) UpdateSiteConfig(ctx context.Context, site_config *dom
 	if err != nil {
 		return err
 	}
-	fmt.Println(site_config.SiteUrl)
-	filter := bson.M{"_id": idHex, "site_configs.site_url": site_config.SiteUrl}
 
 	update := bson.M{
 		"$set": bson.M{
-			"site_configs.$": site_config,
+			"ingest_token": token,
 		},
 	}
 
-	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": idHex}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no config found with the given id")
+	}
 
-	if result.ModifiedCount == 0 {
-		return errors.New("no site config found with the given site url")
+	return nil
+}
+
+func (m *mongoRepository) Rename(ctx context.Context, name string, id string, version int64) (*domain.ConfigDetails, error) {
+
+	var (
+		config domain.ConfigDetails
+		err    error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":       name,
+			"updated_at": time.Now(),
+		},
+		"$inc": bson.M{
+			"version": 1,
+		},
+	}
+
+	result, err := m.Collection.UpdateOne(ctx, atVersion(bson.M{"_id": idHex}, version), update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, missing(ctx, m.Collection, idHex, errors.New("no config found with the given id"))
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = m.withSites(ctx, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func (m *mongoRepository) DeleteOne(ctx context.Context, id string, version int64) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	count, err := m.Collection.DeleteOne(ctx, atVersion(bson.M{"_id": idHex}, version))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return missing(ctx, m.Collection, idHex, errors.New("no config found with the given id"))
 	}
 
+	_, err = m.Sites.DeleteMany(ctx, bson.M{"config_id": idHex})
 	if err != nil {
 		return err
 	}
 
 	return nil
+}
+
+// atVersion narrows filter to documents still at version, so a write
+// against a stale read matches nothing. Documents stored before versions
+// existed count as version 0.
+func atVersion(filter bson.M, version int64) bson.M {
+	switch version {
+	case domain.AnyVersion:
+	case 0:
+		filter["version"] = bson.M{"$in": bson.A{0, nil}}
+	default:
+		filter["version"] = version
+	}
+	return filter
+}
+
+// missing explains a versioned write that matched nothing: notFound if the
+// document is gone, ErrVersionConflict if it moved on to another version.
+func missing(ctx context.Context, collection mongo.Collection, id primitive.ObjectID, notFound error) error {
+	count, err := collection.CountDocuments(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return notFound
+	}
+	return domain.ErrVersionConflict
+}
+
+// Transaction runs fn in a MongoDB transaction. Writes made with the
+// context fn is given commit together, or not at all if fn fails; fn may be
+// run again when the transaction hits a transient error. Transactions need
+// a replica set or sharded cluster.
+func (m *mongoRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	session, err := m.DB.Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
 
+	_, err = session.WithTransaction(ctx, func(sc mongodriver.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
 }
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type outboxRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+	Leases     mongo.Collection
+}
+
+const (
+	outboxCollectionName      = "config_event_outbox"
+	outboxLeaseCollectionName = "config_event_outbox_lease"
+
+	// outboxLeaseID is the one lease document: publishing the outbox is
+	// never split between servers.
+	outboxLeaseID = "publisher"
+)
+
+func NewOutboxMongoRepository(DB mongo.Database) domain.ConfigEventOutboxRepository {
+	return &outboxRepository{DB, DB.Collection(outboxCollectionName), DB.Collection(outboxLeaseCollectionName)}
+}
+
+func (m *outboxRepository) InsertMany(ctx context.Context, messages []domain.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	docs := make([]interface{}, 0, len(messages))
+	for _, message := range messages {
+		docs = append(docs, message)
+	}
+
+	_, err := m.Collection.InsertMany(ctx, docs)
+	return err
+}
+
+// GetPending returns up to limit messages in the order their events were
+// created, which the ObjectIDs they share with the events follow.
+func (m *outboxRepository) GetPending(ctx context.Context, limit int64) ([]domain.OutboxMessage, error) {
+	var (
+		messages []domain.OutboxMessage
+		err      error
+	)
+
+	opts := options.Find().SetSort(bson.M{"_id": 1}).SetLimit(limit)
+	cursor, err := m.Collection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return messages, err
+	}
+	if cursor == nil {
+		return messages, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &messages)
+	if err != nil {
+		return messages, err
+	}
+
+	return messages, nil
+}
+
+func (m *outboxRepository) DeleteOne(ctx context.Context, id primitive.ObjectID) error {
+	_, err := m.Collection.DeleteOne(ctx, bson.M{"_id": id})
+	return err
+}
+
+// Claim takes the lease if it is free, expired or already owner's. When
+// another owner holds it, the filter misses and the upsert collides with
+// the existing lease document.
+func (m *outboxRepository) Claim(ctx context.Context, owner string, until time.Time) (bool, error) {
+	filter := bson.M{
+		"_id": outboxLeaseID,
+		"$or": bson.A{
+			bson.M{"claimed_until": bson.M{"$lte": time.Now()}},
+			bson.M{"claimed_by": owner},
+		},
+	}
+	update := bson.M{"$set": bson.M{"claimed_by": owner, "claimed_until": until}}
+
+	var lease struct {
+		ClaimedBy string `bson:"claimed_by"`
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+	err := m.Leases.FindOneAndUpdate(ctx, filter, update, opts).Decode(&lease)
+	if mongodriver.IsDuplicateKeyError(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func (m *outboxRepository) Release(ctx context.Context, owner string) error {
+	_, err := m.Leases.UpdateOne(ctx, bson.M{"_id": outboxLeaseID, "claimed_by": owner}, bson.M{"$set": bson.M{"claimed_until": time.Time{}}})
+	return err
+}
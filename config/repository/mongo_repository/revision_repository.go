@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type revisionRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+	Counters   mongo.Collection
+}
+
+const (
+	revisionCollectionName = "config_revisions"
+	counterCollectionName  = "config_revision_counters"
+)
+
+func NewRevisionMongoRepository(DB mongo.Database) domain.ConfigRevisionRepository {
+	return &revisionRepository{DB, DB.Collection(revisionCollectionName), DB.Collection(counterCollectionName)}
+}
+
+// InsertOne stores the revision under the next number of its config.
+func (m *revisionRepository) InsertOne(ctx context.Context, revision *domain.ConfigRevision) (*domain.ConfigRevision, error) {
+	var (
+		err error
+	)
+
+	revision.Number, err = m.next(ctx, revision.ConfigID)
+	if err != nil {
+		return revision, err
+	}
+
+	_, err = m.Collection.InsertOne(ctx, revision)
+	if err != nil {
+		return revision, err
+	}
+
+	return revision, nil
+}
+
+// next takes the config's next revision number from its counter, which is
+// bumped in place so concurrent changes never pick the same number. The
+// counter is kept apart from the config so a deletion can still be numbered.
+// Configs with history from before the counter existed start it at their
+// latest revision.
+func (m *revisionRepository) next(ctx context.Context, configID primitive.ObjectID) (int64, error) {
+	var (
+		counter struct {
+			Number int64 `bson:"number"`
+		}
+	)
+
+	after := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	err := m.Counters.FindOneAndUpdate(ctx, bson.M{"_id": configID}, bson.M{"$inc": bson.M{"number": 1}}, after).Decode(&counter)
+	if err == nil {
+		return counter.Number, nil
+	}
+	if !errors.Is(err, mongodriver.ErrNoDocuments) {
+		return 0, err
+	}
+
+	latest, err := m.GetByConfigID(ctx, configID, 1)
+	if err != nil {
+		return 0, err
+	}
+	var start int64
+	if len(latest) > 0 {
+		start = latest[0].Number
+	}
+
+	// The pipeline bumps a counter another change created in the meantime
+	// rather than resetting it.
+	update := bson.A{bson.M{"$set": bson.M{"number": bson.M{"$add": bson.A{bson.M{"$ifNull": bson.A{"$number", start}}, 1}}}}}
+	err = m.Counters.FindOneAndUpdate(ctx, bson.M{"_id": configID}, update, after.SetUpsert(true)).Decode(&counter)
+	if err != nil {
+		return 0, err
+	}
+
+	return counter.Number, nil
+}
+
+func (m *revisionRepository) FindOne(ctx context.Context, configID primitive.ObjectID, number int64) (*domain.ConfigRevision, error) {
+	var (
+		revision domain.ConfigRevision
+		err      error
+	)
+
+	err = m.Collection.FindOne(ctx, bson.M{"config_id": configID, "number": number}).Decode(&revision)
+	if err != nil {
+		return nil, domain.ErrRevisionNotFound
+	}
+
+	return &revision, nil
+}
+
+// GetByConfigID returns the config's most recent revisions, newest first.
+func (m *revisionRepository) GetByConfigID(ctx context.Context, configID primitive.ObjectID, limit int64) ([]domain.ConfigRevision, error) {
+	var (
+		revisions []domain.ConfigRevision
+		err       error
+	)
+
+	opts := options.Find().SetSort(bson.M{"number": -1}).SetLimit(limit)
+	cursor, err := m.Collection.Find(ctx, bson.M{"config_id": configID}, opts)
+	if err != nil {
+		return revisions, err
+	}
+	if cursor == nil {
+		return revisions, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &revisions)
+	if err != nil {
+		return revisions, err
+	}
+
+	return revisions, nil
+}
+
+// EnsureIndexes makes revision numbers unique per config, a last guard
+// behind the counter that hands them out.
+func (m *revisionRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := m.Collection.CreateIndexes(ctx, []mongodriver.IndexModel{
+		{Keys: bson.D{{Key: "config_id", Value: 1}, {Key: "number", Value: -1}}, Options: options.Index().SetUnique(true)},
+	})
+	return err
+}
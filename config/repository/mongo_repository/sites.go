@@ -0,0 +1,409 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+)
+
+// legacyConfig is a config as stored before sites had their own collection.
+type legacyConfig struct {
+	ID         primitive.ObjectID  `bson:"_id"`
+	UserID     primitive.ObjectID  `bson:"user_id"`
+	SiteConfig []domain.SiteConfig `bson:"site_configs"`
+}
+
+func pointers(configs []domain.ConfigDetails) []*domain.ConfigDetails {
+	res := []*domain.ConfigDetails{}
+	for i := range configs {
+		res = append(res, &configs[i])
+	}
+	return res
+}
+
+// withSites fills in the sites of every config with a single query, in the
+// order they were added.
+func (m *mongoRepository) withSites(ctx context.Context, configs ...*domain.ConfigDetails) error {
+	var (
+		sites []domain.SiteConfig
+		err   error
+	)
+
+	if len(configs) == 0 {
+		return nil
+	}
+
+	ids := []primitive.ObjectID{}
+	byID := map[primitive.ObjectID]*domain.ConfigDetails{}
+	for _, config := range configs {
+		config.SiteConfig = []domain.SiteConfig{}
+		ids = append(ids, config.ID)
+		byID[config.ID] = config
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.Sites.Find(ctx, bson.M{"config_id": bson.M{"$in": ids}}, opts)
+	if err != nil {
+		return err
+	}
+	if cursor == nil {
+		return fmt.Errorf("nil cursor value")
+	}
+	err = cursor.All(ctx, &sites)
+	if err != nil {
+		return err
+	}
+
+	for _, site := range sites {
+		if config, ok := byID[site.ConfigID]; ok {
+			config.SiteConfig = append(config.SiteConfig, site)
+		}
+	}
+
+	return nil
+}
+
+// touch records that a site of the config changed.
+func (m *mongoRepository) touch(ctx context.Context, id primitive.ObjectID) error {
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"updated_at": time.Now()}, "$inc": bson.M{"version": 1}})
+	return err
+}
+
+func (m *mongoRepository) findSiteByBadgeToken(ctx context.Context, token string) (*domain.SiteConfig, error) {
+	var (
+		site domain.SiteConfig
+		err  error
+	)
+
+	err = m.Sites.FindOne(ctx, bson.M{"badge_token": token}).Decode(&site)
+	if err != nil {
+		return nil, err
+	}
+
+	return &site, nil
+}
+
+// AddSiteConfig stores the site unless the config already has its URL.
+func (m *mongoRepository) AddSiteConfig(ctx context.Context, site_config *domain.SiteConfig, id string) error {
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	site_config.ConfigID = idHex
+
+	_, err = m.Sites.InsertOne(ctx, site_config)
+	if mongodriver.IsDuplicateKeyError(err) {
+		return domain.ErrDuplicateSite
+	}
+	if err != nil {
+		return err
+	}
+
+	return m.touch(ctx, idHex)
+}
+
+func (m *mongoRepository) RemoveSiteConfig(ctx context.Context, siteID string, id string, version int64) error {
+
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	siteHex, err := primitive.ObjectIDFromHex(siteID)
+	if err != nil {
+		return domain.ErrSiteNotFound
+	}
+
+	count, err := m.Sites.DeleteOne(ctx, atVersion(bson.M{"_id": siteHex, "config_id": idHex}, version))
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return missing(ctx, m.Sites, siteHex, domain.ErrSiteNotFound)
+	}
+
+	return m.touch(ctx, idHex)
+}
+
+// UpdateSiteConfig replaces the settings of the site with the same ID,
+// unless another site of the config already has its URL. The probe results
+// are kept as they are at the time of the update, or cleared when the URL
+// changes, so a result stored in the meantime is never written over.
+func (m *mongoRepository) UpdateSiteConfig(ctx context.Context, site_config *domain.SiteConfig, id string, version int64) error {
+
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	same := bson.M{"$eq": bson.A{"$site_url", bson.M{"$literal": site_config.SiteUrl}}}
+	keep := func(field string, reset interface{}) bson.M {
+		return bson.M{"$cond": bson.A{same, "$" + field, reset}}
+	}
+	update := bson.A{bson.M{
+		"$set": bson.M{
+			"site_url":               bson.M{"$literal": site_config.SiteUrl},
+			"tags":                   bson.M{"$literal": site_config.Tags},
+			"labels":                 bson.M{"$literal": site_config.Labels},
+			"status":                 keep("status", domain.SiteUnknown),
+			"region_details":         keep("region_details", nil),
+			"certificate_expires_at": keep("certificate_expires_at", "$$REMOVE"),
+			"version":                bson.M{"$add": bson.A{"$version", 1}},
+		},
+	}}
+
+	filter := atVersion(bson.M{"_id": site_config.ID, "config_id": idHex}, version)
+	result, err := m.Sites.UpdateOne(ctx, filter, update)
+	if mongodriver.IsDuplicateKeyError(err) {
+		return domain.ErrDuplicateSite
+	}
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return missing(ctx, m.Sites, site_config.ID, domain.ErrSiteNotFound)
+	}
+
+	return m.touch(ctx, idHex)
+}
+
+// SetBadgeToken replaces the site's badge token. Rotating it changes no
+// setting, so neither the site's version nor the config's moves.
+func (m *mongoRepository) SetBadgeToken(ctx context.Context, siteID string, token string, id string) error {
+
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+	siteHex, err := primitive.ObjectIDFromHex(siteID)
+	if err != nil {
+		return domain.ErrSiteNotFound
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"badge_token": token,
+		},
+	}
+
+	result, err := m.Sites.UpdateOne(ctx, bson.M{"_id": siteHex, "config_id": idHex}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrSiteNotFound
+	}
+
+	return nil
+}
+
+func (m *mongoRepository) SetSiteStatus(ctx context.Context, configID primitive.ObjectID, site_url string, status string) error {
+
+	update := bson.M{
+		"$set": bson.M{
+			"status": status,
+		},
+	}
+
+	result, err := m.Sites.UpdateOne(ctx, bson.M{"config_id": configID, "site_url": site_url}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrSiteNotFound
+	}
+
+	return nil
+}
+
+// SetSiteRegion stores the region's latest probe result on the site in one
+// update, replacing the region's previous result in place or appending it
+// when the region reports for the first time, and returns the result it
+// replaced, if any. While the status stays the same the new result keeps
+// the Since of the old one. Like the status, it leaves the version alone.
+func (m *mongoRepository) SetSiteRegion(ctx context.Context, configID primitive.ObjectID, siteID primitive.ObjectID, region domain.RegionDetails) (*domain.RegionDetails, error) {
+
+	var site struct {
+		RegionDetails []domain.RegionDetails `bson:"region_details"`
+	}
+
+	regions := bson.M{"$ifNull": bson.A{"$region_details", bson.A{}}}
+	result := bson.M{"$literal": region}
+	since := bson.M{"$cond": bson.A{
+		bson.M{"$eq": bson.A{"$$this.status", region.Status}},
+		bson.M{"$ifNull": bson.A{"$$this.since", region.Since}},
+		region.Since,
+	}}
+	replaced := bson.M{"$mergeObjects": bson.A{result, bson.M{"since": since}}}
+	update := bson.A{bson.M{"$set": bson.M{"region_details": bson.M{"$cond": bson.M{
+		"if": bson.M{"$in": bson.A{region.Region, bson.M{"$map": bson.M{"input": regions, "in": "$$this.region"}}}},
+		"then": bson.M{"$map": bson.M{
+			"input": regions,
+			"in":    bson.M{"$cond": bson.A{bson.M{"$eq": bson.A{"$$this.region", region.Region}}, replaced, "$$this"}},
+		}},
+		"else": bson.M{"$concatArrays": bson.A{regions, bson.A{result}}},
+	}}}}}
+
+	// The site as it was before the update, which makes telling a change
+	// from a repeat atomic across servers.
+	opts := options.FindOneAndUpdate().SetProjection(bson.M{"region_details": 1})
+	err := m.Sites.FindOneAndUpdate(ctx, bson.M{"_id": siteID, "config_id": configID}, update, opts).Decode(&site)
+	if errors.Is(err, mongodriver.ErrNoDocuments) {
+		return nil, domain.ErrSiteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range site.RegionDetails {
+		if site.RegionDetails[i].Region == region.Region {
+			return &site.RegionDetails[i], nil
+		}
+	}
+
+	return nil, nil
+}
+
+// SetCertificateExpiry records when the site's TLS certificate expires, as
+// last seen by a probe.
+func (m *mongoRepository) SetCertificateExpiry(ctx context.Context, configID primitive.ObjectID, siteID primitive.ObjectID, expiresAt time.Time) error {
+
+	update := bson.M{
+		"$set": bson.M{
+			"certificate_expires_at": expiresAt,
+		},
+	}
+
+	result, err := m.Sites.UpdateOne(ctx, bson.M{"_id": siteID, "config_id": configID}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrSiteNotFound
+	}
+
+	return nil
+}
+
+// GetSites lists the sites matching filter in the order they were added.
+func (m *mongoRepository) GetSites(ctx context.Context, filter interface{}) ([]domain.SiteConfig, error) {
+	var (
+		sites []domain.SiteConfig
+		err   error
+	)
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.Sites.Find(ctx, filter, opts)
+	if err != nil {
+		return sites, err
+	}
+	if cursor == nil {
+		return sites, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &sites)
+	if err != nil {
+		return sites, err
+	}
+
+	return sites, nil
+}
+
+// EnsureIndexes creates the config and site indexes. The URL is unique
+// within a config and so are badge tokens; sites without a token yet are
+// left out. Labels get wildcard indexes so any key can be selected on.
+func (m *mongoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := m.Collection.CreateIndexes(ctx, []mongodriver.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "labels.$**", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Sites.CreateIndexes(ctx, []mongodriver.IndexModel{
+		{Keys: bson.D{{Key: "config_id", Value: 1}, {Key: "site_url", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "user_id", Value: 1}}},
+		{Keys: bson.D{{Key: "site_url", Value: 1}}},
+		{Keys: bson.D{{Key: "status", Value: 1}}},
+		{Keys: bson.D{{Key: "badge_token", Value: 1}}, Options: options.Index().SetUnique(true).SetSparse(true)},
+		{Keys: bson.D{{Key: "labels.$**", Value: 1}}},
+	})
+	return err
+}
+
+// MigrateSites moves the sites still embedded in config documents into the
+// sites collection, giving an ID to those stored before sites had one. A site
+// whose URL is already in the collection is kept as it is there, so the
+// migration can be run again after an interruption.
+func (m *mongoRepository) MigrateSites(ctx context.Context) (int64, error) {
+	var (
+		configs []legacyConfig
+		moved   int64
+	)
+
+	cursor, err := m.Collection.Find(ctx, bson.M{"site_configs": bson.M{"$exists": true}})
+	if err != nil {
+		return 0, err
+	}
+	if cursor == nil {
+		return 0, fmt.Errorf("nil cursor value")
+	}
+	err = cursor.All(ctx, &configs)
+	if err != nil {
+		return 0, err
+	}
+
+	upsert := options.Update().SetUpsert(true)
+	for _, config := range configs {
+		for _, site := range config.SiteConfig {
+			if site.SiteUrl == "" {
+				return moved, errors.New("config " + config.ID.Hex() + " has a site without a url")
+			}
+			if site.ID.IsZero() {
+				site.ID = primitive.NewObjectID()
+			}
+			site.ConfigID = config.ID
+			site.UserID = config.UserID
+			if site.Status == "" {
+				site.Status = domain.SiteUnknown
+			}
+
+			filter := bson.M{"config_id": config.ID, "site_url": site.SiteUrl}
+			result, err := m.Sites.UpdateOne(ctx, filter, bson.M{"$setOnInsert": &site}, upsert)
+			if err != nil {
+				return moved, err
+			}
+			moved += result.UpsertedCount
+		}
+
+		_, err = m.Collection.UpdateOne(ctx, bson.M{"_id": config.ID}, bson.M{"$unset": bson.M{"site_configs": ""}})
+		if err != nil {
+			return moved, err
+		}
+	}
+
+	return moved, nil
+}
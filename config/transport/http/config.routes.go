@@ -1,11 +1,23 @@
 package http
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 	"spectator.main/domain"
 	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/labels"
+	"spectator.main/internals/middleware"
+	"spectator.main/internals/monitorfile"
 )
 
 type ConfigHandler struct {
@@ -18,11 +30,74 @@ func NewConfigHandler(cfg *bootstrap.Config, r *gin.RouterGroup, uu domain.Confi
 		ConfigUsecase: uu,
 		config:        cfg,
 	}
-	r.POST("/config", handler.CreateConfig)
-	r.GET("/config/:user_id", handler.GetConfigByUserID)
-	r.PUT("/config/:config_id/site", handler.AddSiteConfig)
-	r.DELETE("/config/:config_id/site", handler.RemoveSiteConfig)
-	r.PATCH("/config/:config_id/site", handler.UpdateSiteConfig)
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.POST("/config", handler.CreateConfig)
+	protected.GET("/configs", handler.GetConfigs)
+	protected.GET("/configs/export", handler.Export)
+	protected.POST("/configs/import", handler.Import)
+	protected.POST("/configs/import/:source", handler.ImportFrom)
+	protected.POST("/configs/plan", handler.Plan)
+	protected.POST("/configs/apply", handler.Apply)
+	protected.GET("/sites", handler.GetSites)
+	protected.GET("/sites/status", handler.StatusByLabel)
+	protected.GET("/config/:config_id", handler.GetConfig)
+	protected.PATCH("/config/:config_id", handler.RenameConfig)
+	protected.DELETE("/config/:config_id", handler.DeleteConfig)
+	protected.POST("/config/:config_id/sites", handler.AddSiteConfig)
+	protected.GET("/config/:config_id/sites/:site_id", handler.GetSiteConfig)
+	protected.PUT("/config/:config_id/sites/:site_id", handler.UpdateSiteConfig)
+	protected.DELETE("/config/:config_id/sites/:site_id", handler.RemoveSiteConfig)
+	protected.POST("/config/:config_id/sites/:site_id/badge", handler.RotateBadgeToken)
+	protected.PUT("/config/:config_id/notifications", handler.SetNotificationTargets)
+	protected.PUT("/config/:config_id/labels", handler.SetLabels)
+	protected.POST("/config/:config_id/feed", handler.RotateFeedToken)
+	protected.POST("/config/:config_id/ingest-token", handler.RotateIngestToken)
+	protected.GET("/config/:config_id/history", handler.GetHistory)
+	protected.GET("/config/:config_id/history/:revision", handler.GetRevision)
+	protected.POST("/config/:config_id/history/:revision/rollback", handler.Rollback)
+	protected.GET("/config/:config_id/diff", handler.Diff)
+}
+
+// errorStatus maps configs, sites and revisions the caller may not see to
+// 404, invalid labels to 400, URL clashes to 409, stale If-Match versions
+// to 412 and any other failure to fallback.
+func errorStatus(err error, fallback int) int {
+	switch {
+	case errors.Is(err, domain.ErrInvalidLabel), errors.Is(err, domain.ErrTargetNotAllowed):
+		return http.StatusBadRequest
+	case errors.Is(err, domain.ErrConfigNotFound), errors.Is(err, domain.ErrSiteNotFound), errors.Is(err, domain.ErrRevisionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, domain.ErrDuplicateSite):
+		return http.StatusConflict
+	case errors.Is(err, domain.ErrVersionConflict):
+		return http.StatusPreconditionFailed
+	}
+	return fallback
+}
+
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch reads the version a write expects from If-Match; "*" accepts any
+// version. Writes without the header are refused with 428 so nobody
+// overwrites a change they never saw, and a header naming no version fails
+// with 412.
+func ifMatch(c *gin.Context) (int64, bool) {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" {
+		c.JSON(http.StatusPreconditionRequired, gin.H{"error": domain.ErrPreconditionRequired.Error()})
+		return 0, false
+	}
+	if header == "*" {
+		return domain.AnyVersion, true
+	}
+	version, err := strconv.ParseInt(strings.Trim(header, `"`), 10, 64)
+	if err != nil || version < 0 {
+		c.JSON(http.StatusPreconditionFailed, gin.H{"error": domain.ErrVersionConflict.Error()})
+		return 0, false
+	}
+	return version, true
 }
 
 func (h *ConfigHandler) CreateConfig(c *gin.Context) {
@@ -31,47 +106,186 @@ func (h *ConfigHandler) CreateConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+	config.UserID = userID
 	res, err := h.ConfigUsecase.InsertOne(c, &config)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
+	c.Header("ETag", etag(res.Version))
 	c.JSON(http.StatusCreated, res)
 }
 
-func (h *ConfigHandler) GetConfigByUserID(c *gin.Context) {
-	userID := c.Param("user_id")
-	config, err := h.ConfigUsecase.GetByUserID(c, userID)
+// configSorts maps the sort query values to the fields they order by; a
+// leading "-" sorts descending.
+var configSorts = map[string]string{
+	"name":       "name",
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+}
+
+func (h *ConfigHandler) GetConfigs(c *gin.Context) {
+
+	type Response struct {
+		Total       int64                  `json:"total"`
+		PerPage     int64                  `json:"per_page"`
+		CurrentPage int64                  `json:"current_page"`
+		LastPage    int64                  `json:"last_page"`
+		From        int64                  `json:"from"`
+		To          int64                  `json:"to"`
+		Configs     []domain.ConfigSummary `json:"configs"`
+	}
+
+	rp_ctx, _ := c.GetQuery("rp")
+	rp, err := strconv.ParseInt(rp_ctx, 10, 64)
+	if err != nil || rp < 1 || rp > 100 {
+		rp = 25
+	}
+
+	p_ctx, _ := c.GetQuery("p")
+	page, err := strconv.ParseInt(p_ctx, 10, 64)
+	if err != nil || page < 1 {
+		page = 1
+	}
+
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+
+	selector, err := labels.Parse(c.Query("selector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	name_ctx, _ := c.GetQuery("name")
+	filters := bson.D{
+		{Key: "user_id", Value: userID},
+		{Key: "name", Value: primitive.Regex{Pattern: regexp.QuoteMeta(name_ctx), Options: "i"}},
+	}
+	filters = append(filters, selector.Filter("labels")...)
+
+	sort_ctx := c.DefaultQuery("sort", "name")
+	field, ok := configSorts[strings.TrimPrefix(sort_ctx, "-")]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of name, created_at, updated_at, optionally prefixed with -"})
+		return
+	}
+	order := 1
+	if strings.HasPrefix(sort_ctx, "-") {
+		order = -1
+	}
+	setsort := bson.D{{Key: field, Value: order}, {Key: "_id", Value: order}}
+
+	res, count, err := h.ConfigUsecase.GetAllWithPage(c, rp, page, filters, setsort)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
+
+	result := Response{
+		Total:       count,
+		PerPage:     rp,
+		CurrentPage: page,
+		LastPage:    int64(math.Ceil(float64(count) / float64(rp))),
+		From:        page*rp - rp + 1,
+		To:          page * rp,
+		Configs:     res,
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *ConfigHandler) GetConfig(c *gin.Context) {
+	config, err := h.ConfigUsecase.FindOne(c, c.Param("config_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag(config.Version))
 	c.JSON(http.StatusOK, config)
 }
 
+func (h *ConfigHandler) RenameConfig(c *gin.Context) {
+	var request domain.RenameConfigRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	config, err := h.ConfigUsecase.Rename(c, request.Name, c.Param("config_id"), version, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusUnprocessableEntity), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag(config.Version))
+	c.JSON(http.StatusOK, config)
+}
+
+func (h *ConfigHandler) DeleteConfig(c *gin.Context) {
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	err := h.ConfigUsecase.DeleteOne(c, c.Param("config_id"), version, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Config deleted successfully"})
+}
+
 func (h *ConfigHandler) AddSiteConfig(c *gin.Context) {
 	var siteConfig domain.SiteConfig
 	if err := c.ShouldBindJSON(&siteConfig); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := h.ConfigUsecase.AddSiteConfig(c, &siteConfig, c.Param("config_id"))
+	if err := validator.New().Struct(&siteConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	site, err := h.ConfigUsecase.AddSiteConfig(c, &siteConfig, c.Param("config_id"), c.GetString("x-user-id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "Site config added successfully"})
+	c.Header("ETag", etag(site.Version))
+	c.JSON(http.StatusCreated, site)
+}
+
+func (h *ConfigHandler) GetSiteConfig(c *gin.Context) {
+	site, err := h.ConfigUsecase.FindSite(c, c.Param("site_id"), c.Param("config_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag(site.Version))
+	c.JSON(http.StatusOK, site)
 }
 
 func (h *ConfigHandler) RemoveSiteConfig(c *gin.Context) {
-	var removeConfig domain.RemoveConfigRequest
-	if err := c.ShouldBindJSON(&removeConfig); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	version, ok := ifMatch(c)
+	if !ok {
 		return
 	}
-	err := h.ConfigUsecase.RemoveSiteConfig(c, removeConfig.SiteUrl, c.Param("config_id"))
+	err := h.ConfigUsecase.RemoveSiteConfig(c, c.Param("site_id"), c.Param("config_id"), version, c.GetString("x-user-id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Site config removed successfully"})
@@ -83,10 +297,368 @@ func (h *ConfigHandler) UpdateSiteConfig(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	err := h.ConfigUsecase.UpdateSiteConfig(c, &siteConfig, c.Param("config_id"))
+	if err := validator.New().Struct(&siteConfig); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	site, err := h.ConfigUsecase.UpdateSiteConfig(c, &siteConfig, c.Param("site_id"), c.Param("config_id"), version, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag(site.Version))
+	c.JSON(http.StatusOK, site)
+}
+
+func (h *ConfigHandler) SetNotificationTargets(c *gin.Context) {
+	var request domain.NotificationTargetsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	err := h.ConfigUsecase.SetNotificationTargets(c, request.NotificationTargets, c.Param("config_id"), version, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Notification targets updated successfully"})
+}
+
+func (h *ConfigHandler) SetLabels(c *gin.Context) {
+	var request domain.LabelsRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	version, ok := ifMatch(c)
+	if !ok {
+		return
+	}
+	err := h.ConfigUsecase.SetLabels(c, request.Labels, c.Param("config_id"), version, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Labels updated successfully"})
+}
+
+// siteFilter selects the caller's sites matching the selector query, such
+// as "env=prod,team in (payments,auth)".
+func siteFilter(c *gin.Context) (bson.D, bool) {
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return nil, false
+	}
+	selector, err := labels.Parse(c.Query("selector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	filter := bson.D{{Key: "user_id", Value: userID}}
+	return append(filter, selector.Filter("labels")...), true
+}
+
+// GetSites lists the caller's sites across all configs, optionally only
+// those matching a label selector.
+func (h *ConfigHandler) GetSites(c *gin.Context) {
+	filter, ok := siteFilter(c)
+	if !ok {
+		return
+	}
+	sites, err := h.ConfigUsecase.GetSites(c, filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, sites)
+}
+
+// StatusByLabel sums up the caller's sites grouped by their value of the
+// label key group_by, with uptime over the last days days (1-90, default
+// 30).
+func (h *ConfigHandler) StatusByLabel(c *gin.Context) {
+	key := c.Query("group_by")
+	if key == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "group_by must name a label key"})
+		return
+	}
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days < 1 || days > 90 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 90"})
+		return
+	}
+	filter, ok := siteFilter(c)
+	if !ok {
+		return
+	}
+	groups, err := h.ConfigUsecase.StatusByLabel(c, key, filter, days, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, groups)
+}
+
+func (h *ConfigHandler) RotateBadgeToken(c *gin.Context) {
+	token, err := h.ConfigUsecase.RotateBadgeToken(c, c.Param("site_id"), c.Param("config_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	base := strings.TrimSuffix(h.config.PublicURL, "/") + "/badge/" + token
+	c.JSON(http.StatusOK, domain.BadgeTokenResponse{
+		BadgeToken: token,
+		UptimeURL:  base + "/uptime.svg",
+		StatusURL:  base + "/status.svg",
+	})
+}
+
+func (h *ConfigHandler) RotateFeedToken(c *gin.Context) {
+	token, err := h.ConfigUsecase.RotateFeedToken(c, c.Param("config_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	base := strings.TrimSuffix(h.config.PublicURL, "/") + "/feed/" + token
+	c.JSON(http.StatusOK, domain.FeedTokenResponse{
+		FeedToken: token,
+		RSSURL:    base + "/rss.xml",
+		AtomURL:   base + "/atom.xml",
+	})
+}
+
+func (h *ConfigHandler) RotateIngestToken(c *gin.Context) {
+	token, err := h.ConfigUsecase.RotateIngestToken(c, c.Param("config_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, domain.IngestTokenResponse{IngestToken: token})
+}
+
+// revisionNumber reads a revision number from the path parameter or query
+// value raw.
+func revisionNumber(raw string) (int64, bool) {
+	n, err := strconv.ParseInt(raw, 10, 64)
+	return n, err == nil && n >= 1
+}
+
+func (h *ConfigHandler) GetHistory(c *gin.Context) {
+	limit := int64(50)
+	if raw, ok := c.GetQuery("limit"); ok {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 1 || n > 500 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+			return
+		}
+		limit = n
+	}
+	revisions, err := h.ConfigUsecase.GetHistory(c, c.Param("config_id"), limit, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (h *ConfigHandler) GetRevision(c *gin.Context) {
+	number, ok := revisionNumber(c.Param("revision"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrRevisionNotFound.Error()})
+		return
+	}
+	revision, err := h.ConfigUsecase.GetRevision(c, c.Param("config_id"), number, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, revision)
+}
+
+// Diff compares revision from with revision to, or with the current config
+// when to is left out.
+func (h *ConfigHandler) Diff(c *gin.Context) {
+	from, ok := revisionNumber(c.Query("from"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a revision number"})
+		return
+	}
+	var to int64
+	if raw, set := c.GetQuery("to"); set {
+		to, ok = revisionNumber(raw)
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a revision number"})
+			return
+		}
+	}
+	changes, err := h.ConfigUsecase.Diff(c, c.Param("config_id"), from, to, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+func (h *ConfigHandler) Rollback(c *gin.Context) {
+	number, ok := revisionNumber(c.Param("revision"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": domain.ErrRevisionNotFound.Error()})
+		return
+	}
+	config, err := h.ConfigUsecase.Rollback(c, c.Param("config_id"), number, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusUnprocessableEntity), gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("ETag", etag(config.Version))
+	c.JSON(http.StatusOK, config)
+}
+
+// maxImportSize caps the size of an uploaded import file.
+const maxImportSize = 5 << 20
+
+// Export downloads all of the caller's configs as a file, in the format the
+// format query names: json, the default, yaml or csv.
+func (h *ConfigHandler) Export(c *gin.Context) {
+	format := c.DefaultQuery("format", domain.FormatJSON)
+	doc, err := h.ConfigUsecase.Export(c, c.GetString("x-user-id"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "Site config updated successfully"})
+	data, err := monitorfile.Encode(doc, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.Header("Content-Disposition", `attachment; filename="spectator-monitors.`+format+`"`)
+	c.Data(http.StatusOK, monitorfile.ContentType(format), data)
+}
+
+// readDocument reads an uploaded file in the format the format query names,
+// otherwise in the one its Content-Type names, answering with every invalid
+// row when it cannot be read.
+func readDocument(c *gin.Context) (*domain.MonitorDocument, bool) {
+	format := c.Query("format")
+	if format == "" {
+		format = monitorfile.FormatOf(c.ContentType())
+	}
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	doc, errs := monitorfile.Decode(data, format)
+	if len(errs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": (&domain.ImportError{Errors: errs}).Error(), "errors": errs})
+		return nil, false
+	}
+	return doc, true
+}
+
+// documentError answers with the invalid rows of a document the usecase
+// refused, or with err itself.
+func documentError(c *gin.Context, err error) {
+	var invalid *domain.ImportError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": invalid.Error(), "errors": invalid.Errors})
+		return
+	}
+	c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+}
+
+// Import applies an uploaded file to the caller's configs. Nothing is
+// applied when any row is invalid; the response lists every invalid row
+// instead.
+func (h *ConfigHandler) Import(c *gin.Context) {
+	doc, ok := readDocument(c)
+	if !ok {
+		return
+	}
+	result, err := h.ConfigUsecase.Import(c, doc, c.GetString("x-user-id"))
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// Plan lists what applying the uploaded file would change, without changing
+// it. With prune=true configs and sites the file leaves out are deleted.
+func (h *ConfigHandler) Plan(c *gin.Context) {
+	doc, ok := readDocument(c)
+	if !ok {
+		return
+	}
+	plan, err := h.ConfigUsecase.Plan(c, doc, c.Query("prune") == "true", c.GetString("x-user-id"))
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// Apply makes the caller's configs match the uploaded file and lists what
+// it changed; see Plan.
+func (h *ConfigHandler) Apply(c *gin.Context) {
+	doc, ok := readDocument(c)
+	if !ok {
+		return
+	}
+	plan, err := h.ConfigUsecase.Apply(c, doc, c.Query("prune") == "true", c.GetString("x-user-id"))
+	if err != nil {
+		documentError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// sourceConfigNames name the config that takes the monitors an export from
+// another service does not group.
+var sourceConfigNames = map[string]string{
+	domain.SourceUptimeKuma:  "Uptime Kuma",
+	domain.SourceUptimeRobot: "UptimeRobot",
+}
+
+// ImportFrom imports an Uptime Kuma backup or an UptimeRobot getMonitors
+// response. Ungrouped monitors go to the config the config query names. The
+// response lists every setting that could not be carried over.
+func (h *ConfigHandler) ImportFrom(c *gin.Context) {
+	source := c.Param("source")
+	configName := c.DefaultQuery("config", sourceConfigNames[source])
+	data, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize))
+	if err != nil {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		return
+	}
+	doc, unsupported, errs := monitorfile.Convert(data, source, configName)
+	if len(errs) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": (&domain.ImportError{Errors: errs}).Error(), "errors": errs})
+		return
+	}
+	result, err := h.ConfigUsecase.Import(c, doc, c.GetString("x-user-id"))
+	var invalid *domain.ImportError
+	if errors.As(err, &invalid) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": invalid.Error(), "errors": invalid.Errors, "unsupported": unsupported})
+		return
+	}
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	result.Unsupported = unsupported
+	c.JSON(http.StatusOK, result)
 }
@@ -0,0 +1,142 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	_configHandler "spectator.main/config/transport/http"
+	_configUsecase "spectator.main/config/usecase"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	tokenutil "spectator.main/internals/util"
+)
+
+const secret = "test-secret"
+
+// configRepo serves a fixed set of configs. Any other repository method
+// panics, so a request that gets past the ownership check fails the test.
+type configRepo struct {
+	domain.ConfigRepository
+	configs map[string]domain.ConfigDetails
+}
+
+func (r *configRepo) FindOne(ctx context.Context, id string) (*domain.ConfigDetails, error) {
+	config, ok := r.configs[id]
+	if !ok {
+		return nil, domain.ErrConfigNotFound
+	}
+	return &config, nil
+}
+
+type revisionRepo struct {
+	domain.ConfigRevisionRepository
+}
+
+func (r *revisionRepo) GetByConfigID(ctx context.Context, configID primitive.ObjectID, limit int64) ([]domain.ConfigRevision, error) {
+	return []domain.ConfigRevision{}, nil
+}
+
+func token(t *testing.T, userID primitive.ObjectID) string {
+	t.Helper()
+	token, err := tokenutil.CreateAccessToken(&domain.User{ID: userID, Name: "test"}, secret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func setup() (*gin.Engine, primitive.ObjectID, domain.ConfigDetails) {
+	gin.SetMode(gin.TestMode)
+
+	owner := primitive.NewObjectID()
+	site := domain.SiteConfig{ID: primitive.NewObjectID(), SiteUrl: "https://example.com", Version: 1}
+	config := domain.ConfigDetails{
+		ID:         primitive.NewObjectID(),
+		UserID:     owner,
+		Name:       "production",
+		Version:    1,
+		SiteConfig: []domain.SiteConfig{site},
+	}
+	repo := &configRepo{configs: map[string]domain.ConfigDetails{config.ID.Hex(): config}}
+
+	usecase := _configUsecase.NewConfigUsecase(repo, &revisionRepo{}, nil, nil, nil, nil, nil, nil, time.Second, nil)
+	router := gin.New()
+	_configHandler.NewConfigHandler(&bootstrap.Config{AccessTokenSecret: secret}, router.Group("api/v1"), usecase)
+
+	return router, owner, config
+}
+
+func serve(router *gin.Engine, method string, path string, body string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-Match", "*")
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestOwnerCanReadConfig(t *testing.T) {
+	router, owner, config := setup()
+	base := "/api/v1/config/" + config.ID.Hex()
+	site := base + "/sites/" + config.SiteConfig[0].ID.Hex()
+
+	for _, path := range []string{base, site, base + "/history"} {
+		res := serve(router, http.MethodGet, path, "", token(t, owner))
+		if res.Code != http.StatusOK {
+			t.Errorf("GET %s: got %d, want %d: %s", path, res.Code, http.StatusOK, res.Body)
+		}
+	}
+}
+
+func TestOtherUserGetsNotFound(t *testing.T) {
+	router, _, config := setup()
+	other := token(t, primitive.NewObjectID())
+	base := "/api/v1/config/" + config.ID.Hex()
+	site := base + "/sites/" + config.SiteConfig[0].ID.Hex()
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodGet, base, ""},
+		{http.MethodPatch, base, `{"name":"stolen"}`},
+		{http.MethodDelete, base, ""},
+		{http.MethodPost, base + "/sites", `{"site_url":"https://other.example.com"}`},
+		{http.MethodGet, site, ""},
+		{http.MethodPut, site, `{"site_url":"https://other.example.com"}`},
+		{http.MethodDelete, site, ""},
+		{http.MethodPost, site + "/badge", ""},
+		{http.MethodPut, base + "/notifications", `{"notification_targets":[]}`},
+		{http.MethodPut, base + "/labels", `{"labels":{"env":"prod"}}`},
+		{http.MethodPost, base + "/feed", ""},
+		{http.MethodGet, base + "/history", ""},
+		{http.MethodGet, base + "/history/1", ""},
+		{http.MethodGet, base + "/diff?from=1", ""},
+		{http.MethodPost, base + "/history/1/rollback", ""},
+	}
+	for _, r := range requests {
+		res := serve(router, r.method, r.path, r.body, other)
+		if res.Code != http.StatusNotFound {
+			t.Errorf("%s %s: got %d, want %d: %s", r.method, r.path, res.Code, http.StatusNotFound, res.Body)
+		}
+	}
+}
+
+func TestMissingTokenIsUnauthorized(t *testing.T) {
+	router, _, config := setup()
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/config/"+config.ID.Hex(), nil)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	if res.Code != http.StatusUnauthorized {
+		t.Errorf("got %d, want %d", res.Code, http.StatusUnauthorized)
+	}
+}
@@ -2,28 +2,44 @@ package usecase
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"spectator.main/domain"
+	"spectator.main/internals/labels"
 	"spectator.main/internals/rabbitmq"
+	tokenutil "spectator.main/internals/util"
 )
 
 type configUsecase struct {
-	configRepo     domain.ConfigRepository
-	userRepo       domain.UserRepository
-	contextTimeout time.Duration
-	amqpPublisher  rabbitmq.MQPublisher
+	configRepo      domain.ConfigRepository
+	revisionRepo    domain.ConfigRevisionRepository
+	userRepo        domain.UserRepository
+	incidentRepo    domain.IncidentRepository
+	maintenanceRepo domain.MaintenanceRepository
+	statusPageRepo  domain.StatusPageRepository
+	outboxRepo      domain.ConfigEventOutboxRepository
+	onCallUsecase   domain.OnCallUsecase
+	contextTimeout  time.Duration
+	amqpPublisher   rabbitmq.MQPublisher
+	publisherID     string
 }
 
-func NewConfigUsecase(c domain.ConfigRepository, u domain.UserRepository, to time.Duration, amqpPublisher rabbitmq.MQPublisher) domain.ConfigUsecase {
+func NewConfigUsecase(c domain.ConfigRepository, r domain.ConfigRevisionRepository, u domain.UserRepository, i domain.IncidentRepository, m domain.MaintenanceRepository, s domain.StatusPageRepository, o domain.ConfigEventOutboxRepository, oc domain.OnCallUsecase, to time.Duration, amqpPublisher rabbitmq.MQPublisher) domain.ConfigUsecase {
 	return &configUsecase{
-		configRepo:     c,
-		userRepo:       u,
-		contextTimeout: to,
-		amqpPublisher:  amqpPublisher,
+		configRepo:      c,
+		revisionRepo:    r,
+		userRepo:        u,
+		incidentRepo:    i,
+		maintenanceRepo: m,
+		statusPageRepo:  s,
+		outboxRepo:      o,
+		onCallUsecase:   oc,
+		contextTimeout:  to,
+		amqpPublisher:   amqpPublisher,
+		publisherID:     primitive.NewObjectID().Hex(),
 	}
 }
 
@@ -37,21 +53,69 @@ func (c *configUsecase) InsertOne(ctx context.Context, config *domain.ConfigDeta
 		return nil, errors.New("user not found")
 	}
 
+	var res *domain.ConfigDetails
+	err = c.transact(ctx, func(ctx context.Context) error {
+		res, err = c.insert(ctx, config)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+// insert stores a new config and its sites, all at version 1 with fresh
+// tokens, and records its first revision, which tells the workers to start
+// probing it.
+func (c *configUsecase) insert(ctx context.Context, config *domain.ConfigDetails) (*domain.ConfigDetails, error) {
+	err := labels.Validate(config.Labels)
+	if err != nil {
+		return nil, err
+	}
+	for i := range config.SiteConfig {
+		err = labels.Validate(config.SiteConfig[i].Labels)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	config.ID = primitive.NewObjectID()
+	config.Version = 1
 	config.CreatedAt = time.Now()
 	config.UpdatedAt = time.Now()
 
-	res, err := c.configRepo.InsertOne(ctx, config)
+	config.FeedToken, err = tokenutil.CreateRandomToken()
 	if err != nil {
-		return res, err
+		return nil, err
+	}
+	config.IngestToken, err = tokenutil.CreateRandomToken()
+	if err != nil {
+		return nil, err
+	}
+	urls := map[string]bool{}
+	for i := range config.SiteConfig {
+		if urls[config.SiteConfig[i].SiteUrl] {
+			return nil, domain.ErrDuplicateSite
+		}
+		urls[config.SiteConfig[i].SiteUrl] = true
+		config.SiteConfig[i].ID = primitive.NewObjectID()
+		config.SiteConfig[i].Version = 1
+		config.SiteConfig[i].ConfigID = config.ID
+		config.SiteConfig[i].UserID = config.UserID
+		config.SiteConfig[i].Status = domain.SiteUnknown
+		config.SiteConfig[i].BadgeToken, err = tokenutil.CreateRandomToken()
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	configJson, err := json.Marshal(res)
+	res, err := c.configRepo.InsertOne(ctx, config)
 	if err != nil {
 		return res, err
 	}
 
-	err = c.amqpPublisher.Publish(configJson)
+	err = c.record(ctx, &domain.ConfigRevision{Action: domain.RevisionCreated}, config.UserID.Hex(), nil, res)
 	if err != nil {
 		return res, err
 	}
@@ -59,54 +123,578 @@ func (c *configUsecase) InsertOne(ctx context.Context, config *domain.ConfigDeta
 	return res, nil
 }
 
-func (c *configUsecase) GetByUserID(ctx context.Context, userID string) (*domain.ConfigDetails, error) {
+// GetAllWithPage lists a page of configs with their site counts.
+func (c *configUsecase) GetAllWithPage(ctx context.Context, rp int64, p int64, filter interface{}, setsort interface{}) ([]domain.ConfigSummary, int64, error) {
 
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
-	res, err := c.configRepo.GetByUserID(ctx, userID)
+	configs, count, err := c.configRepo.GetAllWithPage(ctx, rp, p, filter, setsort)
 	if err != nil {
-		return res, err
+		return nil, count, err
+	}
+
+	res := []domain.ConfigSummary{}
+	for i := range configs {
+		config := &configs[i]
+		counts := domain.SiteCounts{}
+		for j := range config.SiteConfig {
+			countSite(&counts, &config.SiteConfig[j])
+		}
+		res = append(res, domain.ConfigSummary{
+			ConfigDetails: *config,
+			SiteCounts:    counts,
+		})
+	}
+
+	return res, count, nil
+}
+
+// countSite adds the site to counts under the status the probes last
+// reported for it. Sites without one count as unknown.
+func countSite(counts *domain.SiteCounts, site *domain.SiteConfig) {
+	counts.Total++
+	switch site.Status {
+	case domain.SiteUp:
+		counts.Up++
+	case domain.SiteDown:
+		counts.Down++
+	default:
+		counts.Unknown++
+	}
+}
+
+func (c *configUsecase) FindOne(ctx context.Context, id string, userID string) (*domain.ConfigDetails, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	return c.owned(ctx, id, userID)
+}
+
+// checkVersion fails with ErrVersionConflict unless the caller saw the
+// current version. The repository checks again as it writes.
+func checkVersion(version int64, current int64) error {
+	if version != domain.AnyVersion && version != current {
+		return domain.ErrVersionConflict
+	}
+	return nil
+}
+
+// owned loads a config on behalf of userID. Configs of other users are
+// reported as missing so their IDs cannot be probed.
+func (c *configUsecase) owned(ctx context.Context, id string, userID string) (*domain.ConfigDetails, error) {
+	config, err := c.configRepo.FindOne(ctx, id)
+	if err != nil || config.UserID.Hex() != userID {
+		return nil, domain.ErrConfigNotFound
+	}
+	return config, nil
+}
+
+func (c *configUsecase) Rename(ctx context.Context, name string, id string, version int64, userID string) (*domain.ConfigDetails, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("config name cannot be empty")
+	}
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	err = checkVersion(version, config.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	var res *domain.ConfigDetails
+	err = c.transact(ctx, func(ctx context.Context) error {
+		res, err = c.configRepo.Rename(ctx, name, id, version)
+		if err != nil {
+			return err
+		}
+		return c.record(ctx, &domain.ConfigRevision{Action: domain.RevisionRenamed}, userID, config, res)
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return res, nil
 }
 
-func (c *configUsecase) AddSiteConfig(ctx context.Context, site_config *domain.SiteConfig, id string) error {
+// DeleteOne removes the config and everything pointing at it: its probe
+// incidents, and its sites on manual incidents, maintenance and status page
+// components. It all happens in a single transaction, and the workers hear
+// of it once that commits, from the config.removed event.
+func (c *configUsecase) DeleteOne(ctx context.Context, id string, version int64, userID string) error {
 
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
-	err := c.configRepo.AddSiteConfig(ctx, site_config, id)
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(version, config.Version)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return c.transact(ctx, func(ctx context.Context) error {
+		return c.remove(ctx, config, version, userID)
+	})
 }
 
-func (c *configUsecase) RemoveSiteConfig(ctx context.Context, site_url string, id string) error {
+// remove deletes the config and cleans up after it, recording the deletion.
+func (c *configUsecase) remove(ctx context.Context, config *domain.ConfigDetails, version int64, userID string) error {
+	err := c.configRepo.DeleteOne(ctx, config.ID.Hex(), version)
+	if err != nil {
+		return err
+	}
+
+	err = c.incidentRepo.RemoveConfig(ctx, config.ID)
+	if err != nil {
+		return err
+	}
+	err = c.maintenanceRepo.RemoveConfig(ctx, config.ID)
+	if err != nil {
+		return err
+	}
+	err = c.statusPageRepo.RemoveConfig(ctx, config.ID)
+	if err != nil {
+		return err
+	}
+
+	return c.record(ctx, &domain.ConfigRevision{Action: domain.RevisionDeleted}, userID, config, nil)
+}
+
+func (c *configUsecase) AddSiteConfig(ctx context.Context, site_config *domain.SiteConfig, id string, userID string) (*domain.SiteConfig, error) {
 
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
-	err := c.configRepo.RemoveSiteConfig(ctx, site_url, id)
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	site_config.ID = primitive.NewObjectID()
+	err = c.transact(ctx, func(ctx context.Context) error {
+		err := c.addSite(ctx, config, site_config)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(ctx, domain.RevisionSiteAdded, userID, config, &site_config.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return site_config, nil
+}
+
+// addSite stores a new site under the ID it was given. It starts in the
+// unknown state with a fresh badge token.
+func (c *configUsecase) addSite(ctx context.Context, config *domain.ConfigDetails, site_config *domain.SiteConfig) error {
+	if findSiteByURL(config, site_config.SiteUrl) != nil {
+		return domain.ErrDuplicateSite
+	}
+	err := labels.Validate(site_config.Labels)
 	if err != nil {
 		return err
 	}
 
+	token, err := tokenutil.CreateRandomToken()
+	if err != nil {
+		return err
+	}
+	site_config.Version = 1
+	site_config.ConfigID = config.ID
+	site_config.UserID = config.UserID
+	site_config.Status = domain.SiteUnknown
+	site_config.RegionDetails = nil
+	site_config.CertificateExpiresAt = nil
+	site_config.BadgeToken = token
+
+	return c.configRepo.AddSiteConfig(ctx, site_config, config.ID.Hex())
+}
+
+func (c *configUsecase) FindSite(ctx context.Context, siteID string, id string, userID string) (*domain.SiteConfig, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	site := findSite(config, siteID)
+	if site == nil {
+		return nil, domain.ErrSiteNotFound
+	}
+
+	return site, nil
+}
+
+func findSite(config *domain.ConfigDetails, siteID string) *domain.SiteConfig {
+	for i := range config.SiteConfig {
+		if config.SiteConfig[i].ID.Hex() == siteID {
+			return &config.SiteConfig[i]
+		}
+	}
+	return nil
+}
+
+func findSiteByURL(config *domain.ConfigDetails, siteUrl string) *domain.SiteConfig {
+	for i := range config.SiteConfig {
+		if config.SiteConfig[i].SiteUrl == siteUrl {
+			return &config.SiteConfig[i]
+		}
+	}
 	return nil
 }
 
-func (c *configUsecase) UpdateSiteConfig(ctx context.Context, site_config *domain.SiteConfig, id string) error {
+// RemoveSiteConfig removes the site along with its place on maintenance
+// windows and status page components, and resolves its open incidents.
+func (c *configUsecase) RemoveSiteConfig(ctx context.Context, siteID string, id string, version int64, userID string) error {
 
 	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
 	defer cancel()
 
-	err := c.configRepo.UpdateSiteConfig(ctx, site_config, id)
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	site := findSite(config, siteID)
+	if site == nil {
+		return domain.ErrSiteNotFound
+	}
+	err = checkVersion(version, site.Version)
 	if err != nil {
 		return err
 	}
 
+	return c.transact(ctx, func(ctx context.Context) error {
+		err := c.removeSite(ctx, config, site, version)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(ctx, domain.RevisionSiteRemoved, userID, config, &site.ID)
+	})
+}
+
+func (c *configUsecase) removeSite(ctx context.Context, config *domain.ConfigDetails, site *domain.SiteConfig, version int64) error {
+	err := c.configRepo.RemoveSiteConfig(ctx, site.ID.Hex(), config.ID.Hex(), version)
+	if err != nil {
+		return err
+	}
+
+	err = c.incidentRepo.ResolveSite(ctx, config.ID, site.SiteUrl, time.Now())
+	if err != nil {
+		return err
+	}
+	err = c.maintenanceRepo.RemoveSite(ctx, config.ID, site.SiteUrl)
+	if err != nil {
+		return err
+	}
+
+	return c.statusPageRepo.RemoveSite(ctx, config.ID, site.SiteUrl)
+}
+
+// UpdateSiteConfig replaces the site's settings. The ID and badge token
+// carry over so embedded badges keep working; status and probe results
+// carry over unless the URL changed, in which case maintenance windows and
+// status page components follow the site to its new URL and the incidents
+// still open for the old one are resolved.
+func (c *configUsecase) UpdateSiteConfig(ctx context.Context, site_config *domain.SiteConfig, siteID string, id string, version int64, userID string) (*domain.SiteConfig, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	existing := findSite(config, siteID)
+	if existing == nil {
+		return nil, domain.ErrSiteNotFound
+	}
+	err = checkVersion(version, existing.Version)
+	if err != nil {
+		return nil, err
+	}
+
+	err = c.transact(ctx, func(ctx context.Context) error {
+		err := c.updateSite(ctx, config, existing, site_config, version)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(ctx, domain.RevisionSiteUpdated, userID, config, &existing.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return site_config, nil
+}
+
+func (c *configUsecase) updateSite(ctx context.Context, config *domain.ConfigDetails, existing *domain.SiteConfig, site_config *domain.SiteConfig, version int64) error {
+	if other := findSiteByURL(config, site_config.SiteUrl); other != nil && other.ID != existing.ID {
+		return domain.ErrDuplicateSite
+	}
+	err := labels.Validate(site_config.Labels)
+	if err != nil {
+		return err
+	}
+
+	site_config.ID = existing.ID
+	site_config.ConfigID = existing.ConfigID
+	site_config.UserID = existing.UserID
+	site_config.BadgeToken = existing.BadgeToken
+	renamed := site_config.SiteUrl != existing.SiteUrl
+	if renamed {
+		site_config.Status = domain.SiteUnknown
+		site_config.RegionDetails = nil
+		site_config.CertificateExpiresAt = nil
+	} else {
+		site_config.Status = existing.Status
+		site_config.RegionDetails = existing.RegionDetails
+		site_config.CertificateExpiresAt = existing.CertificateExpiresAt
+	}
+
+	err = c.configRepo.UpdateSiteConfig(ctx, site_config, config.ID.Hex(), version)
+	if err != nil {
+		return err
+	}
+	site_config.Version = existing.Version + 1
+
+	if renamed {
+		err = c.incidentRepo.ResolveSite(ctx, config.ID, existing.SiteUrl, time.Now())
+		if err != nil {
+			return err
+		}
+		err = c.maintenanceRepo.RenameSite(ctx, config.ID, existing.SiteUrl, site_config.SiteUrl)
+		if err != nil {
+			return err
+		}
+		err = c.statusPageRepo.RenameSite(ctx, config.ID, existing.SiteUrl, site_config.SiteUrl)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (c *configUsecase) SetNotificationTargets(ctx context.Context, targets []domain.NotificationTarget, id string, version int64, userID string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	for _, target := range targets {
+		err := c.checkTarget(ctx, target, userID)
+		if err != nil {
+			return err
+		}
+	}
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(version, config.Version)
+	if err != nil {
+		return err
+	}
+
+	return c.transact(ctx, func(ctx context.Context) error {
+		err := c.configRepo.SetNotificationTargets(ctx, targets, id, version)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(ctx, domain.RevisionNotificationsUpdated, userID, config, nil)
+	})
+}
+
+// SetLabels replaces the labels of the config; its sites keep their own.
+func (c *configUsecase) SetLabels(ctx context.Context, set map[string]string, id string, version int64, userID string) error {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	err := labels.Validate(set)
+	if err != nil {
+		return err
+	}
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	err = checkVersion(version, config.Version)
+	if err != nil {
+		return err
+	}
+
+	return c.transact(ctx, func(ctx context.Context) error {
+		err := c.configRepo.SetLabels(ctx, set, id, version)
+		if err != nil {
+			return err
+		}
+		return c.recordChange(ctx, domain.RevisionLabelsUpdated, userID, config, nil)
+	})
+}
+
+// checkTarget refuses targets naming nobody, and user and schedule targets
+// the caller may not notify.
+func (c *configUsecase) checkTarget(ctx context.Context, target domain.NotificationTarget, userID string) error {
+	if target.UserID == nil && target.ScheduleID == nil && target.Address == "" {
+		return errors.New("notification target needs a user_id, schedule_id or address")
+	}
+	if target.UserID == nil && target.ScheduleID == nil {
+		return nil
+	}
+	return c.onCallUsecase.CheckTarget(ctx, &target, userID)
+}
+
+// RotateBadgeToken issues a new badge token for a site, invalidating the old
+// badge URLs. Sites created before badges existed get their first token here.
+func (c *configUsecase) RotateBadgeToken(ctx context.Context, siteID string, id string, userID string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return "", err
+	}
+	if findSite(config, siteID) == nil {
+		return "", domain.ErrSiteNotFound
+	}
+
+	token, err := tokenutil.CreateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = c.configRepo.SetBadgeToken(ctx, siteID, token, id)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RotateFeedToken issues a new token for the config's incident feeds,
+// invalidating the old feed URLs.
+func (c *configUsecase) RotateFeedToken(ctx context.Context, id string, userID string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	_, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := tokenutil.CreateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = c.configRepo.SetFeedToken(ctx, token, id)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// RotateIngestToken issues a new token for reporting probe events on the
+// config's sites, invalidating the old one, and passes it on to the probe
+// workers in a config.ingest_token_rotated event.
+func (c *configUsecase) RotateIngestToken(ctx context.Context, id string, userID string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return "", err
+	}
+
+	var token string
+	err = c.transact(ctx, func(ctx context.Context) error {
+		token, err = c.rotateIngestToken(ctx, config)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// BackfillIngestTokens gives a token to every config created before probe
+// events needed one. The events passing the tokens on are left in the
+// outbox for the server to publish.
+func (c *configUsecase) BackfillIngestTokens(ctx context.Context) (int64, error) {
+	configs, err := c.configRepo.GetWithoutIngestToken(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	var issued int64
+	for i := range configs {
+		err = c.configRepo.Transaction(ctx, func(ctx context.Context) error {
+			_, err := c.rotateIngestToken(ctx, &configs[i])
+			return err
+		})
+		if err != nil {
+			return issued, err
+		}
+		issued++
+	}
+
+	return issued, nil
+}
+
+// rotateIngestToken sets a new token on config and stores the event
+// announcing it.
+func (c *configUsecase) rotateIngestToken(ctx context.Context, config *domain.ConfigDetails) (string, error) {
+	token, err := tokenutil.CreateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = c.configRepo.SetIngestToken(ctx, token, config.ID.Hex())
+	if err != nil {
+		return "", err
+	}
+
+	var revision int64
+	latest, err := c.revisionRepo.GetByConfigID(ctx, config.ID, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(latest) > 0 {
+		revision = latest[0].Number
+	}
+
+	event := domain.ConfigEvent{
+		SchemaVersion: domain.ConfigEventSchemaVersion,
+		ID:            primitive.NewObjectID(),
+		Type:          domain.ConfigEventIngestTokenRotated,
+		ConfigID:      config.ID,
+		UserID:        config.UserID,
+		Revision:      revision,
+		OccurredAt:    time.Now(),
+		Payload:       domain.ConfigEventIngestToken{IngestToken: token},
+	}
+	err = c.emit(ctx, []domain.ConfigEvent{event})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
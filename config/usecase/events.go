@@ -0,0 +1,205 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/rabbitmq"
+)
+
+// outboxBatch is how many pending events one PublishPending call sends.
+const outboxBatch = 100
+
+// transact runs fn in a transaction, so a change, its revision and the
+// events announcing it are stored together or not at all, and then
+// publishes the events. The change is saved by then, so a publish failure
+// only leaves the events in the outbox for PublishPending to send later.
+func (c *configUsecase) transact(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := c.configRepo.Transaction(ctx, fn)
+	if err != nil {
+		return err
+	}
+
+	err = c.PublishPending(ctx)
+	if err != nil {
+		log.Printf("publishing config events: %v", err)
+	}
+	return nil
+}
+
+// emit stores events in the outbox, within the transaction of the change
+// they announce.
+func (c *configUsecase) emit(ctx context.Context, events []domain.ConfigEvent) error {
+	messages := make([]domain.OutboxMessage, 0, len(events))
+	for i := range events {
+		event := &events[i]
+		body, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		headers := map[string]interface{}{
+			"schema_version": int32(event.SchemaVersion),
+			"event_type":     event.Type,
+			"config_id":      event.ConfigID.Hex(),
+		}
+		if event.SiteID != nil {
+			headers["site_id"] = event.SiteID.Hex()
+		}
+
+		messages = append(messages, domain.OutboxMessage{
+			ID:         event.ID,
+			Type:       event.Type,
+			Body:       body,
+			Headers:    headers,
+			OccurredAt: event.OccurredAt,
+		})
+	}
+
+	return c.outboxRepo.InsertMany(ctx, messages)
+}
+
+// PublishPending sends the events waiting in the outbox, oldest first, and
+// stops at the first one RabbitMQ does not take so none overtakes another.
+// Only the holder of the outbox lease publishes; anyone else returns at once
+// and leaves the events to it. An event is removed once sent, so it goes
+// out twice only if the server sending it dies in between.
+func (c *configUsecase) PublishPending(ctx context.Context) error {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	// The lease outlasts ctx, so whatever this call still has in flight
+	// when ctx ends is done before another server may start.
+	claimed, err := c.outboxRepo.Claim(ctx, c.publisherID, time.Now().Add(2*c.contextTimeout))
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return nil
+	}
+	defer func() {
+		err := c.outboxRepo.Release(context.Background(), c.publisherID)
+		if err != nil {
+			log.Printf("releasing the config event outbox: %v", err)
+		}
+	}()
+
+	messages, err := c.outboxRepo.GetPending(ctx, outboxBatch)
+	if err != nil {
+		return err
+	}
+
+	for _, message := range messages {
+		err = c.amqpPublisher.PublishMessage(rabbitmq.Message{
+			Body:        message.Body,
+			ContentType: domain.ConfigEventContentType,
+			Type:        message.Type,
+			MessageID:   message.ID.Hex(),
+			Timestamp:   message.OccurredAt,
+			Headers:     message.Headers,
+			Persistent:  true,
+		})
+		if err != nil {
+			return err
+		}
+		err = c.outboxRepo.DeleteOne(ctx, message.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// configEvents turns a recorded revision into the events announcing it: one
+// for a created or deleted config, otherwise one per config setting and one
+// per site that changed.
+func configEvents(revision *domain.ConfigRevision, before *domain.ConfigDetails, after *domain.ConfigDetails) []domain.ConfigEvent {
+	config := after
+	if config == nil {
+		config = before
+	}
+	event := func(kind string, siteID *primitive.ObjectID, payload interface{}) domain.ConfigEvent {
+		return domain.ConfigEvent{
+			SchemaVersion: domain.ConfigEventSchemaVersion,
+			ID:            primitive.NewObjectID(),
+			Type:          kind,
+			ConfigID:      config.ID,
+			SiteID:        siteID,
+			UserID:        config.UserID,
+			Revision:      revision.Number,
+			OccurredAt:    revision.CreatedAt,
+			Payload:       payload,
+		}
+	}
+
+	switch {
+	case before == nil:
+		return []domain.ConfigEvent{event(domain.ConfigEventCreated, nil, domain.ConfigEventConfig{Config: after})}
+	case after == nil:
+		return []domain.ConfigEvent{event(domain.ConfigEventRemoved, nil, domain.ConfigEventConfig{Config: before})}
+	}
+
+	res := []domain.ConfigEvent{}
+	sites := map[primitive.ObjectID][]domain.ConfigChange{}
+	order := []primitive.ObjectID{}
+	for _, change := range diff(revision.Before, revision.After) {
+		changes := domain.ConfigEventChanges{Changes: []domain.ConfigChange{change}}
+		switch change.Path {
+		case "name":
+			res = append(res, event(domain.ConfigEventRenamed, nil, changes))
+			continue
+		case "notification_targets":
+			res = append(res, event(domain.ConfigEventNotificationsUpdated, nil, changes))
+			continue
+		case "labels":
+			res = append(res, event(domain.ConfigEventLabelsUpdated, nil, changes))
+			continue
+		}
+
+		id, ok := changedSite(change.Path)
+		if !ok {
+			continue
+		}
+		if _, seen := sites[id]; !seen {
+			order = append(order, id)
+		}
+		sites[id] = append(sites[id], change)
+	}
+
+	for _, id := range order {
+		id := id
+		changes := sites[id]
+		switch changes[0].Op {
+		case domain.ChangeAdded:
+			res = append(res, event(domain.ConfigEventSiteAdded, &id, domain.ConfigEventSite{Site: findSite(after, id.Hex())}))
+		case domain.ChangeRemoved:
+			res = append(res, event(domain.ConfigEventSiteRemoved, &id, domain.ConfigEventSite{Site: findSite(before, id.Hex())}))
+		default:
+			res = append(res, event(domain.ConfigEventSiteUpdated, &id, domain.ConfigEventSite{Site: findSite(after, id.Hex()), Changes: changes}))
+		}
+	}
+
+	return res
+}
+
+// changedSite reads the site ID out of a change path like
+// "sites[<id>].tags".
+func changedSite(path string) (primitive.ObjectID, bool) {
+	rest, ok := strings.CutPrefix(path, "sites[")
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	hex, _, ok := strings.Cut(rest, "]")
+	if !ok {
+		return primitive.NilObjectID, false
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	return id, err == nil
+}
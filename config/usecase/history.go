@@ -0,0 +1,305 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+)
+
+// record stores a revision of the change userID made to a config and the
+// events announcing it. Before is nil for a new config and
+// after nil for a deleted one.
+func (c *configUsecase) record(ctx context.Context, revision *domain.ConfigRevision, userID string, before *domain.ConfigDetails, after *domain.ConfigDetails) error {
+	actor, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return err
+	}
+
+	config := before
+	if config == nil {
+		config = after
+	}
+	revision.ID = primitive.NewObjectID()
+	revision.ConfigID = config.ID
+	revision.ActorID = actor
+	revision.CreatedAt = time.Now()
+	revision.Before = snapshot(before)
+	revision.After = snapshot(after)
+
+	_, err = c.revisionRepo.InsertOne(ctx, revision)
+	if err != nil {
+		return err
+	}
+
+	return c.emit(ctx, configEvents(revision, before, after))
+}
+
+// recordChange records a change made to before, reading what the config
+// looks like now.
+func (c *configUsecase) recordChange(ctx context.Context, action string, userID string, before *domain.ConfigDetails, siteID *primitive.ObjectID) error {
+	after, err := c.configRepo.FindOne(ctx, before.ID.Hex())
+	if err != nil {
+		return err
+	}
+	return c.record(ctx, &domain.ConfigRevision{Action: action, SiteID: siteID}, userID, before, after)
+}
+
+func snapshot(config *domain.ConfigDetails) *domain.ConfigSnapshot {
+	if config == nil {
+		return nil
+	}
+	res := &domain.ConfigSnapshot{
+		Name:                config.Name,
+		NotificationTargets: config.NotificationTargets,
+		Labels:              config.Labels,
+		Sites:               []domain.SiteSnapshot{},
+	}
+	for _, site := range config.SiteConfig {
+		res.Sites = append(res.Sites, domain.SiteSnapshot{
+			ID:      site.ID,
+			SiteUrl: site.SiteUrl,
+			Tags:    site.Tags,
+			Labels:  site.Labels,
+		})
+	}
+	return res
+}
+
+// same reports whether two lists hold the same items, an empty list being
+// the same as none.
+func same[T any](a, b []T) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+// sameLabels is same for label sets.
+func sameLabels(a, b map[string]string) bool {
+	return len(a) == 0 && len(b) == 0 || reflect.DeepEqual(a, b)
+}
+
+// diff lists the changes that turn before into after. Sites are matched by
+// ID, so a site that moved to a new URL shows as a changed site_url rather
+// than a removal and an addition.
+func diff(before *domain.ConfigSnapshot, after *domain.ConfigSnapshot) []domain.ConfigChange {
+	if before == nil {
+		before = &domain.ConfigSnapshot{}
+	}
+	if after == nil {
+		after = &domain.ConfigSnapshot{}
+	}
+
+	changes := []domain.ConfigChange{}
+	if before.Name != after.Name {
+		changes = append(changes, domain.ConfigChange{Path: "name", Op: domain.ChangeChanged, Before: before.Name, After: after.Name})
+	}
+	if !same(before.NotificationTargets, after.NotificationTargets) {
+		changes = append(changes, domain.ConfigChange{Path: "notification_targets", Op: domain.ChangeChanged, Before: before.NotificationTargets, After: after.NotificationTargets})
+	}
+	if !sameLabels(before.Labels, after.Labels) {
+		changes = append(changes, domain.ConfigChange{Path: "labels", Op: domain.ChangeChanged, Before: before.Labels, After: after.Labels})
+	}
+
+	old := map[primitive.ObjectID]domain.SiteSnapshot{}
+	for _, site := range before.Sites {
+		old[site.ID] = site
+	}
+	kept := map[primitive.ObjectID]bool{}
+	for _, site := range after.Sites {
+		path := "sites[" + site.ID.Hex() + "]"
+		prev, ok := old[site.ID]
+		if !ok {
+			changes = append(changes, domain.ConfigChange{Path: path, Op: domain.ChangeAdded, After: site})
+			continue
+		}
+		kept[site.ID] = true
+		if prev.SiteUrl != site.SiteUrl {
+			changes = append(changes, domain.ConfigChange{Path: path + ".site_url", Op: domain.ChangeChanged, Before: prev.SiteUrl, After: site.SiteUrl})
+		}
+		if !same(prev.Tags, site.Tags) {
+			changes = append(changes, domain.ConfigChange{Path: path + ".tags", Op: domain.ChangeChanged, Before: prev.Tags, After: site.Tags})
+		}
+		if !sameLabels(prev.Labels, site.Labels) {
+			changes = append(changes, domain.ConfigChange{Path: path + ".labels", Op: domain.ChangeChanged, Before: prev.Labels, After: site.Labels})
+		}
+	}
+	for _, site := range before.Sites {
+		if !kept[site.ID] {
+			changes = append(changes, domain.ConfigChange{Path: "sites[" + site.ID.Hex() + "]", Op: domain.ChangeRemoved, Before: site})
+		}
+	}
+
+	return changes
+}
+
+// GetHistory returns the config's most recent revisions, newest first.
+func (c *configUsecase) GetHistory(ctx context.Context, id string, limit int64, userID string) ([]domain.ConfigRevision, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.revisionRepo.GetByConfigID(ctx, config.ID, limit)
+}
+
+func (c *configUsecase) GetRevision(ctx context.Context, id string, number int64, userID string) (*domain.ConfigRevision, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.revisionRepo.FindOne(ctx, config.ID, number)
+}
+
+// Diff compares the config as revision from left it with the config as
+// revision to left it, or with the config as it is now when to is 0.
+func (c *configUsecase) Diff(ctx context.Context, id string, from int64, to int64, userID string) ([]domain.ConfigChange, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	config, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	left, err := c.revisionRepo.FindOne(ctx, config.ID, from)
+	if err != nil {
+		return nil, err
+	}
+	right := snapshot(config)
+	if to != 0 {
+		revision, err := c.revisionRepo.FindOne(ctx, config.ID, to)
+		if err != nil {
+			return nil, err
+		}
+		right = revision.After
+	}
+
+	return diff(left.After, right), nil
+}
+
+// Rollback puts the config back the way the given revision left it and
+// records that as a new revision. Sites keep their IDs: sites added since
+// are removed, removed ones come back with a new badge token, and changed
+// ones get their URL, tags and labels back.
+func (c *configUsecase) Rollback(ctx context.Context, id string, number int64, userID string) (*domain.ConfigDetails, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	before, err := c.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := c.revisionRepo.FindOne(ctx, before.ID, number)
+	if err != nil {
+		return nil, err
+	}
+	target := revision.After
+	if target == nil {
+		return nil, fmt.Errorf("revision %d deleted the config and cannot be restored", number)
+	}
+
+	var after *domain.ConfigDetails
+	err = c.transact(ctx, func(ctx context.Context) error {
+		after, err = c.restore(ctx, before, target, number, userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return after, nil
+}
+
+// restore brings the config back to target, the state revision number
+// recorded, and records the rollback.
+func (c *configUsecase) restore(ctx context.Context, before *domain.ConfigDetails, target *domain.ConfigSnapshot, number int64, userID string) (*domain.ConfigDetails, error) {
+	var err error
+	id := before.ID.Hex()
+
+	if target.Name != before.Name {
+		_, err = c.configRepo.Rename(ctx, target.Name, id, domain.AnyVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !same(target.NotificationTargets, before.NotificationTargets) {
+		err = c.configRepo.SetNotificationTargets(ctx, target.NotificationTargets, id, domain.AnyVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !sameLabels(target.Labels, before.Labels) {
+		err = c.configRepo.SetLabels(ctx, target.Labels, id, domain.AnyVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Removals go first and additions last so a URL freed by one step is
+	// free for the next.
+	wanted := map[primitive.ObjectID]bool{}
+	for _, site := range target.Sites {
+		wanted[site.ID] = true
+	}
+	for i := range before.SiteConfig {
+		if !wanted[before.SiteConfig[i].ID] {
+			err = c.removeSite(ctx, before, &before.SiteConfig[i], domain.AnyVersion)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	config, err := c.configRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, site := range target.Sites {
+		existing := findSite(config, site.ID.Hex())
+		if existing == nil || existing.SiteUrl == site.SiteUrl && same(existing.Tags, site.Tags) && sameLabels(existing.Labels, site.Labels) {
+			continue
+		}
+		err = c.updateSite(ctx, config, existing, &domain.SiteConfig{SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels}, domain.AnyVersion)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	config, err = c.configRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	for _, site := range target.Sites {
+		if findSite(config, site.ID.Hex()) != nil {
+			continue
+		}
+		err = c.addSite(ctx, config, &domain.SiteConfig{ID: site.ID, SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	after, err := c.configRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	err = c.record(ctx, &domain.ConfigRevision{Action: domain.RevisionRolledBack, RestoredFrom: number}, userID, before, after)
+	if err != nil {
+		return nil, err
+	}
+
+	return after, nil
+}
@@ -0,0 +1,74 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/uptime"
+)
+
+// GetSites lists the sites matching filter across all configs.
+func (c *configUsecase) GetSites(ctx context.Context, filter interface{}) ([]domain.SiteConfig, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	return c.configRepo.GetSites(ctx, filter)
+}
+
+// StatusByLabel groups the sites matching filter by their value of the
+// label key and sums up each group: how many sites are up, down or not yet
+// probed, and their average uptime over the last days days, counted from
+// when a site was added if that is later.
+func (c *configUsecase) StatusByLabel(ctx context.Context, key string, filter interface{}, days int, userID string) ([]domain.LabelStatus, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	owner, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	sites, err := c.configRepo.GetSites(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	since := now.AddDate(0, 0, -days)
+	incidents, err := c.incidentRepo.GetOverlapping(ctx, owner, since, now)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := map[string]*domain.LabelStatus{}
+	for i := range sites {
+		site := &sites[i]
+		value := site.Labels[key]
+		group, ok := groups[value]
+		if !ok {
+			group = &domain.LabelStatus{Key: key, Value: value}
+			groups[value] = group
+		}
+
+		countSite(&group.SiteCounts, site)
+
+		from := since
+		if added := site.ID.Timestamp(); added.After(from) {
+			from = added
+		}
+		group.Uptime += uptime.Percent(uptime.ForSite(incidents, site.ConfigID.Hex(), site.SiteUrl), from, now)
+	}
+
+	res := []domain.LabelStatus{}
+	for _, group := range groups {
+		group.Uptime /= float64(group.SiteCounts.Total)
+		res = append(res, *group)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Value < res[j].Value })
+
+	return res, nil
+}
@@ -0,0 +1,265 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/labels"
+)
+
+// Export returns all of the user's configs as a document Import accepts.
+func (c *configUsecase) Export(ctx context.Context, userID string) (*domain.MonitorDocument, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	configs, err := c.configRepo.GetAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &domain.MonitorDocument{Configs: []domain.ConfigDocument{}}
+	for _, config := range configs {
+		entry := domain.ConfigDocument{
+			Name:                config.Name,
+			Labels:              config.Labels,
+			NotificationTargets: config.NotificationTargets,
+			Sites:               []domain.SiteDocument{},
+		}
+		for _, site := range config.SiteConfig {
+			entry.Sites = append(entry.Sites, domain.SiteDocument{SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels})
+		}
+		doc.Configs = append(doc.Configs, entry)
+	}
+
+	return doc, nil
+}
+
+// validate lists every problem with doc rather than stopping at the first,
+// so a file can be fixed in one go.
+func (c *configUsecase) validate(ctx context.Context, doc *domain.MonitorDocument, userID string) []domain.RowError {
+	errs := []domain.RowError{}
+	if len(doc.Configs) == 0 {
+		return append(errs, domain.RowError{Row: "file", Field: "configs", Error: "the file has no configs"})
+	}
+
+	names := map[string]string{}
+	for _, config := range doc.Configs {
+		name := strings.TrimSpace(config.Name)
+		if name == "" {
+			errs = append(errs, domain.RowError{Row: config.Row, Field: "name", Error: "a config needs a name"})
+		} else if row, ok := names[name]; ok && row != config.Row {
+			errs = append(errs, domain.RowError{Row: config.Row, Field: "name", Error: "config " + name + " is already in " + row})
+		} else {
+			names[name] = config.Row
+		}
+		err := labels.Validate(config.Labels)
+		if err != nil {
+			errs = append(errs, domain.RowError{Row: config.Row, Field: "labels", Error: err.Error()})
+		}
+
+		for _, target := range config.NotificationTargets {
+			switch target.Channel {
+			case domain.ChannelEmail, domain.ChannelSlack, domain.ChannelWebhook:
+			default:
+				errs = append(errs, domain.RowError{Row: config.Row, Field: "notification_targets", Error: "channel must be one of email, slack, webhook"})
+				continue
+			}
+			err := c.checkTarget(ctx, target, userID)
+			if err != nil {
+				errs = append(errs, domain.RowError{Row: config.Row, Field: "notification_targets", Error: err.Error()})
+			}
+		}
+
+		urls := map[string]string{}
+		for _, site := range config.Sites {
+			err := checkSiteUrl(site.SiteUrl)
+			if err != nil {
+				errs = append(errs, domain.RowError{Row: site.Row, Field: "site_url", Error: err.Error()})
+				continue
+			}
+			if row, ok := urls[site.SiteUrl]; ok {
+				errs = append(errs, domain.RowError{Row: site.Row, Field: "site_url", Error: site.SiteUrl + " is already in " + row})
+				continue
+			}
+			urls[site.SiteUrl] = site.Row
+			err = labels.Validate(site.Labels)
+			if err != nil {
+				errs = append(errs, domain.RowError{Row: site.Row, Field: "labels", Error: err.Error()})
+			}
+		}
+	}
+
+	return errs
+}
+
+func checkSiteUrl(siteUrl string) error {
+	if siteUrl == "" {
+		return errors.New("a site needs a url")
+	}
+	u, err := url.Parse(siteUrl)
+	if err != nil || u.Host == "" || u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("site url must be an absolute http or https url")
+	}
+	return nil
+}
+
+// Import applies doc to the user's configs in a single transaction, so a
+// failure part way leaves nothing behind. Configs are matched by name and
+// sites by URL: missing ones are created, the tags of existing sites are
+// replaced, their labels and notification targets too when the file gives
+// them, and nothing the file leaves out is deleted.
+func (c *configUsecase) Import(ctx context.Context, doc *domain.MonitorDocument, userID string) (*domain.ImportResult, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	errs := c.validate(ctx, doc, userID)
+	if len(errs) > 0 {
+		return nil, &domain.ImportError{Errors: errs}
+	}
+	owner, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	_, err = c.userRepo.FindOne(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	var result *domain.ImportResult
+	// The transaction may be retried, so everything it reads and counts is
+	// set up again inside it.
+	err = c.transact(ctx, func(ctx context.Context) error {
+		result = &domain.ImportResult{}
+
+		configs, err := c.configRepo.GetAllByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		byName, errs := matchNames(configs, doc)
+		if len(errs) > 0 {
+			return &domain.ImportError{Errors: errs}
+		}
+
+		for _, entry := range doc.Configs {
+			name := strings.TrimSpace(entry.Name)
+			config, ok := byName[name]
+			if !ok {
+				config = &domain.ConfigDetails{
+					Name:                name,
+					UserID:              owner,
+					Labels:              entry.Labels,
+					NotificationTargets: entry.NotificationTargets,
+					SiteConfig:          []domain.SiteConfig{},
+				}
+				for _, site := range entry.Sites {
+					config.SiteConfig = append(config.SiteConfig, domain.SiteConfig{SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels})
+				}
+				_, err = c.insert(ctx, config)
+				if err != nil {
+					return err
+				}
+				result.ConfigsCreated++
+				result.SitesCreated += len(entry.Sites)
+				continue
+			}
+
+			changed, err := c.merge(ctx, config, entry, result)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			err = c.recordChange(ctx, domain.RevisionImported, userID, config, nil)
+			if err != nil {
+				return err
+			}
+			result.ConfigsUpdated++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// matchNames indexes configs by name, which is how the entries of doc find
+// the config they describe. Names are not unique, and an entry whose name
+// more than one config has is a row error rather than a guess.
+func matchNames(configs []domain.ConfigDetails, doc *domain.MonitorDocument) (map[string]*domain.ConfigDetails, []domain.RowError) {
+	res := map[string]*domain.ConfigDetails{}
+	count := map[string]int{}
+	for i := range configs {
+		res[configs[i].Name] = &configs[i]
+		count[configs[i].Name]++
+	}
+
+	errs := []domain.RowError{}
+	for _, entry := range doc.Configs {
+		name := strings.TrimSpace(entry.Name)
+		if count[name] > 1 {
+			errs = append(errs, domain.RowError{Row: entry.Row, Field: "name", Error: strconv.Itoa(count[name]) + " configs are named " + name + ", rename all but one first"})
+		}
+	}
+
+	return res, errs
+}
+
+// merge brings an existing config in line with its entry of an imported
+// file and reports whether anything changed.
+func (c *configUsecase) merge(ctx context.Context, config *domain.ConfigDetails, entry domain.ConfigDocument, result *domain.ImportResult) (bool, error) {
+	changed := false
+
+	if len(entry.NotificationTargets) > 0 && !same(entry.NotificationTargets, config.NotificationTargets) {
+		err := c.configRepo.SetNotificationTargets(ctx, entry.NotificationTargets, config.ID.Hex(), domain.AnyVersion)
+		if err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+	if entry.Labels != nil && !sameLabels(entry.Labels, config.Labels) {
+		err := c.configRepo.SetLabels(ctx, entry.Labels, config.ID.Hex(), domain.AnyVersion)
+		if err != nil {
+			return changed, err
+		}
+		changed = true
+	}
+
+	for _, site := range entry.Sites {
+		existing := findSiteByURL(config, site.SiteUrl)
+		if existing == nil {
+			err := c.addSite(ctx, config, &domain.SiteConfig{ID: primitive.NewObjectID(), SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels})
+			if err != nil {
+				return changed, err
+			}
+			result.SitesCreated++
+			changed = true
+			continue
+		}
+		set := site.Labels
+		if set == nil {
+			set = existing.Labels
+		}
+		if same(existing.Tags, site.Tags) && sameLabels(existing.Labels, set) {
+			continue
+		}
+		err := c.updateSite(ctx, config, existing, &domain.SiteConfig{SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: set}, domain.AnyVersion)
+		if err != nil {
+			return changed, err
+		}
+		result.SitesUpdated++
+		changed = true
+	}
+
+	return changed, nil
+}
@@ -0,0 +1,237 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+)
+
+// Plan works out what Apply would change to make the user's configs match
+// doc, without changing anything.
+func (c *configUsecase) Plan(ctx context.Context, doc *domain.MonitorDocument, prune bool, userID string) (*domain.Plan, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	errs := c.validate(ctx, doc, userID)
+	if len(errs) > 0 {
+		return nil, &domain.ImportError{Errors: errs}
+	}
+
+	return c.converge(ctx, doc, prune, userID, false)
+}
+
+// Apply makes the user's configs match doc in a single transaction and
+// returns the steps it took. Applying the same document again takes none.
+func (c *configUsecase) Apply(ctx context.Context, doc *domain.MonitorDocument, prune bool, userID string) (*domain.Plan, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, c.contextTimeout)
+	defer cancel()
+
+	errs := c.validate(ctx, doc, userID)
+	if len(errs) > 0 {
+		return nil, &domain.ImportError{Errors: errs}
+	}
+	_, err := c.userRepo.FindOne(ctx, userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	var plan *domain.Plan
+	err = c.transact(ctx, func(ctx context.Context) error {
+		plan, err = c.converge(ctx, doc, prune, userID, true)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return plan, nil
+}
+
+// converge compares the user's configs with doc, matching configs by name
+// and sites by URL, and applies each step it plans when apply is set.
+// Notification targets and labels the document leaves out are left alone,
+// while an empty list or map clears them. A document naming a config the
+// user has more than once fails the plan, so no step lands on the wrong one.
+func (c *configUsecase) converge(ctx context.Context, doc *domain.MonitorDocument, prune bool, userID string, apply bool) (*domain.Plan, error) {
+	configs, err := c.configRepo.GetAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	owner, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	byName, errs := matchNames(configs, doc)
+	if len(errs) > 0 {
+		return nil, &domain.ImportError{Errors: errs}
+	}
+
+	plan := &domain.Plan{Prune: prune, Changes: []domain.PlanChange{}}
+	step := func(change domain.PlanChange) {
+		plan.Changes = append(plan.Changes, change)
+		switch change.Action {
+		case domain.PlanCreate:
+			plan.Summary.Create++
+		case domain.PlanUpdate:
+			plan.Summary.Update++
+		case domain.PlanDelete:
+			plan.Summary.Delete++
+		}
+	}
+
+	wanted := map[string]bool{}
+	for _, entry := range doc.Configs {
+		name := strings.TrimSpace(entry.Name)
+		wanted[name] = true
+		config, ok := byName[name]
+		if !ok {
+			step(domain.PlanChange{Action: domain.PlanCreate, Config: name})
+			for _, site := range entry.Sites {
+				step(domain.PlanChange{Action: domain.PlanCreate, Config: name, SiteUrl: site.SiteUrl, After: site})
+			}
+			if !apply {
+				continue
+			}
+			config = &domain.ConfigDetails{
+				Name:                name,
+				UserID:              owner,
+				Labels:              entry.Labels,
+				NotificationTargets: entry.NotificationTargets,
+				SiteConfig:          []domain.SiteConfig{},
+			}
+			for _, site := range entry.Sites {
+				config.SiteConfig = append(config.SiteConfig, domain.SiteConfig{SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels})
+			}
+			_, err = c.insert(ctx, config)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		id := config.ID
+		changed := false
+		if entry.NotificationTargets != nil && !same(entry.NotificationTargets, config.NotificationTargets) {
+			step(domain.PlanChange{Action: domain.PlanUpdate, Config: name, ConfigID: &id, Field: "notification_targets", Before: config.NotificationTargets, After: entry.NotificationTargets})
+			if apply {
+				err = c.configRepo.SetNotificationTargets(ctx, entry.NotificationTargets, id.Hex(), domain.AnyVersion)
+				if err != nil {
+					return nil, err
+				}
+			}
+			changed = true
+		}
+		if entry.Labels != nil && !sameLabels(entry.Labels, config.Labels) {
+			step(domain.PlanChange{Action: domain.PlanUpdate, Config: name, ConfigID: &id, Field: "labels", Before: config.Labels, After: entry.Labels})
+			if apply {
+				err = c.configRepo.SetLabels(ctx, entry.Labels, id.Hex(), domain.AnyVersion)
+				if err != nil {
+					return nil, err
+				}
+			}
+			changed = true
+		}
+
+		// Sites are removed before any are added so a config never holds
+		// more sites than either side of the plan.
+		urls := map[string]bool{}
+		for _, site := range entry.Sites {
+			urls[site.SiteUrl] = true
+		}
+		if prune {
+			for i := range config.SiteConfig {
+				site := &config.SiteConfig[i]
+				if urls[site.SiteUrl] {
+					continue
+				}
+				siteID := site.ID
+				step(domain.PlanChange{Action: domain.PlanDelete, Config: name, ConfigID: &id, SiteUrl: site.SiteUrl, SiteID: &siteID, Before: planSite(site)})
+				if apply {
+					err = c.removeSite(ctx, config, site, domain.AnyVersion)
+					if err != nil {
+						return nil, err
+					}
+				}
+				changed = true
+			}
+		}
+
+		for _, site := range entry.Sites {
+			existing := findSiteByURL(config, site.SiteUrl)
+			if existing == nil {
+				step(domain.PlanChange{Action: domain.PlanCreate, Config: name, ConfigID: &id, SiteUrl: site.SiteUrl, After: site})
+				if apply {
+					err = c.addSite(ctx, config, &domain.SiteConfig{ID: primitive.NewObjectID(), SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels})
+					if err != nil {
+						return nil, err
+					}
+				}
+				changed = true
+				continue
+			}
+			set := site.Labels
+			if set == nil {
+				set = existing.Labels
+			}
+			if same(existing.Tags, site.Tags) && sameLabels(existing.Labels, set) {
+				continue
+			}
+			siteID := existing.ID
+			if !same(existing.Tags, site.Tags) {
+				step(domain.PlanChange{Action: domain.PlanUpdate, Config: name, ConfigID: &id, SiteUrl: site.SiteUrl, SiteID: &siteID, Field: "tags", Before: existing.Tags, After: site.Tags})
+			}
+			if !sameLabels(existing.Labels, set) {
+				step(domain.PlanChange{Action: domain.PlanUpdate, Config: name, ConfigID: &id, SiteUrl: site.SiteUrl, SiteID: &siteID, Field: "labels", Before: existing.Labels, After: set})
+			}
+			if apply {
+				err = c.updateSite(ctx, config, existing, &domain.SiteConfig{SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: set}, domain.AnyVersion)
+				if err != nil {
+					return nil, err
+				}
+			}
+			changed = true
+		}
+
+		if apply && changed {
+			err = c.recordChange(ctx, domain.RevisionApplied, userID, config, nil)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if !prune {
+		return plan, nil
+	}
+	for i := range configs {
+		config := &configs[i]
+		if wanted[config.Name] {
+			continue
+		}
+		id := config.ID
+		step(domain.PlanChange{Action: domain.PlanDelete, Config: config.Name, ConfigID: &id})
+		for _, site := range config.SiteConfig {
+			siteID := site.ID
+			step(domain.PlanChange{Action: domain.PlanDelete, Config: config.Name, ConfigID: &id, SiteUrl: site.SiteUrl, SiteID: &siteID, Before: planSite(&site)})
+		}
+		if apply {
+			err = c.remove(ctx, config, domain.AnyVersion, userID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return plan, nil
+}
+
+// planSite shows a site the way the document describes it, so the steps
+// that create and delete sites carry their tags and labels alike.
+func planSite(site *domain.SiteConfig) domain.SiteDocument {
+	return domain.SiteDocument{SiteUrl: site.SiteUrl, Tags: site.Tags, Labels: site.Labels}
+}
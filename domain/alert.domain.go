@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	AlertStatusDown = "down"
+	AlertStatusUp   = "up"
+)
+
+const (
+	GroupByConfig = "config"
+	GroupByTag    = "tag"
+	GroupByHost   = "host"
+	GroupByRegion = "region"
+)
+
+// AlertEvent is the result of a probe of one site from one region. Every
+// result is stored as the region's latest one on the site; only those that
+// change the site's state go on to notify anyone. Latency is the response
+// time in milliseconds. CertificateExpiresAt is when the TLS certificate the
+// site presented expires; probes that did not look at it leave it out.
+type AlertEvent struct {
+	ConfigID   primitive.ObjectID `json:"config_id" validate:"required"`
+	SiteUrl    string             `json:"site_url" validate:"required"`
+	Region     string             `json:"region"`
+	Status     string             `json:"status" validate:"required,oneof=down up"`
+	Error      string             `json:"error"`
+	Latency    int64              `json:"latency_ms" validate:"gte=0"`
+	OccurredAt time.Time          `json:"occurred_at"`
+
+	CertificateExpiresAt *time.Time `json:"certificate_expires_at,omitempty"`
+}
+
+// AlertGroupingRule controls how a user's alerts are batched before they go
+// out. Events sharing the GroupBy keys within Window seconds become one
+// notification, and at most RateLimit notifications per channel are sent per
+// hour (0 disables the limit). Open incidents nobody has acknowledged are
+// re-notified every Renotify minutes (0 disables re-notification).
+type AlertGroupingRule struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	GroupBy   []string           `bson:"group_by" json:"group_by" validate:"dive,oneof=config tag host region"`
+	Window    int                `bson:"window_seconds" json:"window_seconds" validate:"gte=0,lte=3600"`
+	RateLimit int                `bson:"rate_limit_per_hour" json:"rate_limit_per_hour" validate:"gte=0"`
+	Renotify  int                `bson:"renotify_minutes" json:"renotify_minutes" validate:"gte=0"`
+}
+
+func DefaultAlertGroupingRule(userID primitive.ObjectID) *AlertGroupingRule {
+	return &AlertGroupingRule{
+		UserID:    userID,
+		GroupBy:   []string{GroupByConfig},
+		Window:    30,
+		RateLimit: 20,
+		Renotify:  30,
+	}
+}
+
+// Notification is a rendered message for one recipient, published to the
+// notification queue for the delivery workers.
+type Notification struct {
+	ID         primitive.ObjectID `json:"id"`
+	UserID     primitive.ObjectID `json:"user_id"`
+	ConfigID   primitive.ObjectID `json:"config_id"`
+	Recipient  Recipient          `json:"recipient"`
+	Subject    string             `json:"subject,omitempty"`
+	Body       string             `json:"body"`
+	EventCount int                `json:"event_count"`
+	Suppressed int                `json:"suppressed"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
+type AlertRuleRepository interface {
+	Upsert(ctx context.Context, rule *AlertGroupingRule) (*AlertGroupingRule, error)
+	FindByUserID(ctx context.Context, userID string) (*AlertGroupingRule, error)
+}
+
+// ErrInvalidIngestToken is returned for events whose token does not match
+// the ingest token of the config they report on.
+var ErrInvalidIngestToken = errors.New("invalid ingest token")
+
+type IngestTokenResponse struct {
+	IngestToken string `json:"ingest_token"`
+}
+
+type AlertUsecase interface {
+	Ingest(ctx context.Context, event *AlertEvent, token string) error
+	UpsertRule(ctx context.Context, rule *AlertGroupingRule) (*AlertGroupingRule, error)
+	GetRule(ctx context.Context, userID string) (*AlertGroupingRule, error)
+	Renotify(ctx context.Context) error
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	BadgeStyleFlat        = "flat"
+	BadgeStyleFlatSquare  = "flat-square"
+	BadgeStylePlastic     = "plastic"
+	BadgeStyleForTheBadge = "for-the-badge"
+)
+
+// Badge is the content of a shields-style badge; rendering it to SVG is left
+// to the transport so the same data can back other formats.
+type Badge struct {
+	Label   string `json:"label"`
+	Message string `json:"message"`
+	Color   string `json:"color"`
+}
+
+type BadgeTokenResponse struct {
+	BadgeToken string `json:"badge_token"`
+	UptimeURL  string `json:"uptime_url"`
+	StatusURL  string `json:"status_url"`
+}
+
+type BadgeUsecase interface {
+	Uptime(ctx context.Context, token string, period time.Duration) (*Badge, error)
+	Status(ctx context.Context, token string) (*Badge, error)
+}
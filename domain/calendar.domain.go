@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Calendar is the format-neutral content of an iCalendar feed. Event UIDs
+// depend only on what the event is about, so calendar apps update an event
+// in place when its times change.
+type Calendar struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	TimeZone    string          `json:"time_zone"`
+	Events      []CalendarEvent `json:"events"`
+}
+
+// CalendarEvent is a timed event in TimeZone, or a whole-day event when
+// AllDay is set. Alarm, when non-zero, reminds that long before Start.
+type CalendarEvent struct {
+	UID         string        `json:"uid"`
+	Summary     string        `json:"summary"`
+	Description string        `json:"description"`
+	URL         string        `json:"url"`
+	Start       time.Time     `json:"start"`
+	End         time.Time     `json:"end"`
+	AllDay      bool          `json:"all_day"`
+	TimeZone    string        `json:"time_zone"`
+	Recurrence  *Recurrence   `json:"recurrence,omitempty"`
+	Updated     time.Time     `json:"updated"`
+	Alarm       time.Duration `json:"alarm"`
+}
+
+type CalendarTokenResponse struct {
+	CalendarToken string `json:"calendar_token"`
+	URL           string `json:"url"`
+	WebcalURL     string `json:"webcal_url"`
+}
+
+type CalendarUsecase interface {
+	Calendar(ctx context.Context, token string) (*Calendar, error)
+	RotateToken(ctx context.Context, userID string) (string, error)
+}
@@ -2,47 +2,178 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// ConfigDetails is a project of monitored sites. The sites live in their own
+// collection; repositories fill SiteConfig when they load a config. Version
+// goes up with every change to the config or its sites and is served as the
+// config's ETag. Labels are free key/value pairs, such as team=payments,
+// that list endpoints select on. IngestToken authenticates the probe events
+// reported for the config's sites.
 type ConfigDetails struct {
 	ID         primitive.ObjectID `bson:"_id" json:"id"`
+	Version    int64              `bson:"version" json:"version"`
 	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
 	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt  time.Time          `bson:"updated_at" json:"updated_at"`
 	Name       string             `bson:"name" json:"name" validate:"required"`
-	SiteConfig []SiteConfig       `bson:"site_configs" json:"site_configs"`
+	Labels     map[string]string  `bson:"labels,omitempty" json:"labels,omitempty"`
+	SiteConfig []SiteConfig       `bson:"-" json:"site_configs"`
+
+	NotificationTargets []NotificationTarget `bson:"notification_targets" json:"notification_targets"`
+	FeedToken           string               `bson:"feed_token,omitempty" json:"feed_token,omitempty"`
+	IngestToken         string               `bson:"ingest_token,omitempty" json:"ingest_token,omitempty"`
 }
 
+// SiteConfig is one monitored site. ID stays the same when the URL changes;
+// the URL is unique within its config. Status is the last state the probes
+// reported, SiteUnknown until they report one. Version counts changes to the
+// site's settings; probe results do not move it. A site's labels are its own
+// and do not inherit those of its config.
 type SiteConfig struct {
-	SiteUrl       string          `bson:"site_url" json:"site_url" validate:"required"`
-	RegionDetails []RegionDetails `bson:"region_details" json:"region_details"`
+	ID            primitive.ObjectID `bson:"_id" json:"id"`
+	Version       int64              `bson:"version" json:"version"`
+	ConfigID      primitive.ObjectID `bson:"config_id" json:"config_id"`
+	UserID        primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Status        string             `bson:"status" json:"status"`
+	SiteUrl       string             `bson:"site_url" json:"site_url" validate:"required"`
+	Tags          []string           `bson:"tags" json:"tags"`
+	Labels        map[string]string  `bson:"labels,omitempty" json:"labels,omitempty"`
+	RegionDetails []RegionDetails    `bson:"region_details" json:"region_details"`
+
+	CertificateExpiresAt *time.Time `bson:"certificate_expires_at,omitempty" json:"certificate_expires_at,omitempty"`
+	BadgeToken           string     `bson:"badge_token,omitempty" json:"badge_token,omitempty"`
 }
 
+// RegionDetails is the latest probe result of a site from one region.
+// ResponseTime is when the probe ran and Latency how long the site took to
+// answer, in milliseconds. Since is when the region took on its status; it
+// is unset on results stored before it was kept.
 type RegionDetails struct {
 	Status       bool      `bson:"status" json:"status"`
 	Region       string    `bson:"region" json:"region"`
 	ResponseTime time.Time `bson:"response_time" json:"response_time"`
+	Latency      int64     `bson:"latency_ms" json:"latency_ms"`
+	Since        time.Time `bson:"since" json:"since"`
 }
 
-type RemoveConfigRequest struct {
-	SiteUrl string `json:"site_url" validate:"required"`
+var (
+	ErrConfigNotFound = errors.New("config not found")
+	ErrSiteNotFound   = errors.New("site not found")
+	ErrDuplicateSite  = errors.New("a site with this url already exists in the config")
+	ErrInvalidLabel   = errors.New("invalid label")
+
+	ErrVersionConflict      = errors.New("the resource was changed since it was read")
+	ErrPreconditionRequired = errors.New("an If-Match header with the current ETag is required")
+)
+
+// AnyVersion stands in for an expected version when a write applies
+// whatever the current version is.
+const AnyVersion int64 = -1
+
+const (
+	SiteUp      = "up"
+	SiteDown    = "down"
+	SiteUnknown = "unknown"
+)
+
+// ConfigSummary is a config as listed among its owner's projects, with its
+// sites counted by the status the probes last reported for them.
+type ConfigSummary struct {
+	ConfigDetails
+	SiteCounts SiteCounts `json:"site_counts"`
+}
+
+type SiteCounts struct {
+	Total   int `json:"total"`
+	Up      int `json:"up"`
+	Down    int `json:"down"`
+	Unknown int `json:"unknown"`
+}
+
+// LabelStatus sums up the sites that share a value of the label key a
+// status is grouped by. Sites without the key are grouped under an empty
+// value. Uptime is the average of the sites over the period asked for.
+type LabelStatus struct {
+	Key        string     `json:"key"`
+	Value      string     `json:"value"`
+	SiteCounts SiteCounts `json:"site_counts"`
+	Uptime     float64    `json:"uptime"`
+}
+
+type LabelsRequest struct {
+	Labels map[string]string `json:"labels"`
+}
+
+type RenameConfigRequest struct {
+	Name string `json:"name" validate:"required"`
 }
 
 type ConfigRepository interface {
 	InsertOne(ctx context.Context, config *ConfigDetails) (*ConfigDetails, error)
-	UpdateSiteConfig(ctx context.Context, site_config *SiteConfig, id string) error
-	RemoveSiteConfig(ctx context.Context, site_url string, id string) error
-	GetByUserID(ctx context.Context, userID string) (*ConfigDetails, error)
+	FindOne(ctx context.Context, id string) (*ConfigDetails, error)
+	UpdateSiteConfig(ctx context.Context, site_config *SiteConfig, id string, version int64) error
+	RemoveSiteConfig(ctx context.Context, siteID string, id string, version int64) error
+	GetAllByUserID(ctx context.Context, userID string) ([]ConfigDetails, error)
+	GetAllWithPage(ctx context.Context, rp int64, p int64, filter interface{}, setsort interface{}) ([]ConfigDetails, int64, error)
 	AddSiteConfig(ctx context.Context, site_config *SiteConfig, id string) error
+	SetNotificationTargets(ctx context.Context, targets []NotificationTarget, id string, version int64) error
+	SetLabels(ctx context.Context, labels map[string]string, id string, version int64) error
+	GetSites(ctx context.Context, filter interface{}) ([]SiteConfig, error)
+	FindByBadgeToken(ctx context.Context, token string) (*ConfigDetails, error)
+	SetBadgeToken(ctx context.Context, siteID string, token string, id string) error
+	FindByFeedToken(ctx context.Context, token string) (*ConfigDetails, error)
+	SetFeedToken(ctx context.Context, token string, id string) error
+	SetIngestToken(ctx context.Context, token string, id string) error
+	GetWithoutIngestToken(ctx context.Context) ([]ConfigDetails, error)
+	Rename(ctx context.Context, name string, id string, version int64) (*ConfigDetails, error)
+	DeleteOne(ctx context.Context, id string, version int64) error
+	SetSiteStatus(ctx context.Context, configID primitive.ObjectID, site_url string, status string) error
+	SetSiteRegion(ctx context.Context, configID primitive.ObjectID, siteID primitive.ObjectID, region RegionDetails) (*RegionDetails, error)
+	SetCertificateExpiry(ctx context.Context, configID primitive.ObjectID, siteID primitive.ObjectID, expiresAt time.Time) error
+	EnsureIndexes(ctx context.Context) error
+	MigrateSites(ctx context.Context) (int64, error)
+	Transaction(ctx context.Context, fn func(ctx context.Context) error) error
 }
 
+// ConfigUsecase acts on behalf of userID, the authenticated caller. Methods
+// taking a config id fail with ErrConfigNotFound unless the caller owns it.
+// Methods taking a version only apply while the config, or the site they
+// change, is still at that version and fail with ErrVersionConflict
+// otherwise; AnyVersion skips the check. Import creates and updates configs
+// and sites but never deletes them; Apply deletes what the document leaves
+// out only when asked to prune. PublishPending sends the events a failed
+// publish left in the outbox. BackfillIngestTokens is for migrations and
+// acts for no caller.
 type ConfigUsecase interface {
 	InsertOne(ctx context.Context, config *ConfigDetails) (*ConfigDetails, error)
-	UpdateSiteConfig(ctx context.Context, site_config *SiteConfig, id string) error
-	RemoveSiteConfig(ctx context.Context, site_url string, id string) error
-	GetByUserID(ctx context.Context, userID string) (*ConfigDetails, error)
-	AddSiteConfig(ctx context.Context, site_config *SiteConfig, id string) error
+	GetAllWithPage(ctx context.Context, rp int64, p int64, filter interface{}, setsort interface{}) ([]ConfigSummary, int64, error)
+	FindOne(ctx context.Context, id string, userID string) (*ConfigDetails, error)
+	Rename(ctx context.Context, name string, id string, version int64, userID string) (*ConfigDetails, error)
+	DeleteOne(ctx context.Context, id string, version int64, userID string) error
+	AddSiteConfig(ctx context.Context, site_config *SiteConfig, id string, userID string) (*SiteConfig, error)
+	FindSite(ctx context.Context, siteID string, id string, userID string) (*SiteConfig, error)
+	UpdateSiteConfig(ctx context.Context, site_config *SiteConfig, siteID string, id string, version int64, userID string) (*SiteConfig, error)
+	RemoveSiteConfig(ctx context.Context, siteID string, id string, version int64, userID string) error
+	SetNotificationTargets(ctx context.Context, targets []NotificationTarget, id string, version int64, userID string) error
+	SetLabels(ctx context.Context, labels map[string]string, id string, version int64, userID string) error
+	GetSites(ctx context.Context, filter interface{}) ([]SiteConfig, error)
+	StatusByLabel(ctx context.Context, key string, filter interface{}, days int, userID string) ([]LabelStatus, error)
+	RotateBadgeToken(ctx context.Context, siteID string, id string, userID string) (string, error)
+	RotateFeedToken(ctx context.Context, id string, userID string) (string, error)
+	RotateIngestToken(ctx context.Context, id string, userID string) (string, error)
+	BackfillIngestTokens(ctx context.Context) (int64, error)
+	GetHistory(ctx context.Context, id string, limit int64, userID string) ([]ConfigRevision, error)
+	GetRevision(ctx context.Context, id string, number int64, userID string) (*ConfigRevision, error)
+	Diff(ctx context.Context, id string, from int64, to int64, userID string) ([]ConfigChange, error)
+	Rollback(ctx context.Context, id string, number int64, userID string) (*ConfigDetails, error)
+	Export(ctx context.Context, userID string) (*MonitorDocument, error)
+	Import(ctx context.Context, doc *MonitorDocument, userID string) (*ImportResult, error)
+	Plan(ctx context.Context, doc *MonitorDocument, prune bool, userID string) (*Plan, error)
+	Apply(ctx context.Context, doc *MonitorDocument, prune bool, userID string) (*Plan, error)
+	PublishPending(ctx context.Context) error
 }
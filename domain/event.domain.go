@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConfigEventSchemaVersion is the version of the ConfigEvent envelope and
+// its payloads. It goes up only when a field is removed or changes meaning;
+// consumers should ignore fields they do not know.
+const ConfigEventSchemaVersion = 1
+
+// ConfigEventContentType is the content type config events are published
+// with.
+const ConfigEventContentType = "application/json"
+
+// Config event types. Each names the payload the event carries:
+//
+//	config.created                ConfigEventConfig, the new config with its sites
+//	config.removed                ConfigEventConfig, the config as it was, with its sites
+//	config.renamed                ConfigEventChanges, the name before and after
+//	config.notifications_updated  ConfigEventChanges, the targets before and after
+//	config.labels_updated         ConfigEventChanges, the labels before and after
+//	site.added                    ConfigEventSite, the new site
+//	site.updated                  ConfigEventSite, the site now, and what changed
+//	site.removed                  ConfigEventSite, the site as it was
+//	config.ingest_token_rotated   ConfigEventIngestToken, the token that replaced the old one
+const (
+	ConfigEventCreated              = "config.created"
+	ConfigEventRemoved              = "config.removed"
+	ConfigEventRenamed              = "config.renamed"
+	ConfigEventNotificationsUpdated = "config.notifications_updated"
+	ConfigEventLabelsUpdated        = "config.labels_updated"
+	ConfigEventSiteAdded            = "site.added"
+	ConfigEventSiteUpdated          = "site.updated"
+	ConfigEventSiteRemoved          = "site.removed"
+	ConfigEventIngestTokenRotated   = "config.ingest_token_rotated"
+)
+
+// ConfigEvent is published to the probe queue for every change to a config
+// or its sites. It is stored in the outbox along with the change and
+// published once that commits, or later if RabbitMQ is unavailable, so it
+// may arrive more than once. A change that touches several
+// sites at once, such as an import or a rollback, publishes one event per
+// site. Probe results are not config changes and publish nothing. The
+// config in config.created carries the ingest token probe workers send with
+// the events they report for its sites; once that token is rotated, or given
+// to a config that had none, config.ingest_token_rotated carries the new
+// one.
+//
+// Revision is the number of the config revision that recorded the change;
+// the events of one revision share it and come in order. A token rotation
+// records no revision, so its event carries the config's latest. One server
+// at a time publishes events, in the order they were stored. ID is unique
+// per event, so consumers can drop redeliveries. Besides the body, each
+// message carries the event type as its AMQP type, the ID as its message
+// id, the time as its timestamp and these headers: schema_version,
+// event_type, config_id and, for site events, site_id.
+//
+// A config.created event looks like:
+//
+//	{
+//	  "schema_version": 1,
+//	  "id": "6651f0c2a1b2c3d4e5f60718",
+//	  "type": "config.created",
+//	  "config_id": "6651f0c2a1b2c3d4e5f60700",
+//	  "user_id": "6651f0c2a1b2c3d4e5f606ff",
+//	  "revision": 1,
+//	  "occurred_at": "2024-05-25T14:03:14Z",
+//	  "payload": {"config": {"id": "6651f0c2a1b2c3d4e5f60700", "name": "shop", "site_configs": [...]}}
+//	}
+type ConfigEvent struct {
+	SchemaVersion int                 `json:"schema_version"`
+	ID            primitive.ObjectID  `json:"id"`
+	Type          string              `json:"type"`
+	ConfigID      primitive.ObjectID  `json:"config_id"`
+	SiteID        *primitive.ObjectID `json:"site_id,omitempty"`
+	UserID        primitive.ObjectID  `json:"user_id"`
+	Revision      int64               `json:"revision"`
+	OccurredAt    time.Time           `json:"occurred_at"`
+	Payload       interface{}         `json:"payload"`
+}
+
+type ConfigEventConfig struct {
+	Config *ConfigDetails `json:"config"`
+}
+
+// ConfigEventChanges lists what changed, paths as in ConfigChange.
+type ConfigEventChanges struct {
+	Changes []ConfigChange `json:"changes"`
+}
+
+type ConfigEventIngestToken struct {
+	IngestToken string `json:"ingest_token"`
+}
+
+type ConfigEventSite struct {
+	Site    *SiteConfig    `json:"site"`
+	Changes []ConfigChange `json:"changes,omitempty"`
+}
+
+// OutboxMessage is a config event waiting in the outbox, already encoded as
+// the message it is published as. ID is the event's ID.
+type OutboxMessage struct {
+	ID         primitive.ObjectID     `bson:"_id"`
+	Type       string                 `bson:"type"`
+	Body       []byte                 `bson:"body"`
+	Headers    map[string]interface{} `bson:"headers"`
+	OccurredAt time.Time              `bson:"occurred_at"`
+}
+
+// ConfigEventOutboxRepository keeps config events until they are published.
+// GetPending returns the oldest first. Claim takes the lease on publishing
+// for owner until the given time, and reports false while another owner
+// holds it; Release gives it up early.
+type ConfigEventOutboxRepository interface {
+	InsertMany(ctx context.Context, messages []OutboxMessage) error
+	GetPending(ctx context.Context, limit int64) ([]OutboxMessage, error)
+	DeleteOne(ctx context.Context, id primitive.ObjectID) error
+	Claim(ctx context.Context, owner string, until time.Time) (bool, error)
+	Release(ctx context.Context, owner string) error
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Feed is the format-neutral content of an RSS or Atom feed. Item IDs are
+// derived from the incident or maintenance ID alone so they never change as
+// the entry is updated.
+type Feed struct {
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link"`
+	Description string     `json:"description"`
+	Updated     time.Time  `json:"updated"`
+	Items       []FeedItem `json:"items"`
+}
+
+type FeedItem struct {
+	ID        string    `json:"id"`
+	Title     string    `json:"title"`
+	Link      string    `json:"link"`
+	Content   string    `json:"content"`
+	Published time.Time `json:"published"`
+	Updated   time.Time `json:"updated"`
+}
+
+type FeedTokenResponse struct {
+	FeedToken string `json:"feed_token"`
+	RSSURL    string `json:"rss_url"`
+	AtomURL   string `json:"atom_url"`
+}
+
+type FeedUsecase interface {
+	StatusPage(ctx context.Context, slug string) (*Feed, error)
+	Config(ctx context.Context, token string) (*Feed, error)
+}
@@ -0,0 +1,230 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	IncidentOpen     = "open"
+	IncidentResolved = "resolved"
+)
+
+const (
+	IncidentSourceProbe  = "probe"
+	IncidentSourceManual = "manual"
+)
+
+// Timeline states of an incident as shown to status page visitors.
+const (
+	IncidentInvestigating = "investigating"
+	IncidentIdentified    = "identified"
+	IncidentMonitoring    = "monitoring"
+	IncidentStateResolved = "resolved"
+)
+
+const (
+	IncidentImpactMinor = "minor"
+	IncidentImpactMajor = "major"
+)
+
+const (
+	IncidentActionAcknowledge = "ack"
+	IncidentActionSnooze      = "snooze"
+)
+
+const (
+	AcknowledgedViaAPI  = "api"
+	AcknowledgedViaLink = "link"
+)
+
+// Incident tracks one outage. Probe incidents cover a single site: they open
+// when the first region reports it down and resolve once every region is back
+// up. Manual incidents are posted by the owner, may affect several sites and
+// status page components, and move through the timeline states by updates.
+type Incident struct {
+	ID              primitive.ObjectID  `bson:"_id" json:"id"`
+	UserID          primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	ConfigID        primitive.ObjectID  `bson:"config_id" json:"config_id"`
+	SiteUrl         string              `bson:"site_url" json:"site_url"`
+	CreatedAt       time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt       time.Time           `bson:"updated_at" json:"updated_at"`
+	Status          string              `bson:"status" json:"status"`
+	Regions         []string            `bson:"regions" json:"regions"`
+	Error           string              `bson:"error" json:"error"`
+	StartedAt       time.Time           `bson:"started_at" json:"started_at"`
+	ResolvedAt      *time.Time          `bson:"resolved_at,omitempty" json:"resolved_at,omitempty"`
+	AcknowledgedBy  *primitive.ObjectID `bson:"acknowledged_by,omitempty" json:"acknowledged_by,omitempty"`
+	AcknowledgedVia string              `bson:"acknowledged_via,omitempty" json:"acknowledged_via,omitempty"`
+	AcknowledgedAt  *time.Time          `bson:"acknowledged_at,omitempty" json:"acknowledged_at,omitempty"`
+	SnoozedUntil    *time.Time          `bson:"snoozed_until,omitempty" json:"snoozed_until,omitempty"`
+	LastNotifiedAt  time.Time           `bson:"last_notified_at" json:"last_notified_at"`
+	NotifyCount     int                 `bson:"notify_count" json:"notify_count"`
+
+	Source       string               `bson:"source,omitempty" json:"source,omitempty"`
+	Title        string               `bson:"title,omitempty" json:"title,omitempty"`
+	Impact       string               `bson:"impact,omitempty" json:"impact,omitempty"`
+	State        string               `bson:"state,omitempty" json:"state,omitempty"`
+	Sites        []StatusSite         `bson:"sites,omitempty" json:"sites,omitempty"`
+	ComponentIDs []primitive.ObjectID `bson:"component_ids,omitempty" json:"component_ids,omitempty"`
+	Updates      []IncidentUpdate     `bson:"updates,omitempty" json:"updates,omitempty"`
+	Postmortem   *Postmortem          `bson:"postmortem,omitempty" json:"postmortem,omitempty"`
+}
+
+type IncidentUpdate struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	State     string             `bson:"state" json:"state" validate:"required,oneof=investigating identified monitoring resolved"`
+	Body      string             `bson:"body" json:"body" validate:"required"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Postmortem is a markdown write-up shown on status pages once PublishedAt
+// is set.
+type Postmortem struct {
+	Body        string     `bson:"body" json:"body"`
+	UpdatedAt   time.Time  `bson:"updated_at" json:"updated_at"`
+	PublishedAt *time.Time `bson:"published_at,omitempty" json:"published_at,omitempty"`
+}
+
+type ManualIncidentRequest struct {
+	Title        string               `json:"title" validate:"required"`
+	Impact       string               `json:"impact" validate:"omitempty,oneof=minor major"`
+	State        string               `json:"state" validate:"omitempty,oneof=investigating identified monitoring"`
+	Body         string               `json:"body" validate:"required"`
+	Sites        []StatusSite         `json:"sites" validate:"dive"`
+	ComponentIDs []primitive.ObjectID `json:"component_ids"`
+	StartedAt    *time.Time           `json:"started_at"`
+}
+
+type IncidentAffectedRequest struct {
+	Sites        []StatusSite         `json:"sites" validate:"dive"`
+	ComponentIDs []primitive.ObjectID `json:"component_ids"`
+}
+
+type PostmortemRequest struct {
+	Body    string `json:"body" validate:"required"`
+	Publish bool   `json:"publish"`
+}
+
+// Name is the headline shown to status page visitors.
+func (i *Incident) Name() string {
+	if i.Title != "" {
+		return i.Title
+	}
+	return i.SiteUrl + " is unavailable"
+}
+
+// CurrentState is the incident's timeline state. Probe incidents only ever
+// investigate and resolve.
+func (i *Incident) CurrentState() string {
+	if i.State != "" {
+		return i.State
+	}
+	if i.Status == IncidentResolved {
+		return IncidentStateResolved
+	}
+	return IncidentInvestigating
+}
+
+// Timeline returns the incident's updates newest first. Probe incidents have
+// no posted updates, so theirs are derived from when they opened and resolved.
+func (i *Incident) Timeline() []IncidentUpdate {
+	if i.Source == IncidentSourceManual {
+		res := make([]IncidentUpdate, 0, len(i.Updates))
+		for j := len(i.Updates) - 1; j >= 0; j-- {
+			res = append(res, i.Updates[j])
+		}
+		return res
+	}
+
+	res := []IncidentUpdate{}
+	if i.ResolvedAt != nil {
+		res = append(res, IncidentUpdate{
+			State:     IncidentStateResolved,
+			Body:      i.SiteUrl + " is available again.",
+			CreatedAt: *i.ResolvedAt,
+		})
+	}
+	return append(res, IncidentUpdate{
+		State:     IncidentInvestigating,
+		Body:      "We are investigating failed checks for " + i.SiteUrl + ".",
+		CreatedAt: i.StartedAt,
+	})
+}
+
+// AffectsSite reports whether the incident concerns the given site.
+func (i *Incident) AffectsSite(configID primitive.ObjectID, siteUrl string) bool {
+	if i.ConfigID == configID && i.SiteUrl == siteUrl {
+		return true
+	}
+	for _, site := range i.Sites {
+		if site.ConfigID == configID && site.SiteUrl == siteUrl {
+			return true
+		}
+	}
+	return false
+}
+
+// PublishedPostmortem returns the postmortem if it may be shown publicly.
+func (i *Incident) PublishedPostmortem() *Postmortem {
+	if i.Postmortem == nil || i.Postmortem.PublishedAt == nil {
+		return nil
+	}
+	return i.Postmortem
+}
+
+// Silenced reports whether notifications for the incident are on hold.
+func (i *Incident) Silenced(at time.Time) bool {
+	return i.AcknowledgedAt != nil || (i.SnoozedUntil != nil && at.Before(*i.SnoozedUntil))
+}
+
+type SnoozeRequest struct {
+	Minutes int `json:"minutes" validate:"required,gt=0,lte=10080"`
+}
+
+// IncidentLinks are the URLs embedded in a notification for one recipient.
+type IncidentLinks struct {
+	Incident    string
+	Acknowledge string
+	Snooze      string
+}
+
+type IncidentRepository interface {
+	InsertOne(ctx context.Context, incident *Incident) (*Incident, error)
+	FindOne(ctx context.Context, id string) (*Incident, error)
+	FindOpen(ctx context.Context, configID primitive.ObjectID, siteUrl string) (*Incident, error)
+	GetByUserID(ctx context.Context, userID string, status string) ([]Incident, error)
+	GetOpenUnacknowledged(ctx context.Context) ([]Incident, error)
+	GetOverlapping(ctx context.Context, userID primitive.ObjectID, from time.Time, to time.Time) ([]Incident, error)
+	UpdateRegions(ctx context.Context, id primitive.ObjectID, regions []string, errMessage string) error
+	Resolve(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	ResolveSite(ctx context.Context, configID primitive.ObjectID, siteUrl string, at time.Time) error
+	Acknowledge(ctx context.Context, id string, userID *primitive.ObjectID, via string, at time.Time) (*Incident, error)
+	Unacknowledge(ctx context.Context, id string) (*Incident, error)
+	Snooze(ctx context.Context, id string, until time.Time) (*Incident, error)
+	MarkNotified(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	AddUpdate(ctx context.Context, id string, update *IncidentUpdate, resolvedAt *time.Time) (*Incident, error)
+	SetAffected(ctx context.Context, id string, sites []StatusSite, componentIDs []primitive.ObjectID) (*Incident, error)
+	SetPostmortem(ctx context.Context, id string, postmortem *Postmortem) (*Incident, error)
+	RemoveConfig(ctx context.Context, configID primitive.ObjectID) error
+}
+
+type IncidentUsecase interface {
+	FindOne(ctx context.Context, id string) (*Incident, error)
+	GetByUserID(ctx context.Context, userID string, status string, selector string) ([]Incident, error)
+	Acknowledge(ctx context.Context, id string, userID string) (*Incident, error)
+	Unacknowledge(ctx context.Context, id string) (*Incident, error)
+	Snooze(ctx context.Context, id string, duration time.Duration) (*Incident, error)
+	CheckActionToken(ctx context.Context, token string) (*Incident, string, error)
+	HandleActionToken(ctx context.Context, token string) (*Incident, string, error)
+	Track(ctx context.Context, config *ConfigDetails, event *AlertEvent) (*Incident, error)
+	GetOpenUnacknowledged(ctx context.Context) ([]Incident, error)
+	MarkNotified(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	Links(incident *Incident, recipientID *primitive.ObjectID) IncidentLinks
+	CreateManual(ctx context.Context, userID string, request *ManualIncidentRequest) (*Incident, error)
+	PostUpdate(ctx context.Context, id string, update *IncidentUpdate) (*Incident, error)
+	SetAffected(ctx context.Context, id string, request *IncidentAffectedRequest) (*Incident, error)
+	SetPostmortem(ctx context.Context, id string, request *PostmortemRequest) (*Incident, error)
+}
@@ -12,3 +12,41 @@ type JwtCustomRefreshClaims struct {
 	ID string `json:"id"`
 	jwt.RegisteredClaims
 }
+
+// IncidentActionClaims back the one-click acknowledge and snooze links sent in
+// notifications. UserID is empty for recipients that are not Spectator users.
+type IncidentActionClaims struct {
+	IncidentID    string `json:"incident_id"`
+	Action        string `json:"action"`
+	UserID        string `json:"user_id,omitempty"`
+	SnoozeMinutes int    `json:"snooze_minutes,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// SubscriptionClaims back the opt-in and unsubscribe links sent to status
+// page subscribers. Confirm links carry the components chosen at sign-up so
+// that nothing changes until the address owner follows the link.
+type SubscriptionClaims struct {
+	SubscriptionID string   `json:"subscription_id"`
+	Action         string   `json:"action"`
+	ComponentIDs   []string `json:"component_ids,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// EmailVerificationClaims back the link that confirms a user owns their
+// email. The link only verifies the email it was sent to.
+type EmailVerificationClaims struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	jwt.RegisteredClaims
+}
+
+// StatusPageViewerClaims are carried by the session cookie of a private
+// status page viewer. Version must match the page's AccessVersion.
+type StatusPageViewerClaims struct {
+	PageID  string `json:"page_id"`
+	UserID  string `json:"user_id,omitempty"`
+	Method  string `json:"method"`
+	Version int    `json:"version"`
+	jwt.RegisteredClaims
+}
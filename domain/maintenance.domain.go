@@ -0,0 +1,149 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrMaintenanceNotFound is returned for maintenance windows that do not
+// exist or belong to another user.
+var ErrMaintenanceNotFound = errors.New("maintenance not found")
+
+const (
+	MaintenanceScheduled  = "scheduled"
+	MaintenanceInProgress = "in_progress"
+	MaintenanceCompleted  = "completed"
+
+	RecurDaily   = "daily"
+	RecurWeekly  = "weekly"
+	RecurMonthly = "monthly"
+
+	// maxOccurrences bounds how far a recurrence is expanded.
+	maxOccurrences = 10000
+)
+
+// Maintenance is a planned window during which the listed sites may be
+// unavailable. ScheduledFor and ScheduledUntil give the first window; a
+// Recurrence repeats it at the same wall-clock time in TimeZone, an IANA
+// name, so windows do not drift across daylight saving changes.
+type Maintenance struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt      time.Time          `bson:"updated_at" json:"updated_at"`
+	Title          string             `bson:"title" json:"title" validate:"required"`
+	Description    string             `bson:"description" json:"description"`
+	Sites          []StatusSite       `bson:"sites" json:"sites" validate:"dive"`
+	ScheduledFor   time.Time          `bson:"scheduled_for" json:"scheduled_for" validate:"required"`
+	ScheduledUntil time.Time          `bson:"scheduled_until" json:"scheduled_until" validate:"required,gtfield=ScheduledFor"`
+	TimeZone       string             `bson:"time_zone" json:"time_zone"`
+	Recurrence     *Recurrence        `bson:"recurrence,omitempty" json:"recurrence,omitempty"`
+}
+
+// Recurrence repeats a maintenance window every Interval days, weeks or
+// months. Count and Until bound it; without either it repeats forever.
+// Monthly windows skip months that lack the first window's day, as RFC 5545
+// does.
+type Recurrence struct {
+	Frequency string     `bson:"frequency" json:"frequency" validate:"required,oneof=daily weekly monthly"`
+	Interval  int        `bson:"interval" json:"interval" validate:"omitempty,min=1"`
+	Count     int        `bson:"count,omitempty" json:"count,omitempty" validate:"omitempty,min=1"`
+	Until     *time.Time `bson:"until,omitempty" json:"until,omitempty"`
+}
+
+// Location returns the maintenance's time zone, UTC if unset or unknown.
+func (m *Maintenance) Location() *time.Location {
+	loc, err := time.LoadLocation(m.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Window returns the window in progress at the given time, or else the next
+// one, or else the last one.
+func (m *Maintenance) Window(at time.Time) (time.Time, time.Time) {
+	length := m.ScheduledUntil.Sub(m.ScheduledFor)
+	r := m.Recurrence
+	if r == nil {
+		return m.ScheduledFor, m.ScheduledUntil
+	}
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	first := m.ScheduledFor.In(m.Location())
+	last, count := first, 0
+	for n := 0; n < maxOccurrences; n++ {
+		start := first
+		switch r.Frequency {
+		case RecurDaily:
+			start = first.AddDate(0, 0, n*interval)
+		case RecurWeekly:
+			start = first.AddDate(0, 0, 7*n*interval)
+		case RecurMonthly:
+			start = first.AddDate(0, n*interval, 0)
+			if start.Day() != first.Day() {
+				continue
+			}
+		}
+		if r.Until != nil && start.After(*r.Until) {
+			break
+		}
+		last, count = start, count+1
+		if at.Before(start.Add(length)) {
+			break
+		}
+		if r.Count > 0 && count >= r.Count {
+			break
+		}
+	}
+	return last, last.Add(length)
+}
+
+func (m *Maintenance) Status(at time.Time) string {
+	start, end := m.Window(at)
+	switch {
+	case at.Before(start):
+		return MaintenanceScheduled
+	case at.Before(end):
+		return MaintenanceInProgress
+	default:
+		return MaintenanceCompleted
+	}
+}
+
+// Affects reports whether the maintenance covers the given site.
+func (m *Maintenance) Affects(configID primitive.ObjectID, siteUrl string) bool {
+	for _, site := range m.Sites {
+		if site.ConfigID == configID && site.SiteUrl == siteUrl {
+			return true
+		}
+	}
+	return false
+}
+
+type MaintenanceRepository interface {
+	InsertOne(ctx context.Context, maintenance *Maintenance) (*Maintenance, error)
+	FindOne(ctx context.Context, id string) (*Maintenance, error)
+	GetByUserID(ctx context.Context, userID string) ([]Maintenance, error)
+	UpdateOne(ctx context.Context, maintenance *Maintenance, id string) (*Maintenance, error)
+	DeleteOne(ctx context.Context, id string) error
+	RemoveConfig(ctx context.Context, configID primitive.ObjectID) error
+	RenameSite(ctx context.Context, configID primitive.ObjectID, oldUrl string, newUrl string) error
+	RemoveSite(ctx context.Context, configID primitive.ObjectID, siteUrl string) error
+}
+
+// MaintenanceUsecase methods taking a maintenance id and a userID fail with
+// ErrMaintenanceNotFound unless userID owns the window.
+type MaintenanceUsecase interface {
+	InsertOne(ctx context.Context, maintenance *Maintenance) (*Maintenance, error)
+	FindOne(ctx context.Context, id string, userID string) (*Maintenance, error)
+	GetByUserID(ctx context.Context, userID string, selector string) ([]Maintenance, error)
+	UpdateOne(ctx context.Context, maintenance *Maintenance, id string, userID string) (*Maintenance, error)
+	DeleteOne(ctx context.Context, id string, userID string) error
+}
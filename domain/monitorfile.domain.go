@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"strconv"
+)
+
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+	FormatCSV  = "csv"
+)
+
+// Other monitoring services whose exports can be imported.
+const (
+	SourceUptimeKuma  = "uptime-kuma"
+	SourceUptimeRobot = "uptimerobot"
+)
+
+// MonitorDocument is the portable form of a user's configs, used to export
+// and import them. Configs are matched by name and sites by URL, so a
+// document can be applied to an account other than the one it came from.
+// A document naming a config the account has more than once is refused.
+type MonitorDocument struct {
+	Configs []ConfigDocument `json:"configs"`
+}
+
+// ConfigDocument is a config in a MonitorDocument. Row locates it in the
+// file it was read from, for error reports.
+type ConfigDocument struct {
+	Name                string               `json:"name"`
+	Labels              map[string]string    `json:"labels,omitempty"`
+	NotificationTargets []NotificationTarget `json:"notification_targets,omitempty"`
+	Sites               []SiteDocument       `json:"sites"`
+	Row                 string               `json:"-"`
+}
+
+type SiteDocument struct {
+	SiteUrl string            `json:"site_url"`
+	Tags    []string          `json:"tags,omitempty"`
+	Labels  map[string]string `json:"labels,omitempty"`
+	Row     string            `json:"-"`
+}
+
+// RowError is a problem with one entry of an imported file.
+type RowError struct {
+	Row   string `json:"row"`
+	Field string `json:"field,omitempty"`
+	Error string `json:"error"`
+}
+
+// ImportError lists everything wrong with an imported file. Nothing of the
+// file is applied when it is returned.
+type ImportError struct {
+	Errors []RowError `json:"errors"`
+}
+
+func (e *ImportError) Error() string {
+	return "the file has " + strconv.Itoa(len(e.Errors)) + " invalid rows"
+}
+
+// ImportResult counts what an import changed. Unsupported lists what an
+// export from another service held that Spectator could not take over.
+type ImportResult struct {
+	ConfigsCreated int                `json:"configs_created"`
+	ConfigsUpdated int                `json:"configs_updated"`
+	SitesCreated   int                `json:"sites_created"`
+	SitesUpdated   int                `json:"sites_updated"`
+	Unsupported    []UnsupportedField `json:"unsupported,omitempty"`
+}
+
+// UnsupportedField is a setting of a monitor in another service's export
+// that was left out of the import, with the value it had and why.
+type UnsupportedField struct {
+	Monitor string      `json:"monitor"`
+	Field   string      `json:"field"`
+	Value   interface{} `json:"value,omitempty"`
+	Reason  string      `json:"reason"`
+}
@@ -0,0 +1,42 @@
+package domain
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrTargetNotAllowed is returned for notification targets naming a schedule
+// of another user, or a user who is neither the caller nor on one of the
+// caller's schedules.
+var ErrTargetNotAllowed = errors.New("notification target not allowed")
+
+type NotificationChannel string
+
+const (
+	ChannelEmail   NotificationChannel = "email"
+	ChannelSlack   NotificationChannel = "slack"
+	ChannelWebhook NotificationChannel = "webhook"
+)
+
+// NotificationTarget says who should be told about a config's alerts and how.
+// Exactly one of UserID, ScheduleID or Address is expected: a schedule target
+// is resolved to whoever is on call at the time the alert fires.
+type NotificationTarget struct {
+	Channel    NotificationChannel `bson:"channel" json:"channel" validate:"required,oneof=email slack webhook"`
+	UserID     *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	ScheduleID *primitive.ObjectID `bson:"schedule_id,omitempty" json:"schedule_id,omitempty"`
+	Address    string              `bson:"address,omitempty" json:"address,omitempty"`
+}
+
+// Recipient is a NotificationTarget resolved to a concrete destination.
+type Recipient struct {
+	Channel NotificationChannel `json:"channel"`
+	UserID  *primitive.ObjectID `json:"user_id,omitempty"`
+	Name    string              `json:"name,omitempty"`
+	Address string              `json:"address"`
+}
+
+type NotificationTargetsRequest struct {
+	NotificationTargets []NotificationTarget `json:"notification_targets" validate:"dive"`
+}
@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type RotationType string
+
+const (
+	RotationDaily  RotationType = "daily"
+	RotationWeekly RotationType = "weekly"
+)
+
+// ErrScheduleNotFound is returned for schedules that do not exist or belong
+// to another user.
+var ErrScheduleNotFound = errors.New("schedule not found")
+
+type OnCallSchedule struct {
+	ID        primitive.ObjectID `bson:"_id" json:"id"`
+	UserID    primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+	Name      string             `bson:"name" json:"name" validate:"required"`
+	TimeZone  string             `bson:"time_zone" json:"time_zone" validate:"required"`
+	Rotations []Rotation         `bson:"rotations" json:"rotations" validate:"dive"`
+	Overrides []OnCallOverride   `bson:"overrides" json:"overrides" validate:"dive"`
+}
+
+// Rotation hands the pager from one participant to the next every ShiftLength
+// days (daily) or weeks (weekly). Handoffs happen at the wall-clock time and,
+// for weekly rotations, the weekday of StartAt in the schedule's time zone.
+// When several rotations are active the later one in the list wins.
+type Rotation struct {
+	Name         string               `bson:"name" json:"name"`
+	Type         RotationType         `bson:"type" json:"type" validate:"required,oneof=daily weekly"`
+	Participants []primitive.ObjectID `bson:"participants" json:"participants" validate:"required,min=1"`
+	ShiftLength  int                  `bson:"shift_length" json:"shift_length" validate:"gte=0"`
+	StartAt      time.Time            `bson:"start_at" json:"start_at" validate:"required"`
+	EndAt        *time.Time           `bson:"end_at,omitempty" json:"end_at,omitempty"`
+}
+
+// OnCallOverride temporarily hands the schedule to UserID between Start and End.
+type OnCallOverride struct {
+	ID     primitive.ObjectID `bson:"_id" json:"id"`
+	UserID primitive.ObjectID `bson:"user_id" json:"user_id" validate:"required"`
+	Start  time.Time          `bson:"start" json:"start" validate:"required"`
+	End    time.Time          `bson:"end" json:"end" validate:"required,gtfield=Start"`
+}
+
+type OnCallUser struct {
+	ID    primitive.ObjectID `json:"id"`
+	Name  string             `json:"name"`
+	Email string             `json:"email"`
+}
+
+type OnCallResponse struct {
+	ScheduleID primitive.ObjectID `json:"schedule_id"`
+	At         time.Time          `json:"at"`
+	User       OnCallUser         `json:"user"`
+	Rotation   string             `json:"rotation,omitempty"`
+	Override   bool               `json:"override"`
+	ShiftStart time.Time          `json:"shift_start"`
+	ShiftEnd   time.Time          `json:"shift_end"`
+}
+
+type OnCallRepository interface {
+	InsertOne(ctx context.Context, schedule *OnCallSchedule) (*OnCallSchedule, error)
+	FindOne(ctx context.Context, id string) (*OnCallSchedule, error)
+	GetByUserID(ctx context.Context, userID string) ([]OnCallSchedule, error)
+	UpdateOne(ctx context.Context, schedule *OnCallSchedule, id string) (*OnCallSchedule, error)
+	DeleteOne(ctx context.Context, id string) error
+	AddOverride(ctx context.Context, override *OnCallOverride, id string) error
+	RemoveOverride(ctx context.Context, overrideID string, id string) error
+}
+
+// OnCallUsecase methods taking a schedule id and a userID fail with
+// ErrScheduleNotFound unless userID owns the schedule. CheckTarget and
+// ResolveTarget fail with ErrTargetNotAllowed for targets userID may not
+// notify: schedules of other users, and users who are neither userID nor on
+// one of userID's schedules.
+type OnCallUsecase interface {
+	InsertOne(ctx context.Context, schedule *OnCallSchedule) (*OnCallSchedule, error)
+	FindOne(ctx context.Context, id string, userID string) (*OnCallSchedule, error)
+	GetByUserID(ctx context.Context, userID string) ([]OnCallSchedule, error)
+	UpdateOne(ctx context.Context, schedule *OnCallSchedule, id string, userID string) (*OnCallSchedule, error)
+	DeleteOne(ctx context.Context, id string, userID string) error
+	AddOverride(ctx context.Context, override *OnCallOverride, id string, userID string) (*OnCallOverride, error)
+	RemoveOverride(ctx context.Context, overrideID string, id string, userID string) error
+	WhoIsOnCall(ctx context.Context, id string, at time.Time, userID string) (*OnCallResponse, error)
+	CheckTarget(ctx context.Context, target *NotificationTarget, userID string) error
+	ResolveTarget(ctx context.Context, target *NotificationTarget, userID string, at time.Time) (*Recipient, error)
+}
@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanDelete = "delete"
+)
+
+// Plan lists the steps that bring a user's configs in line with a
+// MonitorDocument describing all of them. Configs and sites the document
+// leaves out are only deleted when Prune is set.
+type Plan struct {
+	Prune   bool         `json:"prune"`
+	Changes []PlanChange `json:"changes"`
+	Summary PlanSummary  `json:"summary"`
+}
+
+// PlanChange is one step of a Plan. A step on a whole config has no
+// SiteUrl; the sites of a config that is created or deleted get steps of
+// their own. Field names what an update changes; a site that is created or
+// deleted is shown whole, with its tags and labels.
+type PlanChange struct {
+	Action   string              `json:"action"`
+	Config   string              `json:"config"`
+	ConfigID *primitive.ObjectID `json:"config_id,omitempty"`
+	SiteUrl  string              `json:"site_url,omitempty"`
+	SiteID   *primitive.ObjectID `json:"site_id,omitempty"`
+	Field    string              `json:"field,omitempty"`
+	Before   interface{}         `json:"before,omitempty"`
+	After    interface{}         `json:"after,omitempty"`
+}
+
+type PlanSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
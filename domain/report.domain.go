@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ReportDaily   = "daily"
+	ReportWeekly  = "weekly"
+	ReportMonthly = "monthly"
+)
+
+// ErrReportScheduleNotFound is returned for report schedules that do not
+// exist or belong to another user.
+var ErrReportScheduleNotFound = errors.New("report schedule not found")
+
+// ReportSchedule sends a digest of the user's configs at Hour local time:
+// every day, every Weekday (0 is Sunday) or on DayOfMonth. TimeZone falls
+// back to the user's time zone, then UTC. Targets are held to the same
+// rules as a config's notification targets.
+type ReportSchedule struct {
+	ID         primitive.ObjectID   `bson:"_id" json:"id"`
+	UserID     primitive.ObjectID   `bson:"user_id" json:"user_id"`
+	CreatedAt  time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt  time.Time            `bson:"updated_at" json:"updated_at"`
+	Frequency  string               `bson:"frequency" json:"frequency" validate:"required,oneof=daily weekly monthly"`
+	TimeZone   string               `bson:"time_zone" json:"time_zone"`
+	Hour       int                  `bson:"hour" json:"hour" validate:"gte=0,lte=23"`
+	Weekday    int                  `bson:"weekday" json:"weekday" validate:"gte=0,lte=6"`
+	DayOfMonth int                  `bson:"day_of_month" json:"day_of_month" validate:"gte=0,lte=28"`
+	Targets    []NotificationTarget `bson:"targets" json:"targets" validate:"required,min=1,dive"`
+	LastSentAt *time.Time           `bson:"last_sent_at,omitempty" json:"last_sent_at,omitempty"`
+	NextRunAt  time.Time            `bson:"next_run_at" json:"next_run_at"`
+}
+
+type Report struct {
+	UserID              primitive.ObjectID `json:"user_id"`
+	Frequency           string             `json:"frequency"`
+	From                time.Time          `json:"from"`
+	To                  time.Time          `json:"to"`
+	GeneratedAt         time.Time          `json:"generated_at"`
+	Uptime              float64            `json:"uptime"`
+	Sites               []SiteReport       `json:"sites"`
+	Incidents           []Incident         `json:"incidents"`
+	SlowestSites        []SiteReport       `json:"slowest_sites"`
+	CertificateExpiries []SiteReport       `json:"certificate_expiries"`
+}
+
+type SiteReport struct {
+	ConfigID             primitive.ObjectID `json:"config_id"`
+	ConfigName           string             `json:"config_name"`
+	SiteUrl              string             `json:"site_url"`
+	Uptime               float64            `json:"uptime"`
+	Downtime             time.Duration      `json:"downtime"`
+	Incidents            int                `json:"incidents"`
+	Latency              int64              `json:"latency_ms"`
+	CertificateExpiresAt *time.Time         `json:"certificate_expires_at,omitempty"`
+}
+
+type ReportScheduleRepository interface {
+	InsertOne(ctx context.Context, schedule *ReportSchedule) (*ReportSchedule, error)
+	GetByUserID(ctx context.Context, userID string) ([]ReportSchedule, error)
+	GetDue(ctx context.Context, at time.Time) ([]ReportSchedule, error)
+	MarkSent(ctx context.Context, id primitive.ObjectID, sentAt time.Time, nextRunAt time.Time) error
+	DeleteOne(ctx context.Context, id string, userID string) error
+}
+
+type ReportUsecase interface {
+	InsertSchedule(ctx context.Context, schedule *ReportSchedule) (*ReportSchedule, error)
+	GetSchedulesByUserID(ctx context.Context, userID string) ([]ReportSchedule, error)
+	DeleteSchedule(ctx context.Context, id string, userID string) error
+	Build(ctx context.Context, userID string, frequency string, to time.Time, selector string) (*Report, error)
+	RenderHTML(report *Report) (string, error)
+	SendDue(ctx context.Context) error
+}
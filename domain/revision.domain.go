@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	RevisionCreated              = "created"
+	RevisionRenamed              = "renamed"
+	RevisionDeleted              = "deleted"
+	RevisionSiteAdded            = "site_added"
+	RevisionSiteUpdated          = "site_updated"
+	RevisionSiteRemoved          = "site_removed"
+	RevisionNotificationsUpdated = "notifications_updated"
+	RevisionLabelsUpdated        = "labels_updated"
+	RevisionRolledBack           = "rolled_back"
+	RevisionImported             = "imported"
+	RevisionApplied              = "applied"
+)
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// ConfigRevision is an immutable record of one change to a config, numbered
+// from 1 per config. Before is nil for a new config and After nil for a
+// deleted one. RestoredFrom names the revision a rollback restored.
+type ConfigRevision struct {
+	ID           primitive.ObjectID  `bson:"_id" json:"id"`
+	ConfigID     primitive.ObjectID  `bson:"config_id" json:"config_id"`
+	Number       int64               `bson:"number" json:"number"`
+	Action       string              `bson:"action" json:"action"`
+	ActorID      primitive.ObjectID  `bson:"actor_id" json:"actor_id"`
+	SiteID       *primitive.ObjectID `bson:"site_id,omitempty" json:"site_id,omitempty"`
+	RestoredFrom int64               `bson:"restored_from,omitempty" json:"restored_from,omitempty"`
+	CreatedAt    time.Time           `bson:"created_at" json:"created_at"`
+	Before       *ConfigSnapshot     `bson:"before" json:"before"`
+	After        *ConfigSnapshot     `bson:"after" json:"after"`
+}
+
+// ConfigSnapshot is what people configure about a config. Probe results
+// and tokens are left out: they are not settings and rolling back must not
+// revive a rotated token.
+type ConfigSnapshot struct {
+	Name                string               `bson:"name" json:"name"`
+	NotificationTargets []NotificationTarget `bson:"notification_targets" json:"notification_targets"`
+	Labels              map[string]string    `bson:"labels,omitempty" json:"labels,omitempty"`
+	Sites               []SiteSnapshot       `bson:"sites" json:"sites"`
+}
+
+type SiteSnapshot struct {
+	ID      primitive.ObjectID `bson:"_id" json:"id"`
+	SiteUrl string             `bson:"site_url" json:"site_url"`
+	Tags    []string           `bson:"tags" json:"tags"`
+	Labels  map[string]string  `bson:"labels,omitempty" json:"labels,omitempty"`
+}
+
+// ConfigChange is one difference between two snapshots. Path names the
+// field, with sites addressed by ID, as in "sites[<id>].site_url".
+type ConfigChange struct {
+	Path   string      `json:"path"`
+	Op     string      `json:"op"`
+	Before interface{} `json:"before,omitempty"`
+	After  interface{} `json:"after,omitempty"`
+}
+
+type ConfigRevisionRepository interface {
+	InsertOne(ctx context.Context, revision *ConfigRevision) (*ConfigRevision, error)
+	FindOne(ctx context.Context, configID primitive.ObjectID, number int64) (*ConfigRevision, error)
+	GetByConfigID(ctx context.Context, configID primitive.ObjectID, limit int64) ([]ConfigRevision, error)
+	EnsureIndexes(ctx context.Context) error
+}
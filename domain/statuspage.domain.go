@@ -0,0 +1,183 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ComponentOperational      = "operational"
+	ComponentUnderMaintenance = "under_maintenance"
+	ComponentPartialOutage    = "partial_outage"
+	ComponentMajorOutage      = "major_outage"
+)
+
+const (
+	VisibilityPublic     = "public"
+	VisibilityPassword   = "password"
+	VisibilityRestricted = "restricted"
+)
+
+const (
+	ViewerMethodPassword = "password"
+	ViewerMethodUser     = "user"
+	ViewerMethodToken    = "token"
+)
+
+// StatusPage is a page, served at /status/<slug>, that groups sites from its
+// owner's configs into named components. Visibility controls who may see it:
+// anyone, anyone with the page password, or only the owner's organization,
+// meaning the users listed in AllowedUserIDs and users whose verified email
+// is on one of AllowedDomains. ViewerToken, issued on request, lets feed readers and
+// widgets in without a session. AccessVersion changes whenever access rules
+// do, which ends every viewer session issued before.
+type StatusPage struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	UserID      primitive.ObjectID `bson:"user_id" json:"user_id"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at"`
+	Slug        string             `bson:"slug" json:"slug" validate:"required,max=64"`
+	Title       string             `bson:"title" json:"title" validate:"required"`
+	Description string             `bson:"description" json:"description"`
+	Components  []StatusComponent  `bson:"components" json:"components" validate:"dive"`
+
+	Visibility     string               `bson:"visibility" json:"visibility" validate:"omitempty,oneof=public password restricted"`
+	Password       string               `bson:"-" json:"password,omitempty" validate:"omitempty,min=8"`
+	PasswordHash   string               `bson:"password_hash,omitempty" json:"-"`
+	AllowedDomains []string             `bson:"allowed_domains" json:"allowed_domains"`
+	AllowedUserIDs []primitive.ObjectID `bson:"allowed_user_ids" json:"allowed_user_ids"`
+	ViewerToken    string               `bson:"viewer_token,omitempty" json:"-"`
+	AccessVersion  int                  `bson:"access_version" json:"-"`
+}
+
+// Private reports whether viewers need to be let in.
+func (p *StatusPage) Private() bool {
+	return p.Visibility != "" && p.Visibility != VisibilityPublic
+}
+
+// StatusPageVisit audits one viewer of a private page. UserID is set when the
+// viewer signed in as a Spectator user.
+type StatusPageVisit struct {
+	ID        primitive.ObjectID  `bson:"_id" json:"id"`
+	PageID    primitive.ObjectID  `bson:"page_id" json:"page_id"`
+	UserID    *primitive.ObjectID `bson:"user_id,omitempty" json:"user_id,omitempty"`
+	Method    string              `bson:"method" json:"method"`
+	IP        string              `bson:"ip" json:"ip"`
+	UserAgent string              `bson:"user_agent" json:"user_agent"`
+	Path      string              `bson:"path" json:"path"`
+	ViewedAt  time.Time           `bson:"viewed_at" json:"viewed_at"`
+}
+
+type ViewerTokenResponse struct {
+	ViewerToken string `json:"viewer_token"`
+}
+
+type StatusComponent struct {
+	ID          primitive.ObjectID `bson:"_id" json:"id"`
+	Name        string             `bson:"name" json:"name" validate:"required"`
+	Description string             `bson:"description" json:"description"`
+	Sites       []StatusSite       `bson:"sites" json:"sites" validate:"dive"`
+}
+
+type StatusSite struct {
+	ConfigID primitive.ObjectID `bson:"config_id" json:"config_id" validate:"required"`
+	SiteUrl  string             `bson:"site_url" json:"site_url" validate:"required"`
+}
+
+// StatusPageView is everything the public page shows, computed on request.
+// Incidents are the ones still open, History the most recent ones including
+// resolved, and Maintenances those not yet over or finished recently.
+type StatusPageView struct {
+	Page         StatusPage      `json:"page"`
+	Status       string          `json:"status"`
+	Components   []ComponentView `json:"components"`
+	Incidents    []Incident      `json:"incidents"`
+	History      []Incident      `json:"history"`
+	Maintenances []Maintenance   `json:"maintenances"`
+	GeneratedAt  time.Time       `json:"generated_at"`
+}
+
+// Includes reports whether the site is shown on any of the page's components.
+func (p *StatusPage) Includes(configID primitive.ObjectID, siteUrl string) bool {
+	for _, component := range p.Components {
+		for _, site := range component.Sites {
+			if site.ConfigID == configID && site.SiteUrl == siteUrl {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IncidentComponents returns the page's components the incident affects,
+// either directly or through one of their sites.
+func (p *StatusPage) IncidentComponents(incident *Incident) []StatusComponent {
+	res := []StatusComponent{}
+	for _, component := range p.Components {
+		affected := false
+		for _, id := range incident.ComponentIDs {
+			if id == component.ID {
+				affected = true
+			}
+		}
+		for _, site := range component.Sites {
+			if incident.AffectsSite(site.ConfigID, site.SiteUrl) {
+				affected = true
+			}
+		}
+		if affected {
+			res = append(res, component)
+		}
+	}
+	return res
+}
+
+type ComponentView struct {
+	ID          primitive.ObjectID `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Status      string             `json:"status"`
+	Uptime      float64            `json:"uptime"`
+	Days        []UptimeDay        `json:"days"`
+}
+
+type UptimeDay struct {
+	Date     time.Time     `json:"date"`
+	Uptime   float64       `json:"uptime"`
+	Downtime time.Duration `json:"downtime"`
+}
+
+type StatusPageRepository interface {
+	InsertOne(ctx context.Context, page *StatusPage) (*StatusPage, error)
+	FindOne(ctx context.Context, id string) (*StatusPage, error)
+	FindBySlug(ctx context.Context, slug string) (*StatusPage, error)
+	GetByUserID(ctx context.Context, userID string) ([]StatusPage, error)
+	UpdateOne(ctx context.Context, page *StatusPage, id string) (*StatusPage, error)
+	SetViewerToken(ctx context.Context, token string, id string) error
+	DeleteOne(ctx context.Context, id string) error
+	RemoveConfig(ctx context.Context, configID primitive.ObjectID) error
+	RenameSite(ctx context.Context, configID primitive.ObjectID, oldUrl string, newUrl string) error
+	RemoveSite(ctx context.Context, configID primitive.ObjectID, siteUrl string) error
+}
+
+type StatusPageVisitRepository interface {
+	InsertOne(ctx context.Context, visit *StatusPageVisit) (*StatusPageVisit, error)
+	GetByPageID(ctx context.Context, pageID primitive.ObjectID, limit int64) ([]StatusPageVisit, error)
+}
+
+type StatusPageUsecase interface {
+	InsertOne(ctx context.Context, page *StatusPage) (*StatusPage, error)
+	FindOne(ctx context.Context, id string) (*StatusPage, error)
+	GetByUserID(ctx context.Context, userID string, selector string) ([]StatusPage, error)
+	UpdateOne(ctx context.Context, page *StatusPage, id string) (*StatusPage, error)
+	DeleteOne(ctx context.Context, id string) error
+	View(ctx context.Context, slug string) (*StatusPageView, error)
+	FindBySlug(ctx context.Context, slug string) (*StatusPage, error)
+	Authenticate(ctx context.Context, page *StatusPage, email string, password string) (*primitive.ObjectID, string, error)
+	Allows(ctx context.Context, page *StatusPage, userID string) bool
+	RotateViewerToken(ctx context.Context, id string) (string, error)
+	RecordVisit(ctx context.Context, visit *StatusPageVisit) error
+	GetVisits(ctx context.Context, pageID string, limit int64) ([]StatusPageVisit, error)
+}
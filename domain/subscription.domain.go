@@ -0,0 +1,99 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	SubscriptionConfirm          = "subscription.confirm"
+	SubscriptionIncidentCreated  = "incident.created"
+	SubscriptionIncidentUpdated  = "incident.updated"
+	SubscriptionIncidentResolved = "incident.resolved"
+)
+
+// Subscription is an end user following a status page by email or webhook.
+// It receives nothing until ConfirmedAt is set through the opt-in link. An
+// empty ComponentIDs subscribes to every component of the page.
+type Subscription struct {
+	ID           primitive.ObjectID   `bson:"_id" json:"id"`
+	PageID       primitive.ObjectID   `bson:"page_id" json:"page_id"`
+	CreatedAt    time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time            `bson:"updated_at" json:"updated_at"`
+	Channel      NotificationChannel  `bson:"channel" json:"channel" validate:"required,oneof=email webhook"`
+	Address      string               `bson:"address" json:"address" validate:"required"`
+	ComponentIDs []primitive.ObjectID `bson:"component_ids" json:"component_ids"`
+	ConfirmedAt  *time.Time           `bson:"confirmed_at" json:"confirmed_at"`
+}
+
+// Components returns the components of page the subscription follows that
+// the incident affects.
+func (s *Subscription) Components(page *StatusPage, incident *Incident) []StatusComponent {
+	res := []StatusComponent{}
+	for _, component := range page.IncidentComponents(incident) {
+		wanted := len(s.ComponentIDs) == 0
+		for _, id := range s.ComponentIDs {
+			if id == component.ID {
+				wanted = true
+			}
+		}
+		if wanted {
+			res = append(res, component)
+		}
+	}
+	return res
+}
+
+// SubscriptionDelivery is a subscriber message waiting in the outbound queue.
+// SentAt stays nil until the rate-limited sender hands it to the notifier.
+// Kind is SubscriptionConfirm for opt-in messages, which also record the IP
+// address that asked for them, and the incident event otherwise.
+type SubscriptionDelivery struct {
+	ID             primitive.ObjectID `bson:"_id" json:"id"`
+	SubscriptionID primitive.ObjectID `bson:"subscription_id" json:"subscription_id"`
+	PageID         primitive.ObjectID `bson:"page_id" json:"page_id"`
+	UserID         primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Kind           string             `bson:"kind" json:"kind"`
+	Recipient      Recipient          `bson:"recipient" json:"recipient"`
+	Subject        string             `bson:"subject" json:"subject"`
+	Body           string             `bson:"body" json:"body"`
+	RequestIP      string             `bson:"request_ip,omitempty" json:"-"`
+	CreatedAt      time.Time          `bson:"created_at" json:"created_at"`
+	SentAt         *time.Time         `bson:"sent_at" json:"sent_at"`
+}
+
+// ErrTooManyOptIns is returned when an address, or the client asking, has
+// had as many opt-in messages lately as it may.
+var ErrTooManyOptIns = errors.New("too many subscription requests, try again later")
+
+type SubscriptionRepository interface {
+	InsertOne(ctx context.Context, subscription *Subscription) (*Subscription, error)
+	FindOne(ctx context.Context, id string) (*Subscription, error)
+	FindByAddress(ctx context.Context, pageID primitive.ObjectID, channel NotificationChannel, address string) (*Subscription, error)
+	GetByPageID(ctx context.Context, pageID primitive.ObjectID, confirmedOnly bool) ([]Subscription, error)
+	SetComponents(ctx context.Context, id primitive.ObjectID, componentIDs []primitive.ObjectID) error
+	Confirm(ctx context.Context, id primitive.ObjectID, at time.Time) error
+	DeleteOne(ctx context.Context, id string) error
+}
+
+type SubscriptionDeliveryRepository interface {
+	InsertMany(ctx context.Context, deliveries []SubscriptionDelivery) error
+	GetPending(ctx context.Context, optIns bool, limit int64) ([]SubscriptionDelivery, error)
+	HasPendingOptIn(ctx context.Context, subscriptionID primitive.ObjectID) (bool, error)
+	CountOptInsTo(ctx context.Context, address string, since time.Time) (int64, error)
+	CountOptInsFrom(ctx context.Context, ip string, since time.Time) (int64, error)
+	MarkSent(ctx context.Context, id primitive.ObjectID, at time.Time) error
+}
+
+type SubscriptionUsecase interface {
+	Subscribe(ctx context.Context, slug string, subscription *Subscription, ip string) (*Subscription, error)
+	Confirm(ctx context.Context, token string) (*Subscription, error)
+	Unsubscribe(ctx context.Context, token string) (*Subscription, error)
+	GetByPageID(ctx context.Context, pageID string, userID string) ([]Subscription, error)
+	DeleteOne(ctx context.Context, pageID string, id string, userID string) error
+	NotifyIncident(ctx context.Context, incident *Incident, event string) error
+	SendQueued(ctx context.Context) error
+}
@@ -0,0 +1,125 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ErrTemplateNotFound is returned for templates that do not exist or belong
+// to another user.
+var ErrTemplateNotFound = errors.New("template not found")
+
+// AlertTemplate overrides the default alert wording for one channel of one
+// user. Email bodies are html/template, everything else is text/template;
+// Subject is only used for email.
+type AlertTemplate struct {
+	ID        primitive.ObjectID  `bson:"_id" json:"id"`
+	UserID    primitive.ObjectID  `bson:"user_id" json:"user_id"`
+	CreatedAt time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time           `bson:"updated_at" json:"updated_at"`
+	Channel   NotificationChannel `bson:"channel" json:"channel" validate:"required,oneof=email slack webhook"`
+	Subject   string              `bson:"subject" json:"subject"`
+	Body      string              `bson:"body" json:"body" validate:"required"`
+}
+
+// AlertTemplateData is the data model templates are executed against. It is a
+// public contract: fields may be added but are never renamed or removed.
+//
+//	{{.Status}}        "down" or "up"
+//	{{.Site.URL}}      monitored URL
+//	{{.Config.ID}}     config the site belongs to, {{.Config.Name}} its name
+//	{{range .Regions}} {{.Region}} {{.Status}} {{.CheckedAt}} {{end}}
+//	{{.Error}}         probe error, empty on recovery
+//	{{.Duration}}      how long the site has been in its current state
+//	{{.IncidentLink}}  link to the incident, empty when there is none
+//	{{.AcknowledgeLink}} signed one-click link acknowledging the incident
+//	{{.SnoozeLink}}    signed one-click link snoozing the incident for an hour
+//	{{.OccurredAt}}    when the event was detected
+//	{{.Events}}        every event of a grouped alert, each with the fields
+//	                   above; empty when the alert covers a single event
+type AlertTemplateData struct {
+	Status       string        `json:"status"`
+	Site         AlertSite     `json:"site"`
+	Config       AlertConfig   `json:"config"`
+	Regions      []AlertRegion `json:"regions"`
+	Error        string        `json:"error"`
+	Duration     time.Duration `json:"duration"`
+	IncidentLink string        `json:"incident_link"`
+	OccurredAt   time.Time     `json:"occurred_at"`
+
+	AcknowledgeLink string `json:"acknowledge_link"`
+	SnoozeLink      string `json:"snooze_link"`
+
+	Events []AlertTemplateData `json:"events,omitempty"`
+}
+
+type AlertSite struct {
+	URL string `json:"url"`
+}
+
+type AlertConfig struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type AlertRegion struct {
+	Region    string    `json:"region"`
+	Status    bool      `json:"status"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type RenderedMessage struct {
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+// TemplatePreviewRequest renders Subject/Body against Data, or against
+// SampleAlertTemplateData when no data is given.
+type TemplatePreviewRequest struct {
+	Channel NotificationChannel `json:"channel" validate:"required,oneof=email slack webhook"`
+	Subject string              `json:"subject"`
+	Body    string              `json:"body" validate:"required"`
+	Data    *AlertTemplateData  `json:"data"`
+}
+
+type TemplatePreviewResponse struct {
+	Data    AlertTemplateData `json:"data"`
+	Message RenderedMessage   `json:"message"`
+}
+
+func SampleAlertTemplateData() AlertTemplateData {
+	return AlertTemplateData{
+		Status: "down",
+		Site:   AlertSite{URL: "https://example.com/health"},
+		Config: AlertConfig{ID: "000000000000000000000000", Name: "Example"},
+		Regions: []AlertRegion{
+			{Region: "us-east-1", Status: false, CheckedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+			{Region: "eu-west-1", Status: true, CheckedAt: time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)},
+		},
+		Error:        "GET https://example.com/health: 503 Service Unavailable",
+		Duration:     5 * time.Minute,
+		IncidentLink: "https://spectator.example.com/incidents/000000000000000000000000",
+		OccurredAt:   time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+
+		AcknowledgeLink: "https://spectator.example.com/api/v1/incident-action?token=sample",
+		SnoozeLink:      "https://spectator.example.com/api/v1/incident-action?token=sample",
+	}
+}
+
+type AlertTemplateRepository interface {
+	Upsert(ctx context.Context, template *AlertTemplate) (*AlertTemplate, error)
+	FindByUserAndChannel(ctx context.Context, userID string, channel NotificationChannel) (*AlertTemplate, error)
+	GetByUserID(ctx context.Context, userID string) ([]AlertTemplate, error)
+	DeleteOne(ctx context.Context, id string, userID string) error
+}
+
+type AlertTemplateUsecase interface {
+	Upsert(ctx context.Context, template *AlertTemplate) (*AlertTemplate, error)
+	GetByUserID(ctx context.Context, userID string) ([]AlertTemplate, error)
+	DeleteOne(ctx context.Context, id string, userID string) error
+	Preview(ctx context.Context, request *TemplatePreviewRequest) (*TemplatePreviewResponse, error)
+	Render(ctx context.Context, userID string, channel NotificationChannel, data *AlertTemplateData) (*RenderedMessage, error)
+}
@@ -2,32 +2,65 @@ package domain
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// User is a Spectator account. EmailVerifiedAt is set once the user follows
+// the link sent to their email and cleared when the email changes; only a
+// verified email counts towards the domains a status page lets in.
 type User struct {
 	ID        primitive.ObjectID `bson:"_id" json:"id"`
 	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
 	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
 	Name      string             `bson:"name" json:"name" validate:"required"`
 	Email     string             `bson:"email" json:"email" validate:"required"`
-	Password  string             `bson:"password" json:"password" validate:"required"`
+	Password  string             `bson:"password" json:"-" validate:"required"`
+	TimeZone  string             `bson:"time_zone" json:"time_zone"`
+
+	CalendarToken   string     `bson:"calendar_token,omitempty" json:"-"`
+	EmailVerifiedAt *time.Time `bson:"email_verified_at,omitempty" json:"email_verified_at,omitempty"`
+}
+
+// UpdateUserRequest changes the caller's account. Only the fields the
+// request sends are changed.
+type UpdateUserRequest struct {
+	Name     *string `form:"name" json:"name" binding:"omitnil,min=1"`
+	Email    *string `form:"email" json:"email" binding:"omitnil,email"`
+	Password *string `form:"password" json:"password" binding:"omitnil,min=1"`
+	TimeZone *string `form:"time_zone" json:"time_zone"`
 }
 
+// ErrEmailTaken is returned when an account is given an email another
+// account already has. Emails identify users at login, so they are unique.
+var ErrEmailTaken = errors.New("another account already uses this email")
+
+// ErrInvalidVerificationLink is returned for an email verification link that
+// is forged, expired or sent to an email the account no longer has.
+var ErrInvalidVerificationLink = errors.New("invalid or expired verification link")
+
 type UserRepository interface {
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	InsertOne(ctx context.Context, u *User) (*User, error)
 	FindOne(ctx context.Context, id string) (*User, error)
 	GetAllWithPage(ctx context.Context, rp int64, p int64, filter interface{}, setsort interface{}) ([]User, int64, error)
 	GetByCredential(ctx context.Context, username string, password string) (*User, error)
-	UpdateOne(ctx context.Context, user *User, id string) (*User, error)
+	UpdateOne(ctx context.Context, request *UpdateUserRequest, id string) (*User, error)
+	FindByCalendarToken(ctx context.Context, token string) (*User, error)
+	SetCalendarToken(ctx context.Context, token string, id string) error
+	VerifyEmail(ctx context.Context, id string, email string, at time.Time) error
+	EnsureIndexes(ctx context.Context) error
 }
 
+// UserUsecase manages accounts. SendVerification mails the user a link that
+// VerifyEmail accepts to mark their current email verified.
 type UserUsecase interface {
 	InsertOne(ctx context.Context, u *User) (*User, error)
 	GetAllWithPage(ctx context.Context, rp int64, p int64, filter interface{}, setsort interface{}) ([]User, int64, error)
 	FindOne(ctx context.Context, id string) (*User, error)
-	UpdateOne(ctx context.Context, user *User, id string) (*User, error)
+	UpdateOne(ctx context.Context, request *UpdateUserRequest, id string) (*User, error)
+	SendVerification(ctx context.Context, id string) error
+	VerifyEmail(ctx context.Context, token string) (*User, error)
 }
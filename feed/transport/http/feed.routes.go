@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/feed"
+)
+
+type FeedHandler struct {
+	FeedUsecase domain.FeedUsecase
+	config      *bootstrap.Config
+}
+
+// NewFeedHandler mounts the status page feeds on public and the per-config
+// feeds, keyed by the config's feed token, on r. Neither needs a login so
+// feed readers can poll them.
+func NewFeedHandler(cfg *bootstrap.Config, r *gin.RouterGroup, public *gin.RouterGroup, fu domain.FeedUsecase) {
+	handler := &FeedHandler{
+		FeedUsecase: fu,
+		config:      cfg,
+	}
+	public.GET("/:slug/feed.rss", handler.StatusPageRSS)
+	public.GET("/:slug/feed.atom", handler.StatusPageAtom)
+	r.GET("/:token/rss.xml", handler.ConfigRSS)
+	r.GET("/:token/atom.xml", handler.ConfigAtom)
+}
+
+func (h *FeedHandler) StatusPageRSS(c *gin.Context) {
+	res, err := h.FeedUsecase.StatusPage(c, c.Param("slug"))
+	h.write(c, res, err, false)
+}
+
+func (h *FeedHandler) StatusPageAtom(c *gin.Context) {
+	res, err := h.FeedUsecase.StatusPage(c, c.Param("slug"))
+	h.write(c, res, err, true)
+}
+
+func (h *FeedHandler) ConfigRSS(c *gin.Context) {
+	res, err := h.FeedUsecase.Config(c, c.Param("token"))
+	h.write(c, res, err, false)
+}
+
+func (h *FeedHandler) ConfigAtom(c *gin.Context) {
+	res, err := h.FeedUsecase.Config(c, c.Param("token"))
+	h.write(c, res, err, true)
+}
+
+func (h *FeedHandler) write(c *gin.Context, f *domain.Feed, err error, atom bool) {
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return
+	}
+
+	var (
+		body        []byte
+		contentType string
+	)
+	if atom {
+		self := strings.TrimSuffix(h.config.PublicURL, "/") + c.Request.URL.Path
+		body, err = feed.Atom(f, self)
+		contentType = "application/atom+xml; charset=utf-8"
+	} else {
+		body, err = feed.RSS(f)
+		contentType = "application/rss+xml; charset=utf-8"
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "public, max-age=300")
+	c.Header("Last-Modified", f.Updated.UTC().Format(http.TimeFormat))
+	c.Data(http.StatusOK, contentType, body)
+}
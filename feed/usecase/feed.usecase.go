@@ -0,0 +1,193 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"time"
+
+	"spectator.main/domain"
+	"spectator.main/internals/markdown"
+)
+
+const (
+	feedDays  = 90
+	feedItems = 50
+)
+
+type feedUsecase struct {
+	statusPageUsecase domain.StatusPageUsecase
+	configRepo        domain.ConfigRepository
+	incidentRepo      domain.IncidentRepository
+	maintenanceRepo   domain.MaintenanceRepository
+	contextTimeout    time.Duration
+	publicURL         string
+}
+
+func NewFeedUsecase(s domain.StatusPageUsecase, c domain.ConfigRepository, i domain.IncidentRepository, m domain.MaintenanceRepository, to time.Duration, publicURL string) domain.FeedUsecase {
+	return &feedUsecase{
+		statusPageUsecase: s,
+		configRepo:        c,
+		incidentRepo:      i,
+		maintenanceRepo:   m,
+		contextTimeout:    to,
+		publicURL:         strings.TrimSuffix(publicURL, "/"),
+	}
+}
+
+func (f *feedUsecase) StatusPage(c context.Context, slug string) (*domain.Feed, error) {
+
+	ctx, cancel := context.WithTimeout(c, f.contextTimeout)
+	defer cancel()
+
+	view, err := f.statusPageUsecase.View(ctx, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	link := f.publicURL + "/status/" + view.Page.Slug
+	feed := &domain.Feed{
+		ID:          "urn:spectator:status-page:" + view.Page.ID.Hex(),
+		Title:       view.Page.Title + " status",
+		Link:        link,
+		Description: "Incidents and scheduled maintenance for " + view.Page.Title,
+		Updated:     view.Page.UpdatedAt,
+	}
+
+	items := []domain.FeedItem{}
+	for i := range view.History {
+		items = append(items, incidentItem(&view.History[i], link+"#incident-"+view.History[i].ID.Hex()))
+	}
+	for i := range view.Maintenances {
+		items = append(items, maintenanceItem(&view.Maintenances[i], link+"#maintenance-"+view.Maintenances[i].ID.Hex()))
+	}
+	finish(feed, items)
+
+	return feed, nil
+}
+
+// Config lists the incidents and maintenance touching the config's sites,
+// probe and manual alike. The API pages about them need a login, so its
+// items link nowhere.
+func (f *feedUsecase) Config(c context.Context, token string) (*domain.Feed, error) {
+
+	ctx, cancel := context.WithTimeout(c, f.contextTimeout)
+	defer cancel()
+
+	config, err := f.configRepo.FindByFeedToken(ctx, token)
+	if err != nil {
+		return nil, errors.New("feed not found")
+	}
+
+	now := time.Now()
+	incidents, err := f.incidentRepo.GetOverlapping(ctx, config.UserID, now.AddDate(0, 0, -feedDays), now)
+	if err != nil {
+		return nil, err
+	}
+	maintenances, err := f.maintenanceRepo.GetByUserID(ctx, config.UserID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	feed := &domain.Feed{
+		ID:          "urn:spectator:config:" + config.ID.Hex(),
+		Title:       config.Name + " status",
+		Link:        f.publicURL + "/feed/" + token + "/rss.xml",
+		Description: "Incidents and scheduled maintenance for the sites of " + config.Name,
+		Updated:     config.UpdatedAt,
+	}
+
+	items := []domain.FeedItem{}
+	for i := range incidents {
+		for _, site := range config.SiteConfig {
+			if incidents[i].AffectsSite(config.ID, site.SiteUrl) {
+				items = append(items, incidentItem(&incidents[i], ""))
+				break
+			}
+		}
+	}
+	for _, m := range maintenances {
+		// Show recurring maintenance as its current or next window.
+		m.ScheduledFor, m.ScheduledUntil = m.Window(now)
+		if m.ScheduledUntil.Before(now.AddDate(0, 0, -feedDays)) {
+			continue
+		}
+		for _, site := range m.Sites {
+			if site.ConfigID == config.ID {
+				items = append(items, maintenanceItem(&m, ""))
+				break
+			}
+		}
+	}
+	finish(feed, items)
+
+	return feed, nil
+}
+
+// finish orders items newest first, caps them and dates the feed by its most
+// recently changed item.
+func finish(feed *domain.Feed, items []domain.FeedItem) {
+	sort.SliceStable(items, func(i, j int) bool { return items[i].Published.After(items[j].Published) })
+	if len(items) > feedItems {
+		items = items[:feedItems]
+	}
+	for _, item := range items {
+		if item.Updated.After(feed.Updated) {
+			feed.Updated = item.Updated
+		}
+	}
+	feed.Items = items
+}
+
+func formatTime(t time.Time) string {
+	return html.EscapeString(t.UTC().Format("Jan 2, 15:04 MST"))
+}
+
+// incidentItem lists the incident's updates newest first, followed by the
+// postmortem once published. The ID depends on the incident alone, so new
+// updates change the entry instead of adding one.
+func incidentItem(incident *domain.Incident, link string) domain.FeedItem {
+	var content strings.Builder
+	for _, update := range incident.Timeline() {
+		fmt.Fprintf(&content, "<p><small>%s</small><br><strong>%s</strong> - %s</p>", formatTime(update.CreatedAt), html.EscapeString(stateTitle(update.State)), html.EscapeString(update.Body))
+	}
+	if postmortem := incident.PublishedPostmortem(); postmortem != nil {
+		fmt.Fprintf(&content, "<h3>Postmortem</h3>%s", markdown.HTML(postmortem.Body))
+	}
+
+	return domain.FeedItem{
+		ID:        "urn:spectator:incident:" + incident.ID.Hex(),
+		Title:     incident.Name(),
+		Link:      link,
+		Content:   content.String(),
+		Published: incident.StartedAt,
+		Updated:   incident.UpdatedAt,
+	}
+}
+
+func stateTitle(state string) string {
+	if state == "" {
+		return ""
+	}
+	return strings.ToUpper(state[:1]) + state[1:]
+}
+
+func maintenanceItem(maintenance *domain.Maintenance, link string) domain.FeedItem {
+	var content strings.Builder
+	fmt.Fprintf(&content, "<p><strong>Scheduled</strong> for %s until %s.</p>", formatTime(maintenance.ScheduledFor), formatTime(maintenance.ScheduledUntil))
+	if maintenance.Description != "" {
+		fmt.Fprintf(&content, "<p>%s</p>", html.EscapeString(maintenance.Description))
+	}
+
+	return domain.FeedItem{
+		ID:        "urn:spectator:maintenance:" + maintenance.ID.Hex(),
+		Title:     "Scheduled maintenance: " + maintenance.Title,
+		Link:      link,
+		Content:   content.String(),
+		Published: maintenance.CreatedAt,
+		Updated:   maintenance.UpdatedAt,
+	}
+}
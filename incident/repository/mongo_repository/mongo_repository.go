@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "incident"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.IncidentRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+func (m *mongoRepository) InsertOne(ctx context.Context, incident *domain.Incident) (*domain.Incident, error) {
+	var (
+		err error
+	)
+
+	_, err = m.Collection.InsertOne(ctx, incident)
+	if err != nil {
+		return incident, err
+	}
+
+	return incident, nil
+}
+
+func (m *mongoRepository) FindOne(ctx context.Context, id string) (*domain.Incident, error) {
+	var (
+		incident domain.Incident
+		err      error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &incident, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&incident)
+	if err != nil {
+		return &incident, err
+	}
+
+	return &incident, nil
+}
+
+func (m *mongoRepository) FindOpen(ctx context.Context, configID primitive.ObjectID, siteUrl string) (*domain.Incident, error) {
+	var (
+		incident domain.Incident
+		err      error
+	)
+
+	filter := bson.M{"config_id": configID, "site_url": siteUrl, "status": domain.IncidentOpen}
+	err = m.Collection.FindOne(ctx, filter).Decode(&incident)
+	if err != nil {
+		return &incident, err
+	}
+
+	return &incident, nil
+}
+
+func (m *mongoRepository) GetByUserID(ctx context.Context, userID string, status string) ([]domain.Incident, error) {
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"user_id": idHex}
+	if status != "" {
+		filter["status"] = status
+	}
+
+	return m.find(ctx, filter)
+}
+
+func (m *mongoRepository) GetOpenUnacknowledged(ctx context.Context) ([]domain.Incident, error) {
+	filter := bson.M{
+		"status":          domain.IncidentOpen,
+		"source":          bson.M{"$ne": domain.IncidentSourceManual},
+		"acknowledged_at": bson.M{"$exists": false},
+	}
+
+	return m.find(ctx, filter)
+}
+
+// GetOverlapping returns the user's incidents that were open at any point
+// between from and to.
+func (m *mongoRepository) GetOverlapping(ctx context.Context, userID primitive.ObjectID, from time.Time, to time.Time) ([]domain.Incident, error) {
+	filter := bson.M{
+		"user_id":    userID,
+		"started_at": bson.M{"$lt": to},
+		"$or": bson.A{
+			bson.M{"resolved_at": bson.M{"$exists": false}},
+			bson.M{"resolved_at": bson.M{"$gte": from}},
+		},
+	}
+
+	return m.find(ctx, filter)
+}
+
+func (m *mongoRepository) find(ctx context.Context, filter interface{}) ([]domain.Incident, error) {
+	var (
+		incidents []domain.Incident
+	)
+
+	opts := options.Find().SetSort(bson.D{{Key: "started_at", Value: -1}})
+
+	cursor, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return incidents, err
+	}
+	if cursor == nil {
+		return incidents, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &incidents)
+	if err != nil {
+		return incidents, err
+	}
+
+	return incidents, nil
+}
+
+func (m *mongoRepository) UpdateRegions(ctx context.Context, id primitive.ObjectID, regions []string, errMessage string) error {
+	update := bson.M{"$set": bson.M{
+		"regions":    regions,
+		"error":      errMessage,
+		"updated_at": time.Now(),
+	}}
+
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+func (m *mongoRepository) Resolve(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"status":      domain.IncidentResolved,
+		"regions":     []string{},
+		"resolved_at": at,
+		"updated_at":  time.Now(),
+	}}
+
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// ResolveSite resolves the open incidents of a site that is no longer probed
+// at siteUrl.
+func (m *mongoRepository) ResolveSite(ctx context.Context, configID primitive.ObjectID, siteUrl string, at time.Time) error {
+	filter := bson.M{"config_id": configID, "site_url": siteUrl, "status": domain.IncidentOpen}
+	update := bson.M{"$set": bson.M{
+		"status":      domain.IncidentResolved,
+		"regions":     []string{},
+		"resolved_at": at,
+		"updated_at":  time.Now(),
+	}}
+
+	_, err := m.Collection.UpdateMany(ctx, filter, update)
+	return err
+}
+
+func (m *mongoRepository) Acknowledge(ctx context.Context, id string, userID *primitive.ObjectID, via string, at time.Time) (*domain.Incident, error) {
+	set := bson.M{
+		"acknowledged_via": via,
+		"acknowledged_at":  at,
+		"updated_at":       time.Now(),
+	}
+	if userID != nil {
+		set["acknowledged_by"] = userID
+	}
+
+	return m.updateAndFind(ctx, id, bson.M{"$set": set})
+}
+
+func (m *mongoRepository) Unacknowledge(ctx context.Context, id string) (*domain.Incident, error) {
+	update := bson.M{
+		"$unset": bson.M{"acknowledged_by": "", "acknowledged_via": "", "acknowledged_at": "", "snoozed_until": ""},
+		"$set":   bson.M{"updated_at": time.Now()},
+	}
+
+	return m.updateAndFind(ctx, id, update)
+}
+
+func (m *mongoRepository) Snooze(ctx context.Context, id string, until time.Time) (*domain.Incident, error) {
+	update := bson.M{"$set": bson.M{
+		"snoozed_until": until,
+		"updated_at":    time.Now(),
+	}}
+
+	return m.updateAndFind(ctx, id, update)
+}
+
+func (m *mongoRepository) MarkNotified(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{
+		"$set": bson.M{"last_notified_at": at},
+		"$inc": bson.M{"notify_count": 1},
+	}
+
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// AddUpdate appends a timeline update and moves the incident to its state,
+// resolving it at resolvedAt or reopening it when resolvedAt is nil.
+func (m *mongoRepository) AddUpdate(ctx context.Context, id string, update *domain.IncidentUpdate, resolvedAt *time.Time) (*domain.Incident, error) {
+	set := bson.M{
+		"state":      update.State,
+		"status":     domain.IncidentOpen,
+		"updated_at": time.Now(),
+	}
+	change := bson.M{
+		"$push": bson.M{"updates": update},
+		"$set":  set,
+	}
+	if resolvedAt != nil {
+		set["status"] = domain.IncidentResolved
+		set["resolved_at"] = resolvedAt
+	} else {
+		change["$unset"] = bson.M{"resolved_at": ""}
+	}
+
+	return m.updateAndFind(ctx, id, change)
+}
+
+func (m *mongoRepository) SetAffected(ctx context.Context, id string, sites []domain.StatusSite, componentIDs []primitive.ObjectID) (*domain.Incident, error) {
+	update := bson.M{"$set": bson.M{
+		"sites":         sites,
+		"component_ids": componentIDs,
+		"updated_at":    time.Now(),
+	}}
+
+	return m.updateAndFind(ctx, id, update)
+}
+
+func (m *mongoRepository) SetPostmortem(ctx context.Context, id string, postmortem *domain.Postmortem) (*domain.Incident, error) {
+	update := bson.M{"$set": bson.M{
+		"postmortem": postmortem,
+		"updated_at": time.Now(),
+	}}
+
+	return m.updateAndFind(ctx, id, update)
+}
+
+func (m *mongoRepository) updateAndFind(ctx context.Context, id string, update interface{}) (*domain.Incident, error) {
+	var (
+		incident domain.Incident
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.M{"_id": idHex}
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, errors.New("no incident found with the given id")
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(&incident)
+	if err != nil {
+		return nil, err
+	}
+
+	return &incident, nil
+}
+
+// RemoveConfig deletes the probe incidents of a deleted config and takes its
+// sites off manual incidents.
+func (m *mongoRepository) RemoveConfig(ctx context.Context, configID primitive.ObjectID) error {
+	_, err := m.Collection.DeleteMany(ctx, bson.M{"config_id": configID})
+	if err != nil {
+		return err
+	}
+
+	_, err = m.Collection.UpdateMany(ctx,
+		bson.M{"sites.config_id": configID},
+		bson.M{"$pull": bson.M{"sites": bson.M{"config_id": configID}}},
+	)
+	return err
+}
@@ -0,0 +1,256 @@
+package http
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
+)
+
+type IncidentHandler struct {
+	IncidentUsecase domain.IncidentUsecase
+	config          *bootstrap.Config
+}
+
+var actionPage = template.Must(template.New("action").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Spectator</title></head>
+<body><p>{{.Message}}</p>{{if .Incident}}<p>{{.Incident.Name}} &middot; started {{.Incident.StartedAt.Format "2006-01-02 15:04 MST"}}</p>{{end}}
+{{if .Token}}<form method="post"><input type="hidden" name="token" value="{{.Token}}"><button type="submit">{{.Button}}</button></form>{{end}}</body></html>`))
+
+type actionView struct {
+	Message  string
+	Incident *domain.Incident
+	Token    string
+	Button   string
+}
+
+func NewIncidentHandler(cfg *bootstrap.Config, r *gin.RouterGroup, iu domain.IncidentUsecase) {
+	handler := &IncidentHandler{
+		IncidentUsecase: iu,
+		config:          cfg,
+	}
+	r.GET("/incident-action", handler.ConfirmActionLink)
+	r.POST("/incident-action", handler.HandleActionLink)
+
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.GET("/incidents", handler.GetIncidents)
+	protected.GET("/incident/:incident_id", handler.GetIncident)
+	protected.POST("/incident/:incident_id/ack", handler.Acknowledge)
+	protected.POST("/incident/:incident_id/unack", handler.Unacknowledge)
+	protected.POST("/incident/:incident_id/snooze", handler.Snooze)
+	protected.POST("/incident", handler.CreateManualIncident)
+	protected.POST("/incident/:incident_id/update", handler.PostUpdate)
+	protected.PUT("/incident/:incident_id/affected", handler.SetAffected)
+	protected.PUT("/incident/:incident_id/postmortem", handler.SetPostmortem)
+}
+
+// owned loads the incident and hides it from anyone but its owner.
+func (h *IncidentHandler) owned(c *gin.Context) (*domain.Incident, bool) {
+	incident, err := h.IncidentUsecase.FindOne(c, c.Param("incident_id"))
+	if err != nil || incident.UserID.Hex() != c.GetString("x-user-id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "incident not found"})
+		return nil, false
+	}
+	return incident, true
+}
+
+func (h *IncidentHandler) GetIncidents(c *gin.Context) {
+	status, _ := c.GetQuery("status")
+	incidents, err := h.IncidentUsecase.GetByUserID(c, c.GetString("x-user-id"), status, c.Query("selector"))
+	if errors.Is(err, domain.ErrInvalidLabel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, incidents)
+}
+
+func (h *IncidentHandler) GetIncident(c *gin.Context) {
+	incident, ok := h.owned(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, incident)
+}
+
+func (h *IncidentHandler) Acknowledge(c *gin.Context) {
+	if _, ok := h.owned(c); !ok {
+		return
+	}
+	res, err := h.IncidentUsecase.Acknowledge(c, c.Param("incident_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *IncidentHandler) Unacknowledge(c *gin.Context) {
+	if _, ok := h.owned(c); !ok {
+		return
+	}
+	res, err := h.IncidentUsecase.Unacknowledge(c, c.Param("incident_id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *IncidentHandler) Snooze(c *gin.Context) {
+	var request domain.SnoozeRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := h.owned(c); !ok {
+		return
+	}
+	res, err := h.IncidentUsecase.Snooze(c, c.Param("incident_id"), time.Duration(request.Minutes)*time.Minute)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *IncidentHandler) CreateManualIncident(c *gin.Context) {
+	var request domain.ManualIncidentRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.IncidentUsecase.CreateManual(c, c.GetString("x-user-id"), &request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *IncidentHandler) PostUpdate(c *gin.Context) {
+	var update domain.IncidentUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := h.owned(c); !ok {
+		return
+	}
+	res, err := h.IncidentUsecase.PostUpdate(c, c.Param("incident_id"), &update)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *IncidentHandler) SetAffected(c *gin.Context) {
+	var request domain.IncidentAffectedRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := h.owned(c); !ok {
+		return
+	}
+	res, err := h.IncidentUsecase.SetAffected(c, c.Param("incident_id"), &request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *IncidentHandler) SetPostmortem(c *gin.Context) {
+	var request domain.PostmortemRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if _, ok := h.owned(c); !ok {
+		return
+	}
+	res, err := h.IncidentUsecase.SetPostmortem(c, c.Param("incident_id"), &request)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+// ConfirmActionLink serves the one-click links embedded in notifications.
+// Mail scanners follow links, so opening one only asks for confirmation and
+// the action is applied by the form it posts. The signed token is the only
+// credential, so the page reveals nothing beyond the incident the link was
+// issued for.
+func (h *IncidentHandler) ConfirmActionLink(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "private, no-store")
+
+	token, _ := c.GetQuery("token")
+	incident, action, err := h.IncidentUsecase.CheckActionToken(c, token)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		actionPage.Execute(c.Writer, actionView{Message: err.Error()})
+		return
+	}
+
+	view := actionView{Message: "Acknowledge this incident? No further reminders will be sent.", Incident: incident, Token: token, Button: "Acknowledge"}
+	if action == domain.IncidentActionSnooze {
+		view.Message = "Snooze reminders for this incident?"
+		view.Button = "Snooze"
+	}
+	c.Status(http.StatusOK)
+	actionPage.Execute(c.Writer, view)
+}
+
+// HandleActionLink applies the action of a link confirmed on the page
+// ConfirmActionLink served.
+func (h *IncidentHandler) HandleActionLink(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "private, no-store")
+
+	incident, action, err := h.IncidentUsecase.HandleActionToken(c, c.PostForm("token"))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		actionPage.Execute(c.Writer, actionView{Message: err.Error()})
+		return
+	}
+
+	message := "Incident acknowledged. No further reminders will be sent."
+	if action == domain.IncidentActionSnooze {
+		message = "Incident snoozed until " + incident.SnoozedUntil.Format("2006-01-02 15:04 MST") + "."
+	}
+	c.Status(http.StatusOK)
+	actionPage.Execute(c.Writer, actionView{Message: message, Incident: incident})
+}
@@ -0,0 +1,318 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/labels"
+	tokenutil "spectator.main/internals/util"
+)
+
+// linkSnoozeMinutes is how long the one-click snooze link silences an incident.
+const linkSnoozeMinutes = 60
+
+// defaultLinkExpiry is how many hours one-click links stay valid when
+// LINK_EXPIRY_HOUR is not set.
+const defaultLinkExpiry = 24
+
+type incidentUsecase struct {
+	incidentRepo        domain.IncidentRepository
+	configRepo          domain.ConfigRepository
+	statusPageRepo      domain.StatusPageRepository
+	subscriptionUsecase domain.SubscriptionUsecase
+	contextTimeout      time.Duration
+	publicURL           string
+	linkSecret          string
+	linkExpiry          int
+}
+
+func NewIncidentUsecase(i domain.IncidentRepository, c domain.ConfigRepository, p domain.StatusPageRepository, s domain.SubscriptionUsecase, to time.Duration, publicURL string, linkSecret string, linkExpiry int) domain.IncidentUsecase {
+	if linkExpiry <= 0 {
+		linkExpiry = defaultLinkExpiry
+	}
+	return &incidentUsecase{
+		incidentRepo:        i,
+		configRepo:          c,
+		statusPageRepo:      p,
+		subscriptionUsecase: s,
+		contextTimeout:      to,
+		publicURL:           strings.TrimSuffix(publicURL, "/"),
+		linkSecret:          linkSecret,
+		linkExpiry:          linkExpiry,
+	}
+}
+
+func (i *incidentUsecase) FindOne(c context.Context, id string) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	res, err := i.incidentRepo.FindOne(ctx, id)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// GetByUserID lists the user's incidents, with a selector only those
+// concerning a site whose labels meet it.
+func (i *incidentUsecase) GetByUserID(c context.Context, userID string, status string, selector string) ([]domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := i.incidentRepo.GetByUserID(ctx, userID, status)
+	if err != nil {
+		return res, err
+	}
+	if len(sel) == 0 {
+		return res, nil
+	}
+
+	configs, err := i.configRepo.GetAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sites := sel.Sites(configs)
+
+	picked := []domain.Incident{}
+	for _, incident := range res {
+		for _, site := range sites {
+			if incident.AffectsSite(site.ConfigID, site.SiteUrl) {
+				picked = append(picked, incident)
+				break
+			}
+		}
+	}
+
+	return picked, nil
+}
+
+func (i *incidentUsecase) Acknowledge(c context.Context, id string, userID string) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	userHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	return i.incidentRepo.Acknowledge(ctx, id, &userHex, domain.AcknowledgedViaAPI, time.Now())
+}
+
+func (i *incidentUsecase) Unacknowledge(c context.Context, id string) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	return i.incidentRepo.Unacknowledge(ctx, id)
+}
+
+func (i *incidentUsecase) Snooze(c context.Context, id string, duration time.Duration) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	return i.incidentRepo.Snooze(ctx, id, time.Now().Add(duration))
+}
+
+// CheckActionToken returns the incident and action a signed one-click link
+// carries without applying it.
+func (i *incidentUsecase) CheckActionToken(c context.Context, token string) (*domain.Incident, string, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	claims, err := tokenutil.ParseIncidentActionToken(token, i.linkSecret)
+	if err != nil {
+		return nil, "", errors.New("invalid or expired link")
+	}
+	if claims.Action != domain.IncidentActionAcknowledge && claims.Action != domain.IncidentActionSnooze {
+		return nil, "", errors.New("unknown incident action")
+	}
+
+	res, err := i.incidentRepo.FindOne(ctx, claims.IncidentID)
+	return res, claims.Action, err
+}
+
+// HandleActionToken applies the action carried by a signed one-click link and
+// returns the updated incident along with the action performed.
+func (i *incidentUsecase) HandleActionToken(c context.Context, token string) (*domain.Incident, string, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	claims, err := tokenutil.ParseIncidentActionToken(token, i.linkSecret)
+	if err != nil {
+		return nil, "", errors.New("invalid or expired link")
+	}
+
+	switch claims.Action {
+	case domain.IncidentActionAcknowledge:
+		var userID *primitive.ObjectID
+		if userHex, err := primitive.ObjectIDFromHex(claims.UserID); err == nil {
+			userID = &userHex
+		}
+		res, err := i.incidentRepo.Acknowledge(ctx, claims.IncidentID, userID, domain.AcknowledgedViaLink, time.Now())
+		return res, claims.Action, err
+	case domain.IncidentActionSnooze:
+		until := time.Now().Add(time.Duration(claims.SnoozeMinutes) * time.Minute)
+		res, err := i.incidentRepo.Snooze(ctx, claims.IncidentID, until)
+		return res, claims.Action, err
+	}
+
+	return nil, "", errors.New("unknown incident action")
+}
+
+// Track folds a state change into the site's open incident, opening one on
+// the first failing region and resolving it when the last region recovers.
+func (i *incidentUsecase) Track(c context.Context, config *domain.ConfigDetails, event *domain.AlertEvent) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	incident, err := i.incidentRepo.FindOpen(ctx, config.ID, event.SiteUrl)
+	if err != nil {
+		if event.Status != domain.AlertStatusDown {
+			return nil, nil
+		}
+		incident = &domain.Incident{
+			ID:             primitive.NewObjectID(),
+			UserID:         config.UserID,
+			ConfigID:       config.ID,
+			SiteUrl:        event.SiteUrl,
+			CreatedAt:      time.Now(),
+			UpdatedAt:      time.Now(),
+			Status:         domain.IncidentOpen,
+			Regions:        []string{event.Region},
+			Error:          event.Error,
+			StartedAt:      event.OccurredAt,
+			LastNotifiedAt: event.OccurredAt,
+			Source:         domain.IncidentSourceProbe,
+		}
+		incident, err = i.incidentRepo.InsertOne(ctx, incident)
+		if err != nil {
+			return nil, err
+		}
+		i.notifySubscribers(ctx, incident, domain.SubscriptionIncidentCreated)
+		return incident, nil
+	}
+
+	regions := []string{}
+	for _, region := range incident.Regions {
+		if region != event.Region {
+			regions = append(regions, region)
+		}
+	}
+
+	if event.Status == domain.AlertStatusDown {
+		regions = append(regions, event.Region)
+		incident.Error = event.Error
+	} else if len(regions) == 0 {
+		err = i.incidentRepo.Resolve(ctx, incident.ID, event.OccurredAt)
+		if err != nil {
+			return nil, err
+		}
+		incident.Status = domain.IncidentResolved
+		incident.ResolvedAt = &event.OccurredAt
+		incident.Regions = regions
+		i.notifySubscribers(ctx, incident, domain.SubscriptionIncidentResolved)
+		return incident, nil
+	}
+
+	err = i.incidentRepo.UpdateRegions(ctx, incident.ID, regions, incident.Error)
+	if err != nil {
+		return nil, err
+	}
+	changed := !sameRegions(incident.Regions, regions)
+	incident.Regions = regions
+	if changed {
+		i.notifySubscribers(ctx, incident, domain.SubscriptionIncidentUpdated)
+	}
+
+	return incident, nil
+}
+
+func sameRegions(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, region := range a {
+		seen[region] = true
+	}
+	for _, region := range b {
+		if !seen[region] {
+			return false
+		}
+	}
+	return true
+}
+
+// notifySubscribers queues status page subscriber updates. A failure there
+// must not stop the incident from being tracked, so it is only logged.
+func (i *incidentUsecase) notifySubscribers(ctx context.Context, incident *domain.Incident, event string) {
+	if err := i.subscriptionUsecase.NotifyIncident(ctx, incident, event); err != nil {
+		log.Println("subscriber notification for incident", incident.ID.Hex(), "failed:", err)
+	}
+}
+
+func (i *incidentUsecase) GetOpenUnacknowledged(c context.Context) ([]domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	return i.incidentRepo.GetOpenUnacknowledged(ctx)
+}
+
+func (i *incidentUsecase) MarkNotified(c context.Context, id primitive.ObjectID, at time.Time) error {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	return i.incidentRepo.MarkNotified(ctx, id, at)
+}
+
+// Links builds the incident URL and signed action links for one recipient.
+// Links that cannot be signed are left empty rather than failing the alert.
+func (i *incidentUsecase) Links(incident *domain.Incident, recipientID *primitive.ObjectID) domain.IncidentLinks {
+	links := domain.IncidentLinks{
+		Incident: i.publicURL + "/api/v1/incident/" + incident.ID.Hex(),
+	}
+	if i.linkSecret == "" {
+		return links
+	}
+
+	claims := domain.IncidentActionClaims{IncidentID: incident.ID.Hex()}
+	if recipientID != nil {
+		claims.UserID = recipientID.Hex()
+	}
+
+	ack := claims
+	ack.Action = domain.IncidentActionAcknowledge
+	if token, err := tokenutil.CreateIncidentActionToken(&ack, i.linkSecret, i.linkExpiry); err == nil {
+		links.Acknowledge = i.publicURL + "/api/v1/incident-action?token=" + url.QueryEscape(token)
+	}
+
+	snooze := claims
+	snooze.Action = domain.IncidentActionSnooze
+	snooze.SnoozeMinutes = linkSnoozeMinutes
+	if token, err := tokenutil.CreateIncidentActionToken(&snooze, i.linkSecret, i.linkExpiry); err == nil {
+		links.Snooze = i.publicURL + "/api/v1/incident-action?token=" + url.QueryEscape(token)
+	}
+
+	return links
+}
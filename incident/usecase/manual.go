@@ -0,0 +1,220 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+)
+
+// validateAffected checks that every site belongs to one of the user's
+// configs and every component to one of the user's status pages.
+func (i *incidentUsecase) validateAffected(ctx context.Context, userID primitive.ObjectID, sites []domain.StatusSite, componentIDs []primitive.ObjectID) error {
+	configs := map[primitive.ObjectID]*domain.ConfigDetails{}
+	for _, site := range sites {
+		config, ok := configs[site.ConfigID]
+		if !ok {
+			var err error
+			config, err = i.configRepo.FindOne(ctx, site.ConfigID.Hex())
+			if err != nil || config.UserID != userID {
+				return errors.New("config " + site.ConfigID.Hex() + " not found")
+			}
+			configs[site.ConfigID] = config
+		}
+
+		found := false
+		for _, siteConfig := range config.SiteConfig {
+			if siteConfig.SiteUrl == site.SiteUrl {
+				found = true
+			}
+		}
+		if !found {
+			return errors.New("site " + site.SiteUrl + " not found in config " + site.ConfigID.Hex())
+		}
+	}
+
+	if len(componentIDs) == 0 {
+		return nil
+	}
+
+	pages, err := i.statusPageRepo.GetByUserID(ctx, userID.Hex())
+	if err != nil {
+		return err
+	}
+	for _, id := range componentIDs {
+		found := false
+		for _, page := range pages {
+			for _, component := range page.Components {
+				if component.ID == id {
+					found = true
+				}
+			}
+		}
+		if !found {
+			return errors.New("component " + id.Hex() + " not found")
+		}
+	}
+
+	return nil
+}
+
+func (i *incidentUsecase) CreateManual(c context.Context, userID string, request *domain.ManualIncidentRequest) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	userHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+
+	if len(request.Sites) == 0 && len(request.ComponentIDs) == 0 {
+		return nil, errors.New("incident needs at least one affected site or component")
+	}
+	err = i.validateAffected(ctx, userHex, request.Sites, request.ComponentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	startedAt := now
+	if request.StartedAt != nil {
+		startedAt = *request.StartedAt
+	}
+	if request.State == "" {
+		request.State = domain.IncidentInvestigating
+	}
+	if request.Impact == "" {
+		request.Impact = domain.IncidentImpactMinor
+	}
+	if request.ComponentIDs == nil {
+		request.ComponentIDs = []primitive.ObjectID{}
+	}
+
+	incident := &domain.Incident{
+		ID:             primitive.NewObjectID(),
+		UserID:         userHex,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+		Status:         domain.IncidentOpen,
+		Regions:        []string{},
+		StartedAt:      startedAt,
+		LastNotifiedAt: now,
+		Source:         domain.IncidentSourceManual,
+		Title:          request.Title,
+		Impact:         request.Impact,
+		State:          request.State,
+		Sites:          request.Sites,
+		ComponentIDs:   request.ComponentIDs,
+		Updates: []domain.IncidentUpdate{{
+			ID:        primitive.NewObjectID(),
+			State:     request.State,
+			Body:      request.Body,
+			CreatedAt: now,
+		}},
+	}
+
+	incident, err = i.incidentRepo.InsertOne(ctx, incident)
+	if err != nil {
+		return nil, err
+	}
+	i.notifySubscribers(ctx, incident, domain.SubscriptionIncidentCreated)
+
+	return incident, nil
+}
+
+// PostUpdate adds a timeline update. Posting a resolved update resolves the
+// incident; any other state on a resolved incident reopens it.
+func (i *incidentUsecase) PostUpdate(c context.Context, id string, update *domain.IncidentUpdate) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	incident, err := i.incidentRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Source != domain.IncidentSourceManual {
+		return nil, errors.New("timeline updates can only be posted to manual incidents")
+	}
+
+	now := time.Now()
+	update.ID = primitive.NewObjectID()
+	update.CreatedAt = now
+
+	var resolvedAt *time.Time
+	event := domain.SubscriptionIncidentUpdated
+	if update.State == domain.IncidentStateResolved {
+		resolvedAt = &now
+		event = domain.SubscriptionIncidentResolved
+	}
+
+	incident, err = i.incidentRepo.AddUpdate(ctx, id, update, resolvedAt)
+	if err != nil {
+		return nil, err
+	}
+	i.notifySubscribers(ctx, incident, event)
+
+	return incident, nil
+}
+
+func (i *incidentUsecase) SetAffected(c context.Context, id string, request *domain.IncidentAffectedRequest) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	incident, err := i.incidentRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if incident.Source != domain.IncidentSourceManual {
+		return nil, errors.New("affected sites can only be changed on manual incidents")
+	}
+	if len(request.Sites) == 0 && len(request.ComponentIDs) == 0 {
+		return nil, errors.New("incident needs at least one affected site or component")
+	}
+
+	err = i.validateAffected(ctx, incident.UserID, request.Sites, request.ComponentIDs)
+	if err != nil {
+		return nil, err
+	}
+	if request.Sites == nil {
+		request.Sites = []domain.StatusSite{}
+	}
+	if request.ComponentIDs == nil {
+		request.ComponentIDs = []primitive.ObjectID{}
+	}
+
+	return i.incidentRepo.SetAffected(ctx, id, request.Sites, request.ComponentIDs)
+}
+
+// SetPostmortem saves the postmortem draft, publishing or unpublishing it as
+// requested. Only resolved incidents can have their postmortem published.
+func (i *incidentUsecase) SetPostmortem(c context.Context, id string, request *domain.PostmortemRequest) (*domain.Incident, error) {
+
+	ctx, cancel := context.WithTimeout(c, i.contextTimeout)
+	defer cancel()
+
+	incident, err := i.incidentRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	postmortem := &domain.Postmortem{
+		Body:      request.Body,
+		UpdatedAt: time.Now(),
+	}
+	if request.Publish {
+		if incident.Status != domain.IncidentResolved {
+			return nil, errors.New("postmortems can only be published once the incident is resolved")
+		}
+		postmortem.PublishedAt = &postmortem.UpdatedAt
+		if incident.Postmortem != nil && incident.Postmortem.PublishedAt != nil {
+			postmortem.PublishedAt = incident.Postmortem.PublishedAt
+		}
+	}
+
+	return i.incidentRepo.SetPostmortem(ctx, id, postmortem)
+}
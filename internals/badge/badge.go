@@ -0,0 +1,179 @@
+package badge
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"spectator.main/domain"
+)
+
+const (
+	ColorBrightGreen = "#4c1"
+	ColorGreen       = "#97ca00"
+	ColorYellowGreen = "#a4a61d"
+	ColorYellow      = "#dfb317"
+	ColorOrange      = "#fe7d37"
+	ColorRed         = "#e05d44"
+	ColorBlue        = "#007ec6"
+	ColorGrey        = "#9f9f9f"
+
+	labelColor = "#555"
+	fontFamily = "Verdana,Geneva,DejaVu Sans,sans-serif"
+)
+
+// Styles lists the supported badge styles, the first being the default.
+var Styles = []string{
+	domain.BadgeStyleFlat,
+	domain.BadgeStyleFlatSquare,
+	domain.BadgeStylePlastic,
+	domain.BadgeStyleForTheBadge,
+}
+
+// Approximate advance widths of 11px Verdana, the font shields.io measures
+// with. Characters not listed fall back to the width of a lowercase letter.
+var charWidths = map[rune]float64{
+	' ': 3.9, '!': 4.3, '"': 5.1, '#': 9.2, '$': 7, '%': 11.8, '&': 8, '\'': 3,
+	'(': 4.9, ')': 4.9, '*': 7, '+': 9.2, ',': 4, '-': 5, '.': 4, '/': 4.9,
+	':': 4.9, ';': 4.9, '<': 9.2, '=': 9.2, '>': 9.2, '?': 6, '@': 11,
+	'[': 4.9, '\\': 4.9, ']': 4.9, '_': 7, '|': 4.9,
+	'0': 7, '1': 7, '2': 7, '3': 7, '4': 7, '5': 7, '6': 7, '7': 7, '8': 7, '9': 7,
+	'A': 7.5, 'B': 7.5, 'C': 7.7, 'D': 8.5, 'E': 7, 'F': 6.3, 'G': 8.5, 'H': 8.3,
+	'I': 4.6, 'J': 5, 'K': 7.6, 'L': 6.1, 'M': 9.3, 'N': 8.2, 'O': 8.7, 'P': 6.6,
+	'Q': 8.7, 'R': 7.7, 'S': 7.5, 'T': 6.8, 'U': 8.1, 'V': 7.5, 'W': 10.9, 'X': 7.5,
+	'Y': 6.8, 'Z': 7.5,
+	'a': 6.6, 'b': 6.8, 'c': 5.8, 'd': 6.8, 'e': 6.6, 'f': 3.8, 'g': 6.8, 'h': 7,
+	'i': 3, 'j': 3.8, 'k': 6.5, 'l': 3, 'm': 10.7, 'n': 7, 'o': 6.7, 'p': 6.8,
+	'q': 6.8, 'r': 4.7, 's': 5.7, 't': 4.3, 'u': 7, 'v': 6.5, 'w': 9, 'x': 6.5,
+	'y': 6.5, 'z': 5.8,
+}
+
+func textWidth(s string) float64 {
+	var w float64
+	for _, r := range s {
+		if cw, ok := charWidths[r]; ok {
+			w += cw
+		} else {
+			w += 7
+		}
+	}
+	return w
+}
+
+// IsStyle reports whether style is one of Styles.
+func IsStyle(style string) bool {
+	for _, s := range Styles {
+		if s == style {
+			return true
+		}
+	}
+	return false
+}
+
+// SVG renders the badge in the given style. Unknown styles render as flat.
+func SVG(b *domain.Badge, style string) []byte {
+	label, message := b.Label, b.Message
+	padding, height, fontSize, textY := 6.0, 20.0, 11.0, 14.0
+	spacing := 0.0
+
+	switch style {
+	case domain.BadgeStylePlastic:
+		height, textY = 18, 13
+	case domain.BadgeStyleForTheBadge:
+		label, message = strings.ToUpper(label), strings.ToUpper(message)
+		padding, height, fontSize, textY, spacing = 12, 28, 10, 18, 1.25
+	}
+
+	scale := fontSize / 11
+	width := func(s string) float64 {
+		if s == "" {
+			return 0
+		}
+		return textWidth(s)*scale + spacing*float64(len([]rune(s))) + 2*padding
+	}
+	lw, mw := width(label), width(message)
+	if lw == 0 && mw == 0 {
+		mw = 2 * padding
+	}
+	total := lw + mw
+
+	radius := "3"
+	switch style {
+	case domain.BadgeStyleFlatSquare, domain.BadgeStyleForTheBadge:
+		radius = "0"
+	case domain.BadgeStylePlastic:
+		radius = "4"
+	}
+
+	var gradient string
+	switch style {
+	case domain.BadgeStyleFlat:
+		gradient = `<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>`
+	case domain.BadgeStylePlastic:
+		gradient = `<linearGradient id="s" x2="0" y2="100%"><stop offset="0" stop-color="#fff" stop-opacity=".7"/><stop offset=".1" stop-color="#aaa" stop-opacity=".1"/><stop offset=".9" stop-opacity=".3"/><stop offset="1" stop-opacity=".5"/></linearGradient>`
+	}
+	shadow := gradient != ""
+
+	title := message
+	if label != "" {
+		title = label + ": " + message
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" role="img" aria-label="%s">`, num(total), num(height), html.EscapeString(title))
+	fmt.Fprintf(&sb, `<title>%s</title>`, html.EscapeString(title))
+	sb.WriteString(gradient)
+	fmt.Fprintf(&sb, `<clipPath id="r"><rect width="%s" height="%s" rx="%s" fill="#fff"/></clipPath>`, num(total), num(height), radius)
+	sb.WriteString(`<g clip-path="url(#r)">`)
+	fmt.Fprintf(&sb, `<rect width="%s" height="%s" fill="%s"/>`, num(lw), num(height), labelColor)
+	fmt.Fprintf(&sb, `<rect x="%s" width="%s" height="%s" fill="%s"/>`, num(lw), num(mw), num(height), html.EscapeString(b.Color))
+	if gradient != "" {
+		fmt.Fprintf(&sb, `<rect width="%s" height="%s" fill="url(#s)"/>`, num(total), num(height))
+	}
+	sb.WriteString(`</g>`)
+
+	fmt.Fprintf(&sb, `<g fill="#fff" text-anchor="middle" font-family="%s" text-rendering="geometricPrecision" font-size="%s"`, fontFamily, num(fontSize))
+	if style == domain.BadgeStyleForTheBadge {
+		fmt.Fprintf(&sb, ` font-weight="bold" letter-spacing="%s"`, num(spacing))
+	}
+	sb.WriteString(`>`)
+	for _, part := range []struct {
+		text string
+		x    float64
+	}{{label, lw / 2}, {message, lw + mw/2}} {
+		if part.text == "" {
+			continue
+		}
+		text := html.EscapeString(part.text)
+		if shadow {
+			fmt.Fprintf(&sb, `<text x="%s" y="%s" fill="#010101" fill-opacity=".3">%s</text>`, num(part.x), num(textY+1), text)
+		}
+		fmt.Fprintf(&sb, `<text x="%s" y="%s">%s</text>`, num(part.x), num(textY), text)
+	}
+	sb.WriteString(`</g></svg>`)
+
+	return []byte(sb.String())
+}
+
+func num(f float64) string {
+	return strings.TrimSuffix(strings.TrimRight(fmt.Sprintf("%.1f", f), "0"), ".")
+}
+
+// UptimeColor picks the shields.io colour conventionally used for an uptime
+// percentage.
+func UptimeColor(percent float64) string {
+	switch {
+	case percent >= 99.9:
+		return ColorBrightGreen
+	case percent >= 99:
+		return ColorGreen
+	case percent >= 97:
+		return ColorYellowGreen
+	case percent >= 95:
+		return ColorYellow
+	case percent >= 90:
+		return ColorOrange
+	default:
+		return ColorRed
+	}
+}
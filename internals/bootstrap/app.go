@@ -1,6 +1,8 @@
 package bootstrap
 
 import (
+	"log"
+
 	"spectator.main/internals/mongo"
 	"spectator.main/internals/rabbitmq"
 )
@@ -9,13 +11,20 @@ type Application struct {
 	Config   *Config
 	Mongo    mongo.Client
 	RabbitMQ rabbitmq.MQPublisher
+	Notifier rabbitmq.MQPublisher
 }
 
 func App() Application {
 	app := &Application{}
 	app.Config = InitConfig()
+	// The link secret signs incident action links, subscriber links and
+	// viewer sessions. Without it anyone could forge them.
+	if app.Config.LinkSecret == "" {
+		log.Fatal("LINK_SECRET must be set")
+	}
 	app.Mongo = NewMongoDatabase(app.Config)
-	app.RabbitMQ = NewRabbitMQInstance(app.Config)
+	app.RabbitMQ = NewRabbitMQInstance(app.Config, app.Config.RabbitMQQueueName)
+	app.Notifier = NewRabbitMQInstance(app.Config, app.Config.NotificationQueueName)
 	return *app
 }
 
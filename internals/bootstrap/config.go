@@ -18,6 +18,13 @@ type Config struct {
 	RefreshTokenSecret     string `mapstructure:"REFRESH_TOKEN_SECRET"`
 	RabbitMQURI            string `mapstructure:"RABBITMQ_URI"`
 	RabbitMQQueueName      string `mapstructure:"RABBITMQ_QUEUE_NAME"`
+	NotificationQueueName  string `mapstructure:"RABBITMQ_NOTIFICATION_QUEUE_NAME"`
+	PublicURL              string `mapstructure:"PUBLIC_URL"`
+	LinkSecret             string `mapstructure:"LINK_SECRET"`
+	LinkExpiryHour         int    `mapstructure:"LINK_EXPIRY_HOUR"`
+
+	SubscriptionRatePerMinute int `mapstructure:"SUBSCRIPTION_RATE_PER_MINUTE"`
+	ViewerSessionExpiryHour   int `mapstructure:"VIEWER_SESSION_EXPIRY_HOUR"`
 }
 
 func InitConfig() *Config {
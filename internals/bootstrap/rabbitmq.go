@@ -7,17 +7,17 @@ import (
 	"spectator.main/internals/rabbitmq"
 )
 
-func NewRabbitMQInstance(config *Config) rabbitmq.MQPublisher {
+func NewRabbitMQInstance(config *Config, queueName string) rabbitmq.MQPublisher {
 	conn, err := amqp.Dial(config.RabbitMQURI)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	publisher, err := rabbitmq.NewRabbitMQPublisher(conn, config.RabbitMQQueueName)
+	publisher, err := rabbitmq.NewRabbitMQPublisher(conn, queueName)
 	if err != nil {
 		publisher.Close()
 		log.Fatal(err)
 	}
-	log.Println("Connected to RabbitMQ")
+	log.Println("Connected to RabbitMQ queue", queueName)
 	return publisher
 }
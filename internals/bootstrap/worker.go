@@ -0,0 +1,22 @@
+package bootstrap
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// RunEvery calls fn on every tick of interval for the lifetime of the
+// process, logging rather than stopping on errors.
+func RunEvery(name string, interval time.Duration, fn func(ctx context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			err := fn(context.Background())
+			if err != nil {
+				log.Println(name+":", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,299 @@
+package ical
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"spectator.main/domain"
+)
+
+const (
+	prodID = "-//Spectator//Spectator Calendar//EN"
+
+	utcLayout   = "20060102T150405Z"
+	localLayout = "20060102T150405"
+	dateLayout  = "20060102"
+
+	// lineLimit is the longest content line RFC 5545 allows, in octets.
+	lineLimit = 75
+
+	// tzHorizon is how many years past the last event VTIMEZONE definitions
+	// cover when an event repeats without end.
+	tzHorizon = 5
+)
+
+// Encode renders the calendar as RFC 5545 iCalendar. Timed events in a zone
+// other than UTC carry a TZID whose VTIMEZONE lists that zone's offset
+// changes over the years the events span.
+func Encode(cal *domain.Calendar, now time.Time) []byte {
+	var w writer
+	w.line("BEGIN:VCALENDAR")
+	w.line("VERSION:2.0")
+	w.line("PRODID:" + prodID)
+	w.line("CALSCALE:GREGORIAN")
+	w.line("METHOD:PUBLISH")
+	w.line("X-WR-CALNAME:" + text(cal.Name))
+	if cal.Description != "" {
+		w.line("X-WR-CALDESC:" + text(cal.Description))
+	}
+	if cal.TimeZone != "" {
+		w.line("X-WR-TIMEZONE:" + cal.TimeZone)
+	}
+	w.line("REFRESH-INTERVAL;VALUE=DURATION:PT1H")
+	w.line("X-PUBLISHED-TTL:PT1H")
+
+	for _, tz := range zones(cal, now) {
+		w.timezone(tz)
+	}
+	for i := range cal.Events {
+		w.event(&cal.Events[i], now)
+	}
+
+	w.line("END:VCALENDAR")
+	return []byte(w.String())
+}
+
+type writer struct {
+	strings.Builder
+}
+
+// line writes a content line, folding it so no line exceeds lineLimit
+// octets. Folds never split a UTF-8 sequence.
+func (w *writer) line(s string) {
+	limit := lineLimit
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		w.WriteString(s[:cut] + "\r\n ")
+		s = s[cut:]
+		// Continuation lines lose one octet to the leading space.
+		limit = lineLimit - 1
+	}
+	w.WriteString(s + "\r\n")
+}
+
+func (w *writer) event(e *domain.CalendarEvent, now time.Time) {
+	w.line("BEGIN:VEVENT")
+	w.line("UID:" + text(e.UID))
+	stamp := e.Updated
+	if stamp.IsZero() {
+		stamp = now
+	}
+	w.line("DTSTAMP:" + stamp.UTC().Format(utcLayout))
+	w.line("LAST-MODIFIED:" + stamp.UTC().Format(utcLayout))
+
+	if e.AllDay {
+		end := e.End
+		if !end.After(e.Start) {
+			end = e.Start.AddDate(0, 0, 1)
+		}
+		w.line("DTSTART;VALUE=DATE:" + e.Start.Format(dateLayout))
+		w.line("DTEND;VALUE=DATE:" + end.Format(dateLayout))
+		w.line("TRANSP:TRANSPARENT")
+	} else {
+		loc := location(e.TimeZone)
+		w.line("DTSTART" + dateTime(e.Start, loc))
+		w.line("DTEND" + dateTime(e.End, loc))
+	}
+	if e.Recurrence != nil {
+		w.line("RRULE:" + rrule(e.Recurrence))
+	}
+
+	w.line("SUMMARY:" + text(e.Summary))
+	if e.Description != "" {
+		w.line("DESCRIPTION:" + text(e.Description))
+	}
+	if e.URL != "" {
+		w.line("URL:" + e.URL)
+	}
+
+	if e.Alarm > 0 {
+		w.line("BEGIN:VALARM")
+		w.line("ACTION:DISPLAY")
+		w.line("DESCRIPTION:" + text(e.Summary))
+		w.line("TRIGGER:-" + duration(e.Alarm))
+		w.line("END:VALARM")
+	}
+	w.line("END:VEVENT")
+}
+
+// dateTime formats the value and parameters of a date-time property. UTC
+// times use the Z form, others the zone's local time and its TZID.
+func dateTime(t time.Time, loc *time.Location) string {
+	if loc == time.UTC {
+		return ":" + t.UTC().Format(utcLayout)
+	}
+	return ";TZID=" + loc.String() + ":" + t.In(loc).Format(localLayout)
+}
+
+func rrule(r *domain.Recurrence) string {
+	parts := []string{"FREQ=" + strings.ToUpper(r.Frequency)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	} else if r.Until != nil {
+		// UNTIL must be in UTC when DTSTART carries a TZID.
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(utcLayout))
+	}
+	return strings.Join(parts, ";")
+}
+
+// duration formats d as an RFC 5545 duration, whole days as days.
+func duration(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return "P" + strconv.Itoa(int(d/(24*time.Hour))) + "D"
+	}
+	s := "PT"
+	if h := d / time.Hour; h > 0 {
+		s += strconv.Itoa(int(h)) + "H"
+		d -= h * time.Hour
+	}
+	if m := d / time.Minute; m > 0 {
+		s += strconv.Itoa(int(m)) + "M"
+		d -= m * time.Minute
+	}
+	if d > 0 || s == "PT" {
+		s += strconv.Itoa(int(d/time.Second)) + "S"
+	}
+	return s
+}
+
+// text escapes a TEXT value.
+func text(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+func location(name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+type zone struct {
+	loc      *time.Location
+	from, to int
+}
+
+// zones lists the non-UTC zones of timed events with the range of years
+// their definitions must cover.
+func zones(cal *domain.Calendar, now time.Time) []zone {
+	byName := map[string]*zone{}
+	for _, e := range cal.Events {
+		if e.AllDay {
+			continue
+		}
+		loc := location(e.TimeZone)
+		if loc == time.UTC {
+			continue
+		}
+
+		last := e.End.Year()
+		if r := e.Recurrence; r != nil {
+			switch {
+			case r.Until != nil:
+				last = r.Until.Year()
+			default:
+				last = now.Year() + tzHorizon
+			}
+		}
+
+		z, ok := byName[loc.String()]
+		if !ok {
+			z = &zone{loc: loc, from: e.Start.Year(), to: last}
+			byName[loc.String()] = z
+		}
+		if e.Start.Year() < z.from {
+			z.from = e.Start.Year()
+		}
+		if last > z.to {
+			z.to = last
+		}
+	}
+
+	res := []zone{}
+	for _, z := range byName {
+		res = append(res, *z)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].loc.String() < res[j].loc.String() })
+	return res
+}
+
+// timezone writes a VTIMEZONE holding the offset in force at the start of
+// the range and every offset change within it.
+func (w *writer) timezone(z zone) {
+	w.line("BEGIN:VTIMEZONE")
+	w.line("TZID:" + z.loc.String())
+
+	at := time.Date(z.from, time.January, 1, 0, 0, 0, 0, z.loc)
+	end := time.Date(z.to+1, time.January, 1, 0, 0, 0, 0, z.loc)
+	name, offset := at.Zone()
+	w.observance(at.IsDST(), at.Format(localLayout), offset, offset, name)
+
+	for at.Before(end) {
+		next := at.AddDate(0, 0, 1)
+		if _, o := next.Zone(); o != offset {
+			// Narrow the change down to the second.
+			lo, hi := at, next
+			for hi.Sub(lo) > time.Second {
+				mid := lo.Add(hi.Sub(lo) / 2)
+				if _, o := mid.Zone(); o == offset {
+					lo = mid
+				} else {
+					hi = mid
+				}
+			}
+			change := hi.Truncate(time.Second)
+			newName, newOffset := change.Zone()
+			// DTSTART is the local time just before the change.
+			local := change.Add(time.Duration(offset) * time.Second).UTC()
+			w.observance(change.IsDST(), local.Format(localLayout), offset, newOffset, newName)
+			offset = newOffset
+		}
+		at = next
+	}
+
+	w.line("END:VTIMEZONE")
+}
+
+func (w *writer) observance(dst bool, start string, from, to int, name string) {
+	kind := "STANDARD"
+	if dst {
+		kind = "DAYLIGHT"
+	}
+	w.line("BEGIN:" + kind)
+	w.line("DTSTART:" + start)
+	w.line("TZOFFSETFROM:" + utcOffset(from))
+	w.line("TZOFFSETTO:" + utcOffset(to))
+	if name != "" {
+		w.line("TZNAME:" + text(name))
+	}
+	w.line("END:" + kind)
+}
+
+func utcOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	s := fmt.Sprintf("%s%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		s += fmt.Sprintf("%02d", seconds%60)
+	}
+	return s
+}
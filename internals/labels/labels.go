@@ -0,0 +1,258 @@
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"spectator.main/domain"
+)
+
+const (
+	maxKeyLength   = 63
+	maxValueLength = 63
+	maxLabels      = 64
+)
+
+// Operators a selector requirement can use.
+const (
+	OpEquals    = "="
+	OpNotEquals = "!="
+	OpIn        = "in"
+	OpNotIn     = "notin"
+	OpExists    = "exists"
+	OpMissing   = "!"
+)
+
+// Keys are stored as field names, so they may not hold "." or "$".
+var (
+	keyPattern   = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_/-]*[A-Za-z0-9])?$`)
+	valuePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9_./-]*[A-Za-z0-9])?$`)
+)
+
+// Validate checks the keys and values of a label set.
+func Validate(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("%w: at most %d labels are allowed", domain.ErrInvalidLabel, maxLabels)
+	}
+	for key, value := range labels {
+		err := validKey(key)
+		if err != nil {
+			return err
+		}
+		err = validValue(value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validKey(key string) error {
+	if len(key) > maxKeyLength || !keyPattern.MatchString(key) {
+		return fmt.Errorf("%w: key %q must be 1-63 letters, digits, '-', '_' or '/', starting and ending with a letter or digit", domain.ErrInvalidLabel, key)
+	}
+	return nil
+}
+
+func validValue(value string) error {
+	if len(value) > maxValueLength || !valuePattern.MatchString(value) {
+		return fmt.Errorf("%w: value %q must be 1-63 letters, digits, '-', '_', '.' or '/', starting and ending with a letter or digit", domain.ErrInvalidLabel, value)
+	}
+	return nil
+}
+
+// Requirement is one comma separated term of a selector.
+type Requirement struct {
+	Key    string
+	Op     string
+	Values []string
+}
+
+// Selector matches label sets that meet all of its requirements. The empty
+// selector matches everything.
+type Selector []Requirement
+
+var (
+	setPattern    = regexp.MustCompile(`^([^\s!=()]+)\s+(in|notin)\s+\(([^()]*)\)$`)
+	errSelector   = fmt.Errorf("%w: selector terms must look like key=value, key!=value, key in (a,b), key notin (a,b), key or !key", domain.ErrInvalidLabel)
+	errEmptyValue = fmt.Errorf("%w: a selector set needs at least one value", domain.ErrInvalidLabel)
+)
+
+// Parse reads a selector such as "env=prod,team in (payments,auth),!legacy".
+func Parse(selector string) (Selector, error) {
+	res := Selector{}
+	for _, term := range split(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		req, err := parseTerm(term)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, req)
+	}
+	return res, nil
+}
+
+// split cuts the selector at the commas outside parentheses.
+func split(selector string) []string {
+	terms := []string{}
+	depth, start := 0, 0
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(terms, selector[start:])
+}
+
+func parseTerm(term string) (Requirement, error) {
+	if m := setPattern.FindStringSubmatch(term); m != nil {
+		req := Requirement{Key: m[1], Op: m[2]}
+		for _, value := range strings.Split(m[3], ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				req.Values = append(req.Values, value)
+			}
+		}
+		if len(req.Values) == 0 {
+			return req, errEmptyValue
+		}
+		return req, check(req)
+	}
+
+	for _, op := range []string{"!=", "==", "="} {
+		if i := strings.Index(term, op); i > 0 {
+			req := Requirement{
+				Key:    strings.TrimSpace(term[:i]),
+				Op:     OpEquals,
+				Values: []string{strings.TrimSpace(term[i+len(op):])},
+			}
+			if op == "!=" {
+				req.Op = OpNotEquals
+			}
+			return req, check(req)
+		}
+	}
+
+	if strings.HasPrefix(term, "!") {
+		req := Requirement{Key: strings.TrimSpace(term[1:]), Op: OpMissing}
+		return req, check(req)
+	}
+	if strings.ContainsAny(term, " =()") {
+		return Requirement{}, errSelector
+	}
+	req := Requirement{Key: term, Op: OpExists}
+	return req, check(req)
+}
+
+func check(req Requirement) error {
+	err := validKey(req.Key)
+	if err != nil {
+		return err
+	}
+	for _, value := range req.Values {
+		err = validValue(value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Matches reports whether labels meet every requirement of the selector.
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, req := range s {
+		value, ok := labels[req.Key]
+		switch req.Op {
+		case OpEquals:
+			if !ok || value != req.Values[0] {
+				return false
+			}
+		case OpNotEquals:
+			if ok && value == req.Values[0] {
+				return false
+			}
+		case OpIn:
+			if !ok || !contains(req.Values, value) {
+				return false
+			}
+		case OpNotIn:
+			if ok && contains(req.Values, value) {
+				return false
+			}
+		case OpExists:
+			if !ok {
+				return false
+			}
+		case OpMissing:
+			if ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Sites returns the sites of configs whose own labels meet the selector,
+// for lists of things that concern sites rather than carry labels.
+func (s Selector) Sites(configs []domain.ConfigDetails) []domain.SiteConfig {
+	res := []domain.SiteConfig{}
+	for _, config := range configs {
+		for _, site := range config.SiteConfig {
+			if s.Matches(site.Labels) {
+				site.ConfigID = config.ID
+				res = append(res, site)
+			}
+		}
+	}
+	return res
+}
+
+// Filter turns the selector into a query on the label map stored under
+// field, to be added to a filter document. As with Matches, != and notin
+// also match documents without the key.
+func (s Selector) Filter(field string) bson.D {
+	if len(s) == 0 {
+		return bson.D{}
+	}
+
+	terms := bson.A{}
+	for _, req := range s {
+		path := field + "." + req.Key
+		switch req.Op {
+		case OpEquals:
+			terms = append(terms, bson.M{path: req.Values[0]})
+		case OpNotEquals:
+			terms = append(terms, bson.M{path: bson.M{"$ne": req.Values[0]}})
+		case OpIn:
+			terms = append(terms, bson.M{path: bson.M{"$in": req.Values}})
+		case OpNotIn:
+			terms = append(terms, bson.M{path: bson.M{"$nin": req.Values}})
+		case OpExists:
+			terms = append(terms, bson.M{path: bson.M{"$exists": true}})
+		case OpMissing:
+			terms = append(terms, bson.M{path: bson.M{"$exists": false}})
+		}
+	}
+	return bson.D{{Key: "$and", Value: terms}}
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
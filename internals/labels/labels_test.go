@@ -0,0 +1,108 @@
+package labels_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"spectator.main/domain"
+	"spectator.main/internals/labels"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+		want     labels.Selector
+	}{
+		{"empty", "", labels.Selector{}},
+		{"blank terms", " , ", labels.Selector{}},
+		{"equals", "env=prod", labels.Selector{{Key: "env", Op: labels.OpEquals, Values: []string{"prod"}}}},
+		{"double equals", "env==prod", labels.Selector{{Key: "env", Op: labels.OpEquals, Values: []string{"prod"}}}},
+		{"not equals", "env!=prod", labels.Selector{{Key: "env", Op: labels.OpNotEquals, Values: []string{"prod"}}}},
+		{"in", "team in (payments, auth)", labels.Selector{{Key: "team", Op: labels.OpIn, Values: []string{"payments", "auth"}}}},
+		{"notin", "team notin (payments,auth)", labels.Selector{{Key: "team", Op: labels.OpNotIn, Values: []string{"payments", "auth"}}}},
+		{"exists", "legacy", labels.Selector{{Key: "legacy", Op: labels.OpExists}}},
+		{"missing", "!legacy", labels.Selector{{Key: "legacy", Op: labels.OpMissing}}},
+		{"several", "env=prod,team in (payments,auth),!legacy", labels.Selector{
+			{Key: "env", Op: labels.OpEquals, Values: []string{"prod"}},
+			{Key: "team", Op: labels.OpIn, Values: []string{"payments", "auth"}},
+			{Key: "legacy", Op: labels.OpMissing},
+		}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := labels.Parse(test.selector)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", test.selector, err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", test.selector, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseRejectsMalformed(t *testing.T) {
+	tests := []struct {
+		name     string
+		selector string
+	}{
+		{"no key", "=prod"},
+		{"empty value", "env="},
+		{"empty set", "team in ()"},
+		{"blank set", "team in ( , )"},
+		{"unclosed set", "team in (payments"},
+		{"unknown operator", "team has (payments)"},
+		{"space in key", "my team"},
+		{"bare bang", "!"},
+		{"dollar in key", "$where=1"},
+		{"dot in key", "a.b=c"},
+		{"bad value", "env=prod!"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := labels.Parse(test.selector)
+			if !errors.Is(err, domain.ErrInvalidLabel) {
+				t.Errorf("Parse(%q) = %v, want ErrInvalidLabel", test.selector, err)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	set := map[string]string{"env": "prod", "team": "payments"}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"env=prod", true},
+		{"env=dev", false},
+		{"env!=dev", true},
+		{"region!=eu", true},
+		{"team in (payments,auth)", true},
+		{"team in (auth)", false},
+		{"team notin (auth)", true},
+		{"region notin (eu)", true},
+		{"team notin (payments)", false},
+		{"env", true},
+		{"region", false},
+		{"!region", true},
+		{"!env", false},
+		{"env=prod,!legacy,team in (payments)", true},
+		{"env=prod,legacy", false},
+	}
+
+	for _, test := range tests {
+		selector, err := labels.Parse(test.selector)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", test.selector, err)
+		}
+		if got := selector.Matches(set); got != test.want {
+			t.Errorf("%q matches %v = %v, want %v", test.selector, set, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package markdown
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+// HTML renders the subset of Markdown used for postmortems: ATX headings,
+// paragraphs, bullet and numbered lists, block quotes, fenced code, rules,
+// and inline code, bold, italics and links. All text is escaped before any
+// markup is added and only http(s) and mailto links are kept, so the output
+// is safe to embed in a page.
+func HTML(src string) string {
+	var (
+		out   strings.Builder
+		para  []string
+		list  string
+		quote []string
+		code  []string
+		fence bool
+	)
+
+	flushPara := func() {
+		if len(para) > 0 {
+			out.WriteString("<p>" + inline(strings.Join(para, " ")) + "</p>\n")
+			para = nil
+		}
+	}
+	flushList := func() {
+		if list != "" {
+			out.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	flushQuote := func() {
+		if len(quote) > 0 {
+			out.WriteString("<blockquote>" + HTML(strings.Join(quote, "\n")) + "</blockquote>\n")
+			quote = nil
+		}
+	}
+	flush := func() {
+		flushPara()
+		flushList()
+		flushQuote()
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if fence {
+			if strings.HasPrefix(trimmed, "```") {
+				out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+				code, fence = nil, false
+			} else {
+				code = append(code, line)
+			}
+			continue
+		}
+
+		switch {
+		case strings.HasPrefix(trimmed, "```"):
+			flush()
+			fence = true
+		case trimmed == "":
+			flush()
+		case strings.HasPrefix(trimmed, ">"):
+			flushPara()
+			flushList()
+			quote = append(quote, strings.TrimPrefix(strings.TrimPrefix(trimmed, ">"), " "))
+		case heading.MatchString(trimmed):
+			flush()
+			m := heading.FindStringSubmatch(trimmed)
+			level := string(rune('0' + len(m[1])))
+			out.WriteString("<h" + level + ">" + inline(m[2]) + "</h" + level + ">\n")
+		case rule.MatchString(trimmed):
+			flush()
+			out.WriteString("<hr>\n")
+		case bullet.MatchString(trimmed), numbered.MatchString(trimmed):
+			flushPara()
+			flushQuote()
+			kind, item := "ul", bullet.ReplaceAllString(trimmed, "")
+			if numbered.MatchString(trimmed) {
+				kind, item = "ol", numbered.ReplaceAllString(trimmed, "")
+			}
+			if list != kind {
+				flushList()
+				out.WriteString("<" + kind + ">\n")
+				list = kind
+			}
+			out.WriteString("<li>" + inline(item) + "</li>\n")
+		default:
+			flushList()
+			flushQuote()
+			para = append(para, trimmed)
+		}
+	}
+	if fence {
+		out.WriteString("<pre><code>" + html.EscapeString(strings.Join(code, "\n")) + "</code></pre>\n")
+	}
+	flush()
+
+	return out.String()
+}
+
+var (
+	heading  = regexp.MustCompile(`^(#{1,6})\s+(.*?)\s*#*$`)
+	rule     = regexp.MustCompile(`^(\*\s*){3,}$|^(-\s*){3,}$|^(_\s*){3,}$`)
+	bullet   = regexp.MustCompile(`^[-*+]\s+`)
+	numbered = regexp.MustCompile(`^\d+[.)]\s+`)
+
+	codeSpan = regexp.MustCompile("`([^`]+)`")
+	link     = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strong   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emphasis = regexp.MustCompile(`\*([^*]+)\*|\b_([^_]+)_\b`)
+)
+
+// inline escapes s and applies the inline markup. Code spans are cut out
+// first so their contents are left alone.
+func inline(s string) string {
+	spans := []string{}
+	s = codeSpan.ReplaceAllStringFunc(s, func(m string) string {
+		spans = append(spans, "<code>"+html.EscapeString(codeSpan.FindStringSubmatch(m)[1])+"</code>")
+		return "\x00"
+	})
+
+	s = html.EscapeString(s)
+	s = link.ReplaceAllStringFunc(s, func(m string) string {
+		parts := link.FindStringSubmatch(m)
+		href := html.UnescapeString(parts[2])
+		lower := strings.ToLower(href)
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") && !strings.HasPrefix(lower, "mailto:") {
+			return parts[1]
+		}
+		return `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">` + parts[1] + `</a>`
+	})
+	s = strong.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = emphasis.ReplaceAllString(s, "<em>$1$2</em>")
+
+	for _, span := range spans {
+		s = strings.Replace(s, "\x00", span, 1)
+	}
+	return s
+}
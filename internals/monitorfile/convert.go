@@ -0,0 +1,168 @@
+package monitorfile
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+
+	"spectator.main/domain"
+)
+
+var errSource = errors.New("source must be one of " + domain.SourceUptimeKuma + ", " + domain.SourceUptimeRobot)
+
+// secretFields hold credentials; they are reported without their value.
+var secretFields = map[string]bool{
+	"basic_auth_pass":          true,
+	"headers":                  true,
+	"http_password":            true,
+	"custom_http_headers":      true,
+	"oauth_client_secret":      true,
+	"pushToken":                true,
+	"radiusPassword":           true,
+	"radiusSecret":             true,
+	"mqttPassword":             true,
+	"databaseConnectionString": true,
+}
+
+// Convert turns an export of another monitoring service into a document
+// Import accepts. Monitors the export does not group go to a config named
+// configName. Settings Spectator has no place for are listed as unsupported
+// rather than dropped without a word.
+func Convert(data []byte, source string, configName string) (*domain.MonitorDocument, []domain.UnsupportedField, []domain.RowError) {
+	switch source {
+	case domain.SourceUptimeKuma:
+		return fromUptimeKuma(data, configName)
+	case domain.SourceUptimeRobot:
+		return fromUptimeRobot(data, configName)
+	}
+	return nil, nil, []domain.RowError{{Row: "file", Error: errSource.Error()}}
+}
+
+// builder gathers converted monitors into configs, in the order the configs
+// are first seen, along with everything that could not be converted.
+type builder struct {
+	doc         *domain.MonitorDocument
+	byName      map[string]int
+	sites       []builtSite
+	unsupported []domain.UnsupportedField
+}
+
+// builtSite remembers which monitor a site came from and how many
+// notification targets that monitor had.
+type builtSite struct {
+	config  int
+	monitor string
+	targets int
+}
+
+func newBuilder() *builder {
+	return &builder{
+		doc:         &domain.MonitorDocument{Configs: []domain.ConfigDocument{}},
+		byName:      map[string]int{},
+		unsupported: []domain.UnsupportedField{},
+	}
+}
+
+func (b *builder) report(monitor string, field string, value interface{}, reason string) {
+	b.unsupported = append(b.unsupported, domain.UnsupportedField{Monitor: monitor, Field: field, Value: value, Reason: reason})
+}
+
+// add puts the monitor's site in the named config. Spectator notifies per
+// config, so the config gets the targets of all its monitors.
+func (b *builder) add(name string, monitor string, site domain.SiteDocument, targets []domain.NotificationTarget) {
+	i, ok := b.byName[name]
+	if !ok {
+		i = len(b.doc.Configs)
+		b.byName[name] = i
+		b.doc.Configs = append(b.doc.Configs, domain.ConfigDocument{Name: name, Sites: []domain.SiteDocument{}, Row: monitor})
+	}
+	config := &b.doc.Configs[i]
+
+	for _, other := range config.Sites {
+		if other.SiteUrl == site.SiteUrl {
+			b.report(monitor, "url", site.SiteUrl, "another monitor of config "+name+" already checks this url")
+			return
+		}
+	}
+	site.Row = monitor
+	config.Sites = append(config.Sites, site)
+
+	for _, target := range targets {
+		if !hasTarget(config.NotificationTargets, target) {
+			config.NotificationTargets = append(config.NotificationTargets, target)
+		}
+	}
+	b.sites = append(b.sites, builtSite{config: i, monitor: monitor, targets: len(targets)})
+}
+
+func hasTarget(targets []domain.NotificationTarget, target domain.NotificationTarget) bool {
+	for _, t := range targets {
+		if reflect.DeepEqual(t, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// finish reports the monitors that end up notifying more targets than they
+// used to because they share a config with others.
+func (b *builder) finish() (*domain.MonitorDocument, []domain.UnsupportedField, []domain.RowError) {
+	for _, site := range b.sites {
+		config := b.doc.Configs[site.config]
+		if site.targets < len(config.NotificationTargets) {
+			b.report(site.monitor, "notifications", nil, "notifications apply to every site of config "+config.Name+", so this monitor now alerts all of its targets")
+		}
+	}
+	return b.doc, b.unsupported, nil
+}
+
+// leftovers reports the monitor's fields that were neither converted nor
+// skipped, unless they are empty or hold the other service's default.
+func (b *builder) leftovers(monitor string, fields map[string]interface{}, skip map[string]bool, defaults map[string]interface{}, reasons map[string]string) {
+	keys := []string{}
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := fields[key]
+		if skip[key] || empty(value) {
+			continue
+		}
+		if def, ok := defaults[key]; ok && reflect.DeepEqual(value, def) {
+			continue
+		}
+		reason, ok := reasons[key]
+		if !ok {
+			reason = "Spectator has no equivalent setting"
+		}
+		if secretFields[key] {
+			value = nil
+		}
+		b.report(monitor, key, value, reason)
+	}
+}
+
+func empty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func text(fields map[string]interface{}, key string) string {
+	s, _ := fields[key].(string)
+	return s
+}
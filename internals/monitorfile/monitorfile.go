@@ -0,0 +1,284 @@
+package monitorfile
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"spectator.main/domain"
+)
+
+// tagSeparator joins a site's tags in the tags column of a CSV file.
+const tagSeparator = ";"
+
+var csvHeader = []string{"config", "site_url", "tags", "labels"}
+
+var errFormat = errors.New("format must be one of json, yaml, csv")
+
+// ContentType is the media type files of format are served as.
+func ContentType(format string) string {
+	switch format {
+	case domain.FormatYAML:
+		return "application/yaml; charset=utf-8"
+	case domain.FormatCSV:
+		return "text/csv; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// FormatOf picks the format of an upload from its media type, for clients
+// that do not name one.
+func FormatOf(contentType string) string {
+	switch {
+	case strings.Contains(contentType, "yaml"):
+		return domain.FormatYAML
+	case strings.Contains(contentType, "csv"):
+		return domain.FormatCSV
+	}
+	return domain.FormatJSON
+}
+
+// Encode writes doc in format. CSV holds one row per site, with labels
+// written as key=value pairs, and has no room for notification targets or
+// config labels; a config without sites gets a row with an empty site_url.
+func Encode(doc *domain.MonitorDocument, format string) ([]byte, error) {
+	switch format {
+	case domain.FormatJSON:
+		return json.MarshalIndent(doc, "", "  ")
+	case domain.FormatYAML:
+		return encodeYAML(doc)
+	case domain.FormatCSV:
+		return encodeCSV(doc)
+	}
+	return nil, errFormat
+}
+
+// encodeYAML goes through JSON so the document keeps its JSON field names
+// and order.
+func encodeYAML(doc *domain.MonitorDocument) ([]byte, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var node yaml.Node
+	err = yaml.Unmarshal(data, &node)
+	if err != nil {
+		return nil, err
+	}
+	blockStyle(&node)
+	return yaml.Marshal(&node)
+}
+
+// blockStyle drops the flow collections and quoted strings a node parsed
+// from JSON carries; the encoder still quotes strings that need it.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func encodeCSV(doc *domain.MonitorDocument) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write(csvHeader)
+	for _, config := range doc.Configs {
+		if len(config.Sites) == 0 {
+			w.Write([]string{config.Name, "", "", ""})
+		}
+		for _, site := range config.Sites {
+			w.Write([]string{config.Name, site.SiteUrl, strings.Join(site.Tags, tagSeparator), encodeLabels(site.Labels)})
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func encodeLabels(labels map[string]string) string {
+	pairs := []string{}
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, tagSeparator)
+}
+
+// decodeLabels reads key=value pairs; checking the keys and values is left
+// to the import.
+func decodeLabels(cell string) (map[string]string, error) {
+	labels := map[string]string{}
+	for _, pair := range strings.Split(cell, tagSeparator) {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, errors.New("labels must be key=value pairs separated by " + tagSeparator)
+		}
+		labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return labels, nil
+}
+
+// Decode reads a document in format and labels each config and site with
+// where it was found: "line 4" in CSV, "configs[1].sites[0]" otherwise. A
+// file that cannot be read is reported as row errors.
+func Decode(data []byte, format string) (*domain.MonitorDocument, []domain.RowError) {
+	var (
+		doc *domain.MonitorDocument
+		err error
+	)
+
+	switch format {
+	case domain.FormatJSON:
+		doc, err = decodeJSON(data)
+	case domain.FormatYAML:
+		doc, err = decodeYAML(data)
+	case domain.FormatCSV:
+		return decodeCSV(data)
+	default:
+		err = errFormat
+	}
+	if err != nil {
+		return nil, []domain.RowError{{Row: "file", Error: err.Error()}}
+	}
+
+	for i := range doc.Configs {
+		config := &doc.Configs[i]
+		config.Row = "configs[" + strconv.Itoa(i) + "]"
+		for j := range config.Sites {
+			config.Sites[j].Row = config.Row + ".sites[" + strconv.Itoa(j) + "]"
+		}
+	}
+	return doc, nil
+}
+
+// decodeJSON refuses unknown fields so a misspelt one is reported rather
+// than quietly ignored.
+func decodeJSON(data []byte) (*domain.MonitorDocument, error) {
+	var doc domain.MonitorDocument
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	err := dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func decodeYAML(data []byte) (*domain.MonitorDocument, error) {
+	var v interface{}
+	err := yaml.Unmarshal(data, &v)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("yaml: %w", err)
+	}
+	return decodeJSON(data)
+}
+
+// decodeCSV groups rows into configs by name, in the order the names first
+// appear. The header names the columns, so they may come in any order; tags
+// and labels may be left out.
+func decodeCSV(data []byte) (*domain.MonitorDocument, []domain.RowError) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, []domain.RowError{{Row: "line 1", Error: "the file needs a header row: " + strings.Join(csvHeader, ",")}}
+	}
+	column := map[string]int{}
+	for i, name := range header {
+		column[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	errs := []domain.RowError{}
+	for _, name := range header {
+		if !contains(csvHeader, strings.ToLower(strings.TrimSpace(name))) {
+			errs = append(errs, domain.RowError{Row: "line 1", Field: name, Error: "unknown column"})
+		}
+	}
+	for _, name := range csvHeader[:2] {
+		if _, ok := column[name]; !ok {
+			errs = append(errs, domain.RowError{Row: "line 1", Field: name, Error: "missing column"})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	field := func(record []string, name string) string {
+		i, ok := column[name]
+		if !ok || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	doc := &domain.MonitorDocument{Configs: []domain.ConfigDocument{}}
+	byName := map[string]int{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			errs = append(errs, domain.RowError{Row: "line " + strconv.Itoa(perr.StartLine), Error: perr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, append(errs, domain.RowError{Row: "file", Error: err.Error()})
+		}
+		line, _ := r.FieldPos(0)
+		row := "line " + strconv.Itoa(line)
+
+		name := field(record, "config")
+		i, ok := byName[name]
+		if !ok {
+			i = len(doc.Configs)
+			byName[name] = i
+			doc.Configs = append(doc.Configs, domain.ConfigDocument{Name: name, Sites: []domain.SiteDocument{}, Row: row})
+		}
+		siteUrl := field(record, "site_url")
+		if siteUrl == "" {
+			continue
+		}
+		site := domain.SiteDocument{SiteUrl: siteUrl, Row: row}
+		if _, ok := column["labels"]; ok {
+			site.Labels, err = decodeLabels(field(record, "labels"))
+			if err != nil {
+				errs = append(errs, domain.RowError{Row: row, Field: "labels", Error: err.Error()})
+			}
+		}
+		for _, tag := range strings.Split(field(record, "tags"), tagSeparator) {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				site.Tags = append(site.Tags, tag)
+			}
+		}
+		doc.Configs[i].Sites = append(doc.Configs[i].Sites, site)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return doc, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
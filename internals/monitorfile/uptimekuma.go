@@ -0,0 +1,227 @@
+package monitorfile
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"spectator.main/domain"
+)
+
+// kumaBackup is the JSON file Uptime Kuma's Settings > Backup exports.
+// Monitors are kept as plain maps so every field they carry can be checked.
+type kumaBackup struct {
+	Version          string                   `json:"version"`
+	NotificationList []kumaNotification       `json:"notificationList"`
+	MonitorList      []map[string]interface{} `json:"monitorList"`
+}
+
+// kumaNotification holds its settings as a JSON string.
+type kumaNotification struct {
+	ID     int    `json:"id"`
+	Name   string `json:"name"`
+	Config string `json:"config"`
+}
+
+type kumaNotificationConfig struct {
+	Type            string `json:"type"`
+	SmtpTo          string `json:"smtpTo"`
+	SlackWebhookURL string `json:"slackwebhookURL"`
+	WebhookURL      string `json:"webhookURL"`
+}
+
+// kumaHTTPTypes are the monitor types that check a URL.
+var kumaHTTPTypes = map[string]bool{
+	"http":       true,
+	"keyword":    true,
+	"json-query": true,
+}
+
+// kumaSkip are the fields that are converted or only describe Uptime Kuma's
+// own bookkeeping.
+var kumaSkip = map[string]bool{
+	"id":                   true,
+	"type":                 true,
+	"url":                  true,
+	"parent":               true,
+	"pathName":             true,
+	"tags":                 true,
+	"notificationIDList":   true,
+	"active":               true,
+	"weight":               true,
+	"childrenIDs":          true,
+	"includeSensitiveData": true,
+	"forceInactive":        true,
+	"maintenance":          true,
+	"screenshot":           true,
+	"dns_last_result":      true,
+	"created_date":         true,
+	"user_id":              true,
+	"path":                 true,
+}
+
+// kumaDefaults are the values Uptime Kuma gives a new HTTP monitor.
+var kumaDefaults = map[string]interface{}{
+	"method":                   "GET",
+	"maxredirects":             float64(10),
+	"accepted_statuscodes":     []interface{}{"200-299"},
+	"dns_resolve_type":         "A",
+	"dns_resolve_server":       "1.1.1.1",
+	"packetSize":               float64(56),
+	"httpBodyEncoding":         "json",
+	"kafkaProducerSaslOptions": map[string]interface{}{"mechanism": "None"},
+	"conditions":               "[]",
+}
+
+var kumaReasons = map[string]string{
+	"name":                 "sites are known by their url and have no name",
+	"description":          "sites have no description",
+	"interval":             "Spectator probes every site on the same schedule",
+	"retryInterval":        "Spectator probes every site on the same schedule",
+	"resendInterval":       "Spectator sends an alert once per incident",
+	"maxretries":           "Spectator decides a site is down from its probe regions, without retries",
+	"timeout":              "Spectator probes every site with the same timeout",
+	"keyword":              "Spectator checks that a site answers, not what it answers",
+	"invertKeyword":        "Spectator checks that a site answers, not what it answers",
+	"jsonPath":             "Spectator checks that a site answers, not what it answers",
+	"expectedValue":        "Spectator checks that a site answers, not what it answers",
+	"upsideDown":           "Spectator has no inverted checks",
+	"method":               "Spectator probes sites with a plain GET",
+	"body":                 "Spectator probes sites with a plain GET",
+	"headers":              "Spectator probes sites with a plain GET",
+	"httpBodyEncoding":     "Spectator probes sites with a plain GET",
+	"basic_auth_user":      "Spectator probes sites without credentials",
+	"basic_auth_pass":      "Spectator probes sites without credentials",
+	"authMethod":           "Spectator probes sites without credentials",
+	"accepted_statuscodes": "Spectator has no per-site accepted status codes",
+	"maxredirects":         "Spectator has no per-site redirect limit",
+	"ignoreTls":            "Spectator always verifies certificates",
+	"expiryNotification":   "Spectator always tracks certificate expiry",
+	"proxyId":              "Spectator probes sites without a proxy",
+}
+
+// fromUptimeKuma converts a backup file. Groups become configs named after
+// the group; HTTP, keyword and JSON query monitors become sites, with tags
+// written as name or name:value. Email, Slack and webhook notifications
+// become notification targets of the config.
+func fromUptimeKuma(data []byte, configName string) (*domain.MonitorDocument, []domain.UnsupportedField, []domain.RowError) {
+	var backup kumaBackup
+	err := json.Unmarshal(data, &backup)
+	if err != nil {
+		return nil, nil, []domain.RowError{{Row: "file", Error: err.Error()}}
+	}
+	if backup.MonitorList == nil {
+		return nil, nil, []domain.RowError{{Row: "file", Field: "monitorList", Error: "not an Uptime Kuma backup: it has no monitorList"}}
+	}
+
+	type notification struct {
+		name    string
+		kind    string
+		targets []domain.NotificationTarget
+	}
+	notifications := map[string]notification{}
+	for _, n := range backup.NotificationList {
+		var config kumaNotificationConfig
+		err := json.Unmarshal([]byte(n.Config), &config)
+		if err != nil {
+			return nil, nil, []domain.RowError{{Row: "notificationList " + strconv.Quote(n.Name), Field: "config", Error: err.Error()}}
+		}
+		entry := notification{name: n.Name, kind: config.Type}
+		switch config.Type {
+		case "smtp":
+			for _, address := range strings.Split(config.SmtpTo, ",") {
+				if address = strings.TrimSpace(address); address != "" {
+					entry.targets = append(entry.targets, domain.NotificationTarget{Channel: domain.ChannelEmail, Address: address})
+				}
+			}
+		case "slack":
+			entry.targets = []domain.NotificationTarget{{Channel: domain.ChannelSlack, Address: config.SlackWebhookURL}}
+		case "webhook":
+			entry.targets = []domain.NotificationTarget{{Channel: domain.ChannelWebhook, Address: config.WebhookURL}}
+		}
+		notifications[strconv.Itoa(n.ID)] = entry
+	}
+
+	groups := map[float64]string{}
+	for _, monitor := range backup.MonitorList {
+		if text(monitor, "type") == "group" {
+			id, _ := monitor["id"].(float64)
+			groups[id] = text(monitor, "name")
+		}
+	}
+
+	b := newBuilder()
+	for i, monitor := range backup.MonitorList {
+		label := "monitorList[" + strconv.Itoa(i) + "] " + strconv.Quote(text(monitor, "name"))
+		kind := text(monitor, "type")
+		if kind == "group" {
+			continue
+		}
+		if !kumaHTTPTypes[kind] || text(monitor, "url") == "" {
+			b.report(label, "type", kind, "only monitors that check a url can be imported; the monitor was left out")
+			continue
+		}
+
+		name := configName
+		if parent, ok := monitor["parent"].(float64); ok && groups[parent] != "" {
+			name = groups[parent]
+		}
+
+		site := domain.SiteDocument{SiteUrl: text(monitor, "url")}
+		tags, _ := monitor["tags"].([]interface{})
+		for _, raw := range tags {
+			tag, _ := raw.(map[string]interface{})
+			value := text(tag, "name")
+			if v := text(tag, "value"); v != "" {
+				value += ":" + v
+			}
+			if value != "" {
+				site.Tags = append(site.Tags, value)
+			}
+		}
+
+		targets := []domain.NotificationTarget{}
+		ids, _ := monitor["notificationIDList"].(map[string]interface{})
+		keys := []string{}
+		for id, on := range ids {
+			if on == true {
+				keys = append(keys, id)
+			}
+		}
+		sort.Slice(keys, func(i, j int) bool { return lessNumeric(keys[i], keys[j]) })
+		for _, id := range keys {
+			n, ok := notifications[id]
+			switch {
+			case !ok:
+				b.report(label, "notificationIDList", id, "the backup has no notification with this id")
+			case len(n.targets) == 0:
+				b.report(label, "notificationIDList", n.name, "Spectator cannot notify through "+n.kind)
+			default:
+				for _, target := range n.targets {
+					if !hasTarget(targets, target) {
+						targets = append(targets, target)
+					}
+				}
+			}
+		}
+
+		if active, ok := monitor["active"]; ok && (active == false || active == float64(0)) {
+			b.report(label, "active", active, "paused monitors are imported as sites that are probed")
+		}
+		b.leftovers(label, monitor, kumaSkip, kumaDefaults, kumaReasons)
+		b.add(name, label, site, targets)
+	}
+
+	return b.finish()
+}
+
+// lessNumeric orders numeric ids by value.
+func lessNumeric(a string, b string) bool {
+	x, errA := strconv.Atoi(a)
+	y, errB := strconv.Atoi(b)
+	if errA != nil || errB != nil {
+		return a < b
+	}
+	return x < y
+}
@@ -0,0 +1,132 @@
+package monitorfile
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"spectator.main/domain"
+)
+
+// robotExport is the response of UptimeRobot's getMonitors API call, made
+// with alert_contacts=1 so each monitor lists who it alerts.
+type robotExport struct {
+	Monitors []map[string]interface{} `json:"monitors"`
+}
+
+// UptimeRobot monitor types and alert contact types.
+const (
+	robotHTTP    = 1
+	robotKeyword = 2
+
+	robotEmail   = 2
+	robotWebhook = 5
+	robotSlack   = 11
+
+	robotPaused = 0
+)
+
+var robotSkip = map[string]bool{
+	"id":              true,
+	"type":            true,
+	"url":             true,
+	"status":          true,
+	"create_datetime": true,
+	"alert_contacts":  true,
+}
+
+// robotDefaults are the values UptimeRobot gives a new HTTP monitor.
+var robotDefaults = map[string]interface{}{
+	"interval": float64(300),
+	"timeout":  float64(30),
+}
+
+var robotReasons = map[string]string{
+	"friendly_name":        "sites are known by their url and have no name",
+	"interval":             "Spectator probes every site on the same schedule",
+	"timeout":              "Spectator probes every site with the same timeout",
+	"keyword_type":         "Spectator checks that a site answers, not what it answers",
+	"keyword_case_type":    "Spectator checks that a site answers, not what it answers",
+	"keyword_value":        "Spectator checks that a site answers, not what it answers",
+	"http_method":          "Spectator probes sites with a plain GET",
+	"post_type":            "Spectator probes sites with a plain GET",
+	"post_value":           "Spectator probes sites with a plain GET",
+	"post_content_type":    "Spectator probes sites with a plain GET",
+	"custom_http_headers":  "Spectator probes sites with a plain GET",
+	"http_username":        "Spectator probes sites without credentials",
+	"http_password":        "Spectator probes sites without credentials",
+	"http_auth_type":       "Spectator probes sites without credentials",
+	"custom_http_statuses": "Spectator has no per-site accepted status codes",
+	"mwindows":             "maintenance windows are created per config in Spectator",
+}
+
+// fromUptimeRobot converts a getMonitors response. UptimeRobot has no
+// groups, so every monitor goes to the config named configName. HTTP and
+// keyword monitors become sites; email, Slack and webhook alert contacts
+// become notification targets of the config.
+func fromUptimeRobot(data []byte, configName string) (*domain.MonitorDocument, []domain.UnsupportedField, []domain.RowError) {
+	var export robotExport
+	err := json.Unmarshal(data, &export)
+	if err != nil {
+		return nil, nil, []domain.RowError{{Row: "file", Error: err.Error()}}
+	}
+	if export.Monitors == nil {
+		return nil, nil, []domain.RowError{{Row: "file", Field: "monitors", Error: "not an UptimeRobot export: it has no monitors"}}
+	}
+
+	b := newBuilder()
+	for i, monitor := range export.Monitors {
+		label := "monitors[" + strconv.Itoa(i) + "] " + strconv.Quote(text(monitor, "friendly_name"))
+		kind, _ := monitor["type"].(float64)
+		if kind != robotHTTP && kind != robotKeyword || text(monitor, "url") == "" {
+			b.report(label, "type", monitor["type"], "only monitors that check a url can be imported; the monitor was left out")
+			continue
+		}
+
+		targets := []domain.NotificationTarget{}
+		contacts, _ := monitor["alert_contacts"].([]interface{})
+		for _, raw := range contacts {
+			contact, _ := raw.(map[string]interface{})
+			value := text(contact, "value")
+			channel := domain.NotificationChannel("")
+			switch contactType(contact["type"]) {
+			case robotEmail:
+				channel = domain.ChannelEmail
+			case robotWebhook:
+				channel = domain.ChannelWebhook
+			case robotSlack:
+				channel = domain.ChannelSlack
+			default:
+				b.report(label, "alert_contacts", contact["id"], "Spectator cannot notify through UptimeRobot alert contact type "+strconv.Itoa(contactType(contact["type"])))
+				continue
+			}
+			if !empty(contact["threshold"]) || !empty(contact["recurrence"]) {
+				b.report(label, "alert_contacts", value, "Spectator alerts at once and does not repeat alerts")
+			}
+			target := domain.NotificationTarget{Channel: channel, Address: value}
+			if !hasTarget(targets, target) {
+				targets = append(targets, target)
+			}
+		}
+
+		if status, ok := monitor["status"].(float64); ok && status == robotPaused {
+			b.report(label, "status", status, "paused monitors are imported as sites that are probed")
+		}
+		b.leftovers(label, monitor, robotSkip, robotDefaults, robotReasons)
+		b.add(configName, label, domain.SiteDocument{SiteUrl: text(monitor, "url")}, targets)
+	}
+
+	return b.finish()
+}
+
+// contactType reads an alert contact type, which the API returns as either
+// a number or a string.
+func contactType(v interface{}) int {
+	switch t := v.(type) {
+	case float64:
+		return int(t)
+	case string:
+		n, _ := strconv.Atoi(t)
+		return n
+	}
+	return 0
+}
@@ -25,6 +25,16 @@ func NewRabbitMQPublisher(conn *amqp.Connection, queueName string) (*rabbitMQPub
 }
 
 func (p *rabbitMQPublisher) Publish(message []byte) error {
+	return p.PublishMessage(Message{Body: message, ContentType: "text/plain"})
+}
+
+// PublishMessage publishes a message with its properties and headers.
+func (p *rabbitMQPublisher) PublishMessage(message Message) error {
+	deliveryMode := amqp.Transient
+	if message.Persistent {
+		deliveryMode = amqp.Persistent
+	}
+
 	// Declare the queue (ensure it exists)
 	_, err := p.channel.QueueDeclare(
 		p.queueName, // queue name
@@ -44,8 +54,13 @@ func (p *rabbitMQPublisher) Publish(message []byte) error {
 		false,       // mandatory
 		false,       // immediate
 		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        message,
+			ContentType:  message.ContentType,
+			Type:         message.Type,
+			MessageId:    message.MessageID,
+			Timestamp:    message.Timestamp,
+			Headers:      amqp.Table(message.Headers),
+			DeliveryMode: deliveryMode,
+			Body:         message.Body,
 		},
 	)
 
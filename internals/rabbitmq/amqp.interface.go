@@ -1,5 +1,22 @@
 package rabbitmq
 
+import (
+	"time"
+)
+
 type MQPublisher interface {
 	Publish(message []byte) error
+	PublishMessage(message Message) error
+}
+
+// Message is a body together with the properties consumers route and
+// decode it by. Persistent messages survive a broker restart.
+type Message struct {
+	Body        []byte
+	ContentType string
+	Type        string
+	MessageID   string
+	Timestamp   time.Time
+	Headers     map[string]interface{}
+	Persistent  bool
 }
@@ -0,0 +1,204 @@
+package render
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	htmltemplate "html/template"
+	"io"
+	texttemplate "text/template"
+	"text/template/parse"
+	"time"
+
+	"spectator.main/domain"
+)
+
+const (
+	MaxTemplateSize = 16 * 1024
+	MaxOutputSize   = 64 * 1024
+	Timeout         = 2 * time.Second
+)
+
+var (
+	ErrTemplateTooLarge = errors.New("template exceeds the maximum size")
+	ErrOutputTooLarge   = errors.New("rendered output exceeds the maximum size")
+	ErrTimeout          = errors.New("template execution timed out")
+	ErrTemplateCall     = errors.New("templates may not define or call other templates")
+)
+
+// checkpoint is the function guard puts at the top of every range body.
+const checkpoint = "spectatorCheckpoint"
+
+type executor interface {
+	Execute(w io.Writer, data interface{}) error
+}
+
+// limitedWriter fails the write that would push the output past max, or
+// any write once ctx is done, which aborts template execution instead of
+// buffering unbounded output.
+type limitedWriter struct {
+	ctx context.Context
+	buf bytes.Buffer
+	max int
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if w.ctx.Err() != nil {
+		return 0, ErrTimeout
+	}
+	if w.buf.Len()+len(p) > w.max {
+		return 0, ErrOutputTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+// funcs returns the checkpoint function, which fails once ctx is done.
+func funcs(ctx context.Context) map[string]interface{} {
+	return map[string]interface{}{
+		checkpoint: func() (bool, error) {
+			if ctx.Err() != nil {
+				return false, ErrTimeout
+			}
+			return false, nil
+		},
+	}
+}
+
+// guard refuses template calls, which can recurse, and makes every range
+// body start with {{if checkpoint}}{{end}}. A loop that writes nothing never
+// reaches limitedWriter, so without it a loop could outlive the deadline.
+func guard(node parse.Node, fns map[string]interface{}) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			err := guard(child, fns)
+			if err != nil {
+				return err
+			}
+		}
+	case *parse.TemplateNode:
+		return ErrTemplateCall
+	case *parse.IfNode:
+		return guardBranch(&n.BranchNode, fns)
+	case *parse.WithNode:
+		return guardBranch(&n.BranchNode, fns)
+	case *parse.RangeNode:
+		err := guardBranch(&n.BranchNode, fns)
+		if err != nil {
+			return err
+		}
+		// Each range gets its own node, as html/template rewrites the
+		// trees it escapes.
+		tree, err := parse.New(checkpoint).Parse("{{if "+checkpoint+"}}{{end}}", "", "", map[string]*parse.Tree{}, fns)
+		if err != nil {
+			return err
+		}
+		n.List.Nodes = append(tree.Root.Nodes, n.List.Nodes...)
+	}
+	return nil
+}
+
+func guardBranch(n *parse.BranchNode, fns map[string]interface{}) error {
+	err := guard(n.List, fns)
+	if err != nil {
+		return err
+	}
+	return guard(n.ElseList, fns)
+}
+
+// Text parses and executes a user-supplied text/template with size limits
+// and a deadline.
+func Text(ctx context.Context, src string, data interface{}) (string, error) {
+	if len(src) > MaxTemplateSize {
+		return "", ErrTemplateTooLarge
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	fns := funcs(ctx)
+	t, err := texttemplate.New("text").Option("missingkey=error").Funcs(fns).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	if len(t.Templates()) > 1 {
+		return "", ErrTemplateCall
+	}
+	err = guard(t.Root, fns)
+	if err != nil {
+		return "", err
+	}
+	return execute(ctx, t, data)
+}
+
+// HTML is Text for html/template, escaping data for use in email bodies.
+func HTML(ctx context.Context, src string, data interface{}) (string, error) {
+	if len(src) > MaxTemplateSize {
+		return "", ErrTemplateTooLarge
+	}
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	fns := funcs(ctx)
+	t, err := htmltemplate.New("html").Option("missingkey=error").Funcs(fns).Parse(src)
+	if err != nil {
+		return "", err
+	}
+	if len(t.Templates()) > 1 {
+		return "", ErrTemplateCall
+	}
+	err = guard(t.Tree.Root, fns)
+	if err != nil {
+		return "", err
+	}
+	return execute(ctx, t, data)
+}
+
+// execute runs t in the calling goroutine: the writer and the range
+// checkpoints stop it at the deadline, so nothing is left running after a
+// timeout.
+func execute(ctx context.Context, t executor, data interface{}) (res string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New("template execution panicked")
+		}
+	}()
+
+	w := &limitedWriter{ctx: ctx, max: MaxOutputSize}
+	err = t.Execute(w, data)
+	switch {
+	case err == nil:
+		return w.buf.String(), nil
+	case ctx.Err() != nil || errors.Is(err, ErrTimeout):
+		return "", ErrTimeout
+	case errors.Is(err, ErrOutputTooLarge):
+		return "", ErrOutputTooLarge
+	}
+	return "", err
+}
+
+// Message renders an alert for the given channel: email bodies go through
+// html/template, every other body and the email subject through text/template.
+func Message(ctx context.Context, channel domain.NotificationChannel, subject string, body string, data domain.AlertTemplateData) (*domain.RenderedMessage, error) {
+	var (
+		message domain.RenderedMessage
+		err     error
+	)
+
+	if channel == domain.ChannelEmail {
+		message.Subject, err = Text(ctx, subject, data)
+		if err != nil {
+			return nil, err
+		}
+		message.Body, err = HTML(ctx, body, data)
+	} else {
+		message.Body, err = Text(ctx, body, data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &message, nil
+}
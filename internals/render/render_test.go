@@ -0,0 +1,116 @@
+package render_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"spectator.main/domain"
+	"spectator.main/internals/render"
+)
+
+// loop nests ranges over the data deep enough that nothing could finish
+// them, and writes nothing, so only the range checkpoint can stop it.
+const loop = "{{range .}}{{range $}}{{range $}}{{end}}{{end}}{{end}}"
+
+func items(n int) []int {
+	return make([]int, n)
+}
+
+func TestRendersWithinLimits(t *testing.T) {
+	data := domain.AlertTemplateData{}
+	data.Config.Name = "<shop>"
+
+	text, err := render.Text(context.Background(), "{{.Config.Name}} is down", data)
+	if err != nil || text != "<shop> is down" {
+		t.Errorf("Text = %q, %v", text, err)
+	}
+	html, err := render.HTML(context.Background(), "<b>{{.Config.Name}}</b>", data)
+	if err != nil || html != "<b>&lt;shop&gt;</b>" {
+		t.Errorf("HTML = %q, %v", html, err)
+	}
+}
+
+func TestEndlessRangeTimesOut(t *testing.T) {
+	start := time.Now()
+	_, err := render.Text(context.Background(), loop, items(10000))
+	if !errors.Is(err, render.ErrTimeout) {
+		t.Fatalf("Text = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > render.Timeout+time.Second {
+		t.Errorf("Text stopped after %v, want about %v", elapsed, render.Timeout)
+	}
+}
+
+func TestEndlessRangeStopsAtCallerDeadline(t *testing.T) {
+	for name, fn := range map[string]func(context.Context, string, interface{}) (string, error){
+		"text": render.Text,
+		"html": render.HTML,
+	} {
+		t.Run(name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := fn(ctx, loop, items(10000))
+			if !errors.Is(err, render.ErrTimeout) {
+				t.Fatalf("got %v, want ErrTimeout", err)
+			}
+			if elapsed := time.Since(start); elapsed > time.Second {
+				t.Errorf("stopped after %v, want about 100ms", elapsed)
+			}
+		})
+	}
+}
+
+func TestOutputTooLarge(t *testing.T) {
+	src := "{{range .}}" + strings.Repeat("x", 100) + "{{end}}"
+	data := items(render.MaxOutputSize / 100 * 2)
+
+	_, err := render.Text(context.Background(), src, data)
+	if !errors.Is(err, render.ErrOutputTooLarge) {
+		t.Errorf("Text = %v, want ErrOutputTooLarge", err)
+	}
+	_, err = render.HTML(context.Background(), src, data)
+	if !errors.Is(err, render.ErrOutputTooLarge) {
+		t.Errorf("HTML = %v, want ErrOutputTooLarge", err)
+	}
+}
+
+func TestTemplateTooLarge(t *testing.T) {
+	src := strings.Repeat("x", render.MaxTemplateSize+1)
+
+	_, err := render.Text(context.Background(), src, nil)
+	if !errors.Is(err, render.ErrTemplateTooLarge) {
+		t.Errorf("Text = %v, want ErrTemplateTooLarge", err)
+	}
+	_, err = render.HTML(context.Background(), src, nil)
+	if !errors.Is(err, render.ErrTemplateTooLarge) {
+		t.Errorf("HTML = %v, want ErrTemplateTooLarge", err)
+	}
+}
+
+func TestTemplateCallsAreRefused(t *testing.T) {
+	tests := map[string]string{
+		"define and call": `{{define "x"}}{{template "x" .}}{{end}}{{template "x" .}}`,
+		"block":           `{{block "x" .}}{{end}}`,
+		"call":            `{{template "x"}}`,
+		"call in range":   `{{range .}}{{template "x"}}{{end}}`,
+		"call in else":    `{{if .}}{{else}}{{with .}}{{template "x"}}{{end}}{{end}}`,
+	}
+
+	for name, src := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := render.Text(context.Background(), src, items(1))
+			if !errors.Is(err, render.ErrTemplateCall) {
+				t.Errorf("Text = %v, want ErrTemplateCall", err)
+			}
+			_, err = render.HTML(context.Background(), src, items(1))
+			if !errors.Is(err, render.ErrTemplateCall) {
+				t.Errorf("HTML = %v, want ErrTemplateCall", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package uptime
+
+import (
+	"sort"
+	"time"
+
+	"spectator.main/domain"
+)
+
+// Downtime sums how much of [from, to) the given incidents cover, counting
+// overlapping incidents once. Open incidents are treated as lasting until to.
+func Downtime(incidents []domain.Incident, from time.Time, to time.Time) time.Duration {
+	type span struct{ start, end time.Time }
+
+	spans := []span{}
+	for _, incident := range incidents {
+		start := incident.StartedAt
+		end := to
+		if incident.ResolvedAt != nil {
+			end = *incident.ResolvedAt
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			spans = append(spans, span{start, end})
+		}
+	}
+
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+
+	var (
+		total time.Duration
+		cur   *span
+	)
+	for i := range spans {
+		if cur != nil && !spans[i].start.After(cur.end) {
+			if spans[i].end.After(cur.end) {
+				cur.end = spans[i].end
+			}
+			continue
+		}
+		if cur != nil {
+			total += cur.end.Sub(cur.start)
+		}
+		cur = &spans[i]
+	}
+	if cur != nil {
+		total += cur.end.Sub(cur.start)
+	}
+
+	return total
+}
+
+// Percent is the share of [from, to) not covered by the incidents, 0-100.
+func Percent(incidents []domain.Incident, from time.Time, to time.Time) float64 {
+	period := to.Sub(from)
+	if period <= 0 {
+		return 100
+	}
+	return 100 * (1 - float64(Downtime(incidents, from, to))/float64(period))
+}
+
+// ForSite filters incidents down to one site of one config.
+func ForSite(incidents []domain.Incident, configID string, siteUrl string) []domain.Incident {
+	res := []domain.Incident{}
+	for _, incident := range incidents {
+		if incident.ConfigID.Hex() == configID && incident.SiteUrl == siteUrl {
+			res = append(res, incident)
+		}
+	}
+	return res
+}
+
+// Daily returns one entry per UTC day for the last days days up to now, each
+// averaging the uptime of the given sites (one incident list per site).
+func Daily(sites [][]domain.Incident, days int, now time.Time) []domain.UptimeDay {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	res := make([]domain.UptimeDay, 0, days)
+
+	for i := days - 1; i >= 0; i-- {
+		from := today.AddDate(0, 0, -i)
+		to := from.AddDate(0, 0, 1)
+		if to.After(now) {
+			to = now
+		}
+
+		day := domain.UptimeDay{Date: from, Uptime: 100}
+		if len(sites) > 0 {
+			var (
+				percent  float64
+				downtime time.Duration
+			)
+			for _, incidents := range sites {
+				percent += Percent(incidents, from, to)
+				downtime += Downtime(incidents, from, to)
+			}
+			day.Uptime = percent / float64(len(sites))
+			day.Downtime = downtime / time.Duration(len(sites))
+		}
+		res = append(res, day)
+	}
+
+	return res
+}
@@ -1,6 +1,11 @@
 package tokenutil
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"time"
 
@@ -75,3 +80,174 @@ func ExtractIDFromToken(requestToken string, secret string) (string, error) {
 	return claims["id"].(string), nil
 
 }
+
+const incidentActionPurpose = "incident-action"
+
+func CreateIncidentActionToken(claims *domain.IncidentActionClaims, secret string, expiry int) (string, error) {
+	key, err := linkKey(secret, incidentActionPurpose)
+	if err != nil {
+		return "", err
+	}
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expiry)))
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return t, err
+}
+
+func ParseIncidentActionToken(requestToken string, secret string) (*domain.IncidentActionClaims, error) {
+	key, err := linkKey(secret, incidentActionPurpose)
+	if err != nil {
+		return nil, err
+	}
+	claims := &domain.IncidentActionClaims{}
+	_, err = jwt.ParseWithClaims(requestToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+const subscriptionPurpose = "subscription"
+
+// CreateSubscriptionToken signs a subscriber link. An expiry of 0 makes the
+// link valid for as long as the subscription exists.
+func CreateSubscriptionToken(claims *domain.SubscriptionClaims, secret string, expiry int) (string, error) {
+	key, err := linkKey(secret, subscriptionPurpose)
+	if err != nil {
+		return "", err
+	}
+	if expiry > 0 {
+		claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expiry)))
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return t, err
+}
+
+func ParseSubscriptionToken(requestToken string, secret string) (*domain.SubscriptionClaims, error) {
+	key, err := linkKey(secret, subscriptionPurpose)
+	if err != nil {
+		return nil, err
+	}
+	claims := &domain.SubscriptionClaims{}
+	_, err = jwt.ParseWithClaims(requestToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ErrNoLinkSecret is returned when a link token is signed or parsed without
+// a link secret. An empty key would let anyone mint valid tokens.
+var ErrNoLinkSecret = errors.New("no link secret is configured")
+
+// linkKey derives the key of one kind of link token from the link secret,
+// so a token issued for one purpose never verifies as another.
+func linkKey(secret string, purpose string) ([]byte, error) {
+	if secret == "" {
+		return nil, ErrNoLinkSecret
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil), nil
+}
+
+// viewerSubject marks viewer session tokens so no other token signed with the
+// same secret is accepted as one.
+const viewerSubject = "status-page-viewer"
+
+func CreateViewerSessionToken(claims *domain.StatusPageViewerClaims, secret string, expiry int) (string, error) {
+	key, err := linkKey(secret, viewerSubject)
+	if err != nil {
+		return "", err
+	}
+	claims.Subject = viewerSubject
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expiry)))
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return t, err
+}
+
+func ParseViewerSessionToken(requestToken string, secret string) (*domain.StatusPageViewerClaims, error) {
+	key, err := linkKey(secret, viewerSubject)
+	if err != nil {
+		return nil, err
+	}
+	claims := &domain.StatusPageViewerClaims{}
+	_, err = jwt.ParseWithClaims(requestToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if claims.Subject != viewerSubject {
+		return nil, fmt.Errorf("not a viewer session token")
+	}
+	return claims, nil
+}
+
+const emailVerificationPurpose = "email-verification"
+
+func CreateEmailVerificationToken(claims *domain.EmailVerificationClaims, secret string, expiry int) (string, error) {
+	key, err := linkKey(secret, emailVerificationPurpose)
+	if err != nil {
+		return "", err
+	}
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Hour * time.Duration(expiry)))
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	t, err := token.SignedString(key)
+	if err != nil {
+		return "", err
+	}
+	return t, err
+}
+
+func ParseEmailVerificationToken(requestToken string, secret string) (*domain.EmailVerificationClaims, error) {
+	key, err := linkKey(secret, emailVerificationPurpose)
+	if err != nil {
+		return nil, err
+	}
+	claims := &domain.EmailVerificationClaims{}
+	_, err = jwt.ParseWithClaims(requestToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// CreateRandomToken returns a URL-safe random token for capability links such
+// as badge URLs, where knowing the token is the only authorization.
+func CreateRandomToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,176 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "maintenance"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.MaintenanceRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+func (m *mongoRepository) InsertOne(ctx context.Context, maintenance *domain.Maintenance) (*domain.Maintenance, error) {
+	var (
+		err error
+	)
+
+	_, err = m.Collection.InsertOne(ctx, maintenance)
+	if err != nil {
+		return maintenance, err
+	}
+
+	return maintenance, nil
+}
+
+func (m *mongoRepository) FindOne(ctx context.Context, id string) (*domain.Maintenance, error) {
+	var (
+		maintenance domain.Maintenance
+		err         error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &maintenance, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&maintenance)
+	if err != nil {
+		return &maintenance, err
+	}
+
+	return &maintenance, nil
+}
+
+func (m *mongoRepository) GetByUserID(ctx context.Context, userID string) ([]domain.Maintenance, error) {
+	var (
+		maintenances []domain.Maintenance
+		err          error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return maintenances, err
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "scheduled_for", Value: -1}})
+
+	cursor, err := m.Collection.Find(ctx, bson.M{"user_id": idHex}, opts)
+	if err != nil {
+		return maintenances, err
+	}
+	if cursor == nil {
+		return maintenances, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &maintenances)
+	if err != nil {
+		return maintenances, err
+	}
+
+	return maintenances, nil
+}
+
+func (m *mongoRepository) UpdateOne(ctx context.Context, maintenance *domain.Maintenance, id string) (*domain.Maintenance, error) {
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return maintenance, err
+	}
+
+	filter := bson.M{"_id": idHex}
+	update := bson.M{"$set": bson.M{
+		"title":           maintenance.Title,
+		"description":     maintenance.Description,
+		"sites":           maintenance.Sites,
+		"scheduled_for":   maintenance.ScheduledFor,
+		"scheduled_until": maintenance.ScheduledUntil,
+		"time_zone":       maintenance.TimeZone,
+		"recurrence":      maintenance.Recurrence,
+		"updated_at":      time.Now(),
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return maintenance, err
+	}
+	if result.MatchedCount == 0 {
+		return maintenance, errors.New("no maintenance found with the given id")
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(maintenance)
+	if err != nil {
+		return maintenance, err
+	}
+
+	return maintenance, nil
+}
+
+func (m *mongoRepository) DeleteOne(ctx context.Context, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	count, err := m.Collection.DeleteOne(ctx, bson.M{"_id": idHex})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("no maintenance found with the given id")
+	}
+
+	return nil
+}
+
+// RemoveConfig takes the sites of a deleted config off every maintenance.
+func (m *mongoRepository) RemoveConfig(ctx context.Context, configID primitive.ObjectID) error {
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"sites.config_id": configID},
+		bson.M{"$pull": bson.M{"sites": bson.M{"config_id": configID}}},
+	)
+	return err
+}
+
+// RenameSite follows a site to its new URL in every maintenance.
+func (m *mongoRepository) RenameSite(ctx context.Context, configID primitive.ObjectID, oldUrl string, newUrl string) error {
+	site := bson.M{"config_id": configID, "site_url": oldUrl}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"s.config_id": configID, "s.site_url": oldUrl}},
+	})
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"sites": bson.M{"$elemMatch": site}},
+		bson.M{"$set": bson.M{"sites.$[s].site_url": newUrl}},
+		opts,
+	)
+	return err
+}
+
+// RemoveSite takes a deleted site off every maintenance.
+func (m *mongoRepository) RemoveSite(ctx context.Context, configID primitive.ObjectID, siteUrl string) error {
+	site := bson.M{"config_id": configID, "site_url": siteUrl}
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"sites": bson.M{"$elemMatch": site}},
+		bson.M{"$pull": bson.M{"sites": site}},
+	)
+	return err
+}
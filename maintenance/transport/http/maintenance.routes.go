@@ -0,0 +1,122 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
+)
+
+type MaintenanceHandler struct {
+	MaintenanceUsecase domain.MaintenanceUsecase
+	config             *bootstrap.Config
+}
+
+func NewMaintenanceHandler(cfg *bootstrap.Config, r *gin.RouterGroup, mu domain.MaintenanceUsecase) {
+	handler := &MaintenanceHandler{
+		MaintenanceUsecase: mu,
+		config:             cfg,
+	}
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.POST("/maintenance", handler.CreateMaintenance)
+	protected.GET("/maintenances", handler.GetMaintenancesByUserID)
+	protected.GET("/maintenance/:maintenance_id", handler.GetMaintenance)
+	protected.PUT("/maintenance/:maintenance_id", handler.UpdateMaintenance)
+	protected.DELETE("/maintenance/:maintenance_id", handler.DeleteMaintenance)
+}
+
+// errorStatus maps maintenance windows the caller may not see to 404 and
+// any other failure to fallback.
+func errorStatus(err error, fallback int) int {
+	if errors.Is(err, domain.ErrMaintenanceNotFound) {
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *MaintenanceHandler) CreateMaintenance(c *gin.Context) {
+	var maintenance domain.Maintenance
+	if err := c.ShouldBindJSON(&maintenance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&maintenance); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+	maintenance.UserID = userID
+	res, err := h.MaintenanceUsecase.InsertOne(c, &maintenance)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *MaintenanceHandler) GetMaintenancesByUserID(c *gin.Context) {
+	maintenances, err := h.MaintenanceUsecase.GetByUserID(c, c.GetString("x-user-id"), c.Query("selector"))
+	if errors.Is(err, domain.ErrInvalidLabel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, maintenances)
+}
+
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	maintenance, err := h.MaintenanceUsecase.FindOne(c, c.Param("maintenance_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, maintenance)
+}
+
+func (h *MaintenanceHandler) UpdateMaintenance(c *gin.Context) {
+	var maintenance domain.Maintenance
+	if err := c.ShouldBindJSON(&maintenance); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&maintenance); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.MaintenanceUsecase.UpdateOne(c, &maintenance, c.Param("maintenance_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusUnprocessableEntity), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *MaintenanceHandler) DeleteMaintenance(c *gin.Context) {
+	err := h.MaintenanceUsecase.DeleteOne(c, c.Param("maintenance_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Maintenance deleted successfully"})
+}
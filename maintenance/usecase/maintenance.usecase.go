@@ -0,0 +1,217 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/labels"
+)
+
+type maintenanceUsecase struct {
+	maintenanceRepo domain.MaintenanceRepository
+	configRepo      domain.ConfigRepository
+	userRepo        domain.UserRepository
+	contextTimeout  time.Duration
+}
+
+func NewMaintenanceUsecase(m domain.MaintenanceRepository, c domain.ConfigRepository, u domain.UserRepository, to time.Duration) domain.MaintenanceUsecase {
+	return &maintenanceUsecase{
+		maintenanceRepo: m,
+		configRepo:      c,
+		userRepo:        u,
+		contextTimeout:  to,
+	}
+}
+
+func (m *maintenanceUsecase) InsertOne(c context.Context, maintenance *domain.Maintenance) (*domain.Maintenance, error) {
+
+	ctx, cancel := context.WithTimeout(c, m.contextTimeout)
+	defer cancel()
+
+	user, err := m.userRepo.FindOne(ctx, maintenance.UserID.Hex())
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if maintenance.TimeZone == "" {
+		maintenance.TimeZone = user.TimeZone
+	}
+
+	err = validateSchedule(maintenance)
+	if err != nil {
+		return nil, err
+	}
+	err = m.validateSites(ctx, maintenance)
+	if err != nil {
+		return nil, err
+	}
+
+	maintenance.ID = primitive.NewObjectID()
+	maintenance.CreatedAt = time.Now()
+	maintenance.UpdatedAt = time.Now()
+
+	res, err := m.maintenanceRepo.InsertOne(ctx, maintenance)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (m *maintenanceUsecase) FindOne(c context.Context, id string, userID string) (*domain.Maintenance, error) {
+
+	ctx, cancel := context.WithTimeout(c, m.contextTimeout)
+	defer cancel()
+
+	return m.owned(ctx, id, userID)
+}
+
+// owned loads a maintenance window on behalf of userID. Windows of other
+// users are reported as missing.
+func (m *maintenanceUsecase) owned(ctx context.Context, id string, userID string) (*domain.Maintenance, error) {
+	maintenance, err := m.maintenanceRepo.FindOne(ctx, id)
+	if err != nil || maintenance.UserID.Hex() != userID {
+		return nil, domain.ErrMaintenanceNotFound
+	}
+	return maintenance, nil
+}
+
+// GetByUserID lists the user's maintenance, with a selector only the
+// windows covering a site whose labels meet it.
+func (m *maintenanceUsecase) GetByUserID(c context.Context, userID string, selector string) ([]domain.Maintenance, error) {
+
+	ctx, cancel := context.WithTimeout(c, m.contextTimeout)
+	defer cancel()
+
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := m.maintenanceRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return res, err
+	}
+	if len(sel) == 0 {
+		return res, nil
+	}
+
+	configs, err := m.configRepo.GetAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sites := sel.Sites(configs)
+
+	picked := []domain.Maintenance{}
+	for _, maintenance := range res {
+		for _, site := range sites {
+			if maintenance.Affects(site.ConfigID, site.SiteUrl) {
+				picked = append(picked, maintenance)
+				break
+			}
+		}
+	}
+
+	return picked, nil
+}
+
+func (m *maintenanceUsecase) UpdateOne(c context.Context, maintenance *domain.Maintenance, id string, userID string) (*domain.Maintenance, error) {
+
+	ctx, cancel := context.WithTimeout(c, m.contextTimeout)
+	defer cancel()
+
+	existing, err := m.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	maintenance.UserID = existing.UserID
+	if maintenance.TimeZone == "" {
+		maintenance.TimeZone = existing.TimeZone
+	}
+
+	err = validateSchedule(maintenance)
+	if err != nil {
+		return nil, err
+	}
+	err = m.validateSites(ctx, maintenance)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := m.maintenanceRepo.UpdateOne(ctx, maintenance, id)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (m *maintenanceUsecase) DeleteOne(c context.Context, id string, userID string) error {
+
+	ctx, cancel := context.WithTimeout(c, m.contextTimeout)
+	defer cancel()
+
+	_, err := m.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return m.maintenanceRepo.DeleteOne(ctx, id)
+}
+
+// validateSchedule checks the time zone and recurrence. Windows may not
+// overlap their own repeats.
+func validateSchedule(maintenance *domain.Maintenance) error {
+	if maintenance.TimeZone == "" {
+		maintenance.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(maintenance.TimeZone); err != nil {
+		return errors.New("unknown time zone " + maintenance.TimeZone)
+	}
+
+	r := maintenance.Recurrence
+	if r == nil {
+		return nil
+	}
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if r.Until != nil && r.Until.Before(maintenance.ScheduledFor) {
+		return errors.New("recurrence ends before the first window")
+	}
+	period := 24 * time.Hour * time.Duration(r.Interval)
+	switch r.Frequency {
+	case domain.RecurWeekly:
+		period *= 7
+	case domain.RecurMonthly:
+		period *= 28
+	}
+	if maintenance.ScheduledUntil.Sub(maintenance.ScheduledFor) >= period {
+		return errors.New("maintenance window is longer than its recurrence interval")
+	}
+	return nil
+}
+
+// validateSites makes sure every affected site belongs to the owner.
+func (m *maintenanceUsecase) validateSites(ctx context.Context, maintenance *domain.Maintenance) error {
+	for _, site := range maintenance.Sites {
+		config, err := m.configRepo.FindOne(ctx, site.ConfigID.Hex())
+		if err != nil || config.UserID != maintenance.UserID {
+			return errors.New("config " + site.ConfigID.Hex() + " not found")
+		}
+
+		found := false
+		for _, siteConfig := range config.SiteConfig {
+			if siteConfig.SiteUrl == site.SiteUrl {
+				found = true
+			}
+		}
+		if !found {
+			return errors.New("site " + site.SiteUrl + " not found in config " + config.Name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "oncall_schedule"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.OnCallRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+func (m *mongoRepository) InsertOne(ctx context.Context, schedule *domain.OnCallSchedule) (*domain.OnCallSchedule, error) {
+	var (
+		err error
+	)
+
+	_, err = m.Collection.InsertOne(ctx, schedule)
+	if err != nil {
+		return schedule, err
+	}
+
+	return schedule, nil
+}
+
+func (m *mongoRepository) FindOne(ctx context.Context, id string) (*domain.OnCallSchedule, error) {
+	var (
+		schedule domain.OnCallSchedule
+		err      error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &schedule, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&schedule)
+	if err != nil {
+		return &schedule, err
+	}
+
+	return &schedule, nil
+}
+
+func (m *mongoRepository) GetByUserID(ctx context.Context, userID string) ([]domain.OnCallSchedule, error) {
+	var (
+		schedules []domain.OnCallSchedule
+		err       error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return schedules, err
+	}
+
+	cursor, err := m.Collection.Find(ctx, bson.M{"user_id": idHex})
+	if err != nil {
+		return schedules, err
+	}
+	if cursor == nil {
+		return schedules, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &schedules)
+	if err != nil {
+		return schedules, err
+	}
+
+	return schedules, nil
+}
+
+func (m *mongoRepository) UpdateOne(ctx context.Context, schedule *domain.OnCallSchedule, id string) (*domain.OnCallSchedule, error) {
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return schedule, err
+	}
+
+	filter := bson.M{"_id": idHex}
+	update := bson.M{"$set": bson.M{
+		"name":       schedule.Name,
+		"time_zone":  schedule.TimeZone,
+		"rotations":  schedule.Rotations,
+		"updated_at": time.Now(),
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return schedule, err
+	}
+	if result.MatchedCount == 0 {
+		return schedule, errors.New("no schedule found with the given id")
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(schedule)
+	if err != nil {
+		return schedule, err
+	}
+
+	return schedule, nil
+}
+
+func (m *mongoRepository) DeleteOne(ctx context.Context, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	count, err := m.Collection.DeleteOne(ctx, bson.M{"_id": idHex})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("no schedule found with the given id")
+	}
+
+	return nil
+}
+
+func (m *mongoRepository) AddOverride(ctx context.Context, override *domain.OnCallOverride, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": idHex}
+	update := bson.M{
+		"$push": bson.M{"overrides": override},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no schedule found with the given id")
+	}
+
+	return nil
+}
+
+func (m *mongoRepository) RemoveOverride(ctx context.Context, overrideID string, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	overrideHex, err := primitive.ObjectIDFromHex(overrideID)
+	if err != nil {
+		return err
+	}
+
+	filter := bson.M{"_id": idHex}
+	update := bson.M{
+		"$pull": bson.M{"overrides": bson.M{"_id": overrideHex}},
+		"$set":  bson.M{"updated_at": time.Now()},
+	}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+	if result.ModifiedCount == 0 {
+		return errors.New("no override found with the given id")
+	}
+
+	return nil
+}
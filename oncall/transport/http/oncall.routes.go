@@ -0,0 +1,168 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
+)
+
+type OnCallHandler struct {
+	OnCallUsecase domain.OnCallUsecase
+	config        *bootstrap.Config
+}
+
+func NewOnCallHandler(cfg *bootstrap.Config, r *gin.RouterGroup, ou domain.OnCallUsecase) {
+	handler := &OnCallHandler{
+		OnCallUsecase: ou,
+		config:        cfg,
+	}
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.POST("/oncall", handler.CreateSchedule)
+	protected.GET("/oncalls", handler.GetSchedulesByUserID)
+	protected.GET("/oncall/:schedule_id", handler.GetSchedule)
+	protected.PUT("/oncall/:schedule_id", handler.UpdateSchedule)
+	protected.DELETE("/oncall/:schedule_id", handler.DeleteSchedule)
+	protected.POST("/oncall/:schedule_id/override", handler.AddOverride)
+	protected.DELETE("/oncall/:schedule_id/override/:override_id", handler.RemoveOverride)
+	protected.GET("/oncall/:schedule_id/now", handler.WhoIsOnCall)
+}
+
+// errorStatus maps schedules the caller may not see to 404 and any other
+// failure to fallback.
+func errorStatus(err error, fallback int) int {
+	if errors.Is(err, domain.ErrScheduleNotFound) {
+		return http.StatusNotFound
+	}
+	return fallback
+}
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *OnCallHandler) CreateSchedule(c *gin.Context) {
+	var schedule domain.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&schedule); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+	schedule.UserID = userID
+	res, err := h.OnCallUsecase.InsertOne(c, &schedule)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *OnCallHandler) GetSchedulesByUserID(c *gin.Context) {
+	schedules, err := h.OnCallUsecase.GetByUserID(c, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+func (h *OnCallHandler) GetSchedule(c *gin.Context) {
+	schedule, err := h.OnCallUsecase.FindOne(c, c.Param("schedule_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedule)
+}
+
+func (h *OnCallHandler) UpdateSchedule(c *gin.Context) {
+	var schedule domain.OnCallSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&schedule); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.OnCallUsecase.UpdateOne(c, &schedule, c.Param("schedule_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *OnCallHandler) DeleteSchedule(c *gin.Context) {
+	err := h.OnCallUsecase.DeleteOne(c, c.Param("schedule_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Schedule deleted successfully"})
+}
+
+func (h *OnCallHandler) AddOverride(c *gin.Context) {
+	var override domain.OnCallOverride
+	if err := c.ShouldBindJSON(&override); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&override); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.OnCallUsecase.AddOverride(c, &override, c.Param("schedule_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *OnCallHandler) RemoveOverride(c *gin.Context) {
+	err := h.OnCallUsecase.RemoveOverride(c, c.Param("override_id"), c.Param("schedule_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(errorStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Override removed successfully"})
+}
+
+// WhoIsOnCall answers for now, or for the RFC3339 instant given in ?at=.
+func (h *OnCallHandler) WhoIsOnCall(c *gin.Context) {
+	at := time.Now()
+	if at_ctx, ok := c.GetQuery("at"); ok {
+		parsed, err := time.Parse(time.RFC3339, at_ctx)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "at must be an RFC3339 timestamp"})
+			return
+		}
+		at = parsed
+	}
+	res, err := h.OnCallUsecase.WhoIsOnCall(c, c.Param("schedule_id"), at, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,392 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+)
+
+type onCallUsecase struct {
+	onCallRepo     domain.OnCallRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+}
+
+func NewOnCallUsecase(o domain.OnCallRepository, u domain.UserRepository, to time.Duration) domain.OnCallUsecase {
+	return &onCallUsecase{
+		onCallRepo:     o,
+		userRepo:       u,
+		contextTimeout: to,
+	}
+}
+
+func (o *onCallUsecase) InsertOne(c context.Context, schedule *domain.OnCallSchedule) (*domain.OnCallSchedule, error) {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	_, err := o.userRepo.FindOne(ctx, schedule.UserID.Hex())
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	err = o.validate(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	schedule.ID = primitive.NewObjectID()
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = time.Now()
+	if schedule.Overrides == nil {
+		schedule.Overrides = []domain.OnCallOverride{}
+	}
+	for i := range schedule.Overrides {
+		schedule.Overrides[i].ID = primitive.NewObjectID()
+	}
+
+	res, err := o.onCallRepo.InsertOne(ctx, schedule)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (o *onCallUsecase) FindOne(c context.Context, id string, userID string) (*domain.OnCallSchedule, error) {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	return o.owned(ctx, id, userID)
+}
+
+// owned loads a schedule on behalf of userID. Schedules of other users are
+// reported as missing.
+func (o *onCallUsecase) owned(ctx context.Context, id string, userID string) (*domain.OnCallSchedule, error) {
+	schedule, err := o.onCallRepo.FindOne(ctx, id)
+	if err != nil || schedule.UserID.Hex() != userID {
+		return nil, domain.ErrScheduleNotFound
+	}
+	return schedule, nil
+}
+
+func (o *onCallUsecase) GetByUserID(c context.Context, userID string) ([]domain.OnCallSchedule, error) {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	res, err := o.onCallRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (o *onCallUsecase) UpdateOne(c context.Context, schedule *domain.OnCallSchedule, id string, userID string) (*domain.OnCallSchedule, error) {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	_, err := o.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	err = o.validate(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := o.onCallRepo.UpdateOne(ctx, schedule, id)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (o *onCallUsecase) DeleteOne(c context.Context, id string, userID string) error {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	_, err := o.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return o.onCallRepo.DeleteOne(ctx, id)
+}
+
+func (o *onCallUsecase) AddOverride(c context.Context, override *domain.OnCallOverride, id string, userID string) (*domain.OnCallOverride, error) {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	_, err := o.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	_, err = o.userRepo.FindOne(ctx, override.UserID.Hex())
+	if err != nil {
+		return nil, errors.New("override user not found")
+	}
+
+	override.ID = primitive.NewObjectID()
+
+	err = o.onCallRepo.AddOverride(ctx, override, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return override, nil
+}
+
+func (o *onCallUsecase) RemoveOverride(c context.Context, overrideID string, id string, userID string) error {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	_, err := o.owned(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	return o.onCallRepo.RemoveOverride(ctx, overrideID, id)
+}
+
+func (o *onCallUsecase) WhoIsOnCall(c context.Context, id string, at time.Time, userID string) (*domain.OnCallResponse, error) {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	schedule, err := o.owned(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := onCallAt(schedule, at)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := o.userRepo.FindOne(ctx, res.User.ID.Hex())
+	if err != nil {
+		return nil, errors.New("on-call user not found")
+	}
+	res.User.Name = user.Name
+	res.User.Email = user.Email
+
+	return res, nil
+}
+
+func (o *onCallUsecase) CheckTarget(c context.Context, target *domain.NotificationTarget, userID string) error {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	return o.checkTarget(ctx, target, userID)
+}
+
+// checkTarget lets userID notify their own schedules, themselves and the
+// people on their schedules, and nobody else.
+func (o *onCallUsecase) checkTarget(ctx context.Context, target *domain.NotificationTarget, userID string) error {
+	if target.ScheduleID != nil {
+		_, err := o.owned(ctx, target.ScheduleID.Hex(), userID)
+		if err != nil {
+			return domain.ErrTargetNotAllowed
+		}
+	}
+
+	if target.UserID != nil && target.UserID.Hex() != userID {
+		schedules, err := o.onCallRepo.GetByUserID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		if !onSchedules(schedules, *target.UserID) {
+			return domain.ErrTargetNotAllowed
+		}
+	}
+
+	return nil
+}
+
+// onSchedules reports whether user takes part in a rotation or an override
+// of any of the schedules.
+func onSchedules(schedules []domain.OnCallSchedule, user primitive.ObjectID) bool {
+	for _, schedule := range schedules {
+		for _, rotation := range schedule.Rotations {
+			for _, participant := range rotation.Participants {
+				if participant == user {
+					return true
+				}
+			}
+		}
+		for _, override := range schedule.Overrides {
+			if override.UserID == user {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ResolveTarget checks the target again when it fires, so targets saved
+// before the check existed, or whose schedule changed hands since, notify
+// nobody.
+func (o *onCallUsecase) ResolveTarget(c context.Context, target *domain.NotificationTarget, userID string, at time.Time) (*domain.Recipient, error) {
+
+	ctx, cancel := context.WithTimeout(c, o.contextTimeout)
+	defer cancel()
+
+	err := o.checkTarget(ctx, target, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	recipient := &domain.Recipient{
+		Channel: target.Channel,
+		Address: target.Address,
+	}
+
+	var onCall *primitive.ObjectID
+	switch {
+	case target.ScheduleID != nil:
+		schedule, err := o.onCallRepo.FindOne(ctx, target.ScheduleID.Hex())
+		if err != nil {
+			return nil, err
+		}
+		res, err := onCallAt(schedule, at)
+		if err != nil {
+			return nil, err
+		}
+		onCall = &res.User.ID
+	case target.UserID != nil:
+		onCall = target.UserID
+	}
+
+	if onCall != nil {
+		user, err := o.userRepo.FindOne(ctx, onCall.Hex())
+		if err != nil {
+			return nil, errors.New("target user not found")
+		}
+		recipient.UserID = &user.ID
+		recipient.Name = user.Name
+		if target.Channel == domain.ChannelEmail {
+			recipient.Address = user.Email
+		}
+	}
+
+	if recipient.Address == "" {
+		return nil, errors.New("notification target has no address")
+	}
+
+	return recipient, nil
+}
+
+func (o *onCallUsecase) validate(ctx context.Context, schedule *domain.OnCallSchedule) error {
+	_, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return errors.New("invalid time zone")
+	}
+
+	for _, rotation := range schedule.Rotations {
+		if rotation.EndAt != nil && !rotation.EndAt.After(rotation.StartAt) {
+			return errors.New("rotation must end after it starts")
+		}
+		for _, participant := range rotation.Participants {
+			_, err := o.userRepo.FindOne(ctx, participant.Hex())
+			if err != nil {
+				return errors.New("participant " + participant.Hex() + " not found")
+			}
+		}
+	}
+	for _, override := range schedule.Overrides {
+		_, err := o.userRepo.FindOne(ctx, override.UserID.Hex())
+		if err != nil {
+			return errors.New("override user " + override.UserID.Hex() + " not found")
+		}
+	}
+
+	return nil
+}
+
+// onCallAt works out who holds the schedule at the given instant. Overrides
+// beat rotations, and the most recently added override wins if several overlap.
+func onCallAt(schedule *domain.OnCallSchedule, at time.Time) (*domain.OnCallResponse, error) {
+	loc, err := time.LoadLocation(schedule.TimeZone)
+	if err != nil {
+		return nil, errors.New("invalid time zone")
+	}
+
+	res := &domain.OnCallResponse{
+		ScheduleID: schedule.ID,
+		At:         at.In(loc),
+	}
+
+	for i := len(schedule.Overrides) - 1; i >= 0; i-- {
+		override := schedule.Overrides[i]
+		if !at.Before(override.Start) && at.Before(override.End) {
+			res.User.ID = override.UserID
+			res.Override = true
+			res.ShiftStart = override.Start.In(loc)
+			res.ShiftEnd = override.End.In(loc)
+			return res, nil
+		}
+	}
+
+	for i := len(schedule.Rotations) - 1; i >= 0; i-- {
+		rotation := schedule.Rotations[i]
+		if at.Before(rotation.StartAt) || len(rotation.Participants) == 0 {
+			continue
+		}
+		if rotation.EndAt != nil && !at.Before(*rotation.EndAt) {
+			continue
+		}
+
+		user, start, end := rotationShift(&rotation, at, loc)
+		res.User.ID = user
+		res.Rotation = rotation.Name
+		res.ShiftStart = start
+		res.ShiftEnd = end
+		return res, nil
+	}
+
+	return nil, errors.New("nobody is on call at the given time")
+}
+
+// rotationShift counts whole calendar days in the schedule's time zone rather
+// than 24h periods, so handoffs stay at the same wall-clock time across DST.
+func rotationShift(rotation *domain.Rotation, at time.Time, loc *time.Location) (primitive.ObjectID, time.Time, time.Time) {
+	start := rotation.StartAt.In(loc)
+	now := at.In(loc)
+
+	handoff := func(days int) time.Time {
+		return time.Date(start.Year(), start.Month(), start.Day()+days, start.Hour(), start.Minute(), start.Second(), 0, loc)
+	}
+
+	startDate := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	nowDate := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(nowDate.Sub(startDate).Hours() / 24)
+	if now.Before(handoff(days)) {
+		days--
+	}
+
+	shiftLength := rotation.ShiftLength
+	if shiftLength <= 0 {
+		shiftLength = 1
+	}
+	unit := shiftLength
+	if rotation.Type == domain.RotationWeekly {
+		unit = 7 * shiftLength
+	}
+
+	shift := days / unit
+	participant := rotation.Participants[shift%len(rotation.Participants)]
+
+	return participant, handoff(shift * unit), handoff((shift + 1) * unit)
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "report_schedule"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.ReportScheduleRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+func (m *mongoRepository) InsertOne(ctx context.Context, schedule *domain.ReportSchedule) (*domain.ReportSchedule, error) {
+	var (
+		err error
+	)
+
+	_, err = m.Collection.InsertOne(ctx, schedule)
+	if err != nil {
+		return schedule, err
+	}
+
+	return schedule, nil
+}
+
+func (m *mongoRepository) GetByUserID(ctx context.Context, userID string) ([]domain.ReportSchedule, error) {
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.find(ctx, bson.M{"user_id": idHex})
+}
+
+func (m *mongoRepository) GetDue(ctx context.Context, at time.Time) ([]domain.ReportSchedule, error) {
+	return m.find(ctx, bson.M{"next_run_at": bson.M{"$lte": at}})
+}
+
+func (m *mongoRepository) find(ctx context.Context, filter interface{}) ([]domain.ReportSchedule, error) {
+	var (
+		schedules []domain.ReportSchedule
+	)
+
+	cursor, err := m.Collection.Find(ctx, filter)
+	if err != nil {
+		return schedules, err
+	}
+	if cursor == nil {
+		return schedules, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &schedules)
+	if err != nil {
+		return schedules, err
+	}
+
+	return schedules, nil
+}
+
+func (m *mongoRepository) MarkSent(ctx context.Context, id primitive.ObjectID, sentAt time.Time, nextRunAt time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"last_sent_at": sentAt,
+		"next_run_at":  nextRunAt,
+		"updated_at":   time.Now(),
+	}}
+
+	_, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// DeleteOne removes the schedule only when userID owns it.
+func (m *mongoRepository) DeleteOne(ctx context.Context, id string, userID string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrReportScheduleNotFound
+	}
+	owner, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.ErrReportScheduleNotFound
+	}
+
+	count, err := m.Collection.DeleteOne(ctx, bson.M{"_id": idHex, "user_id": owner})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain.ErrReportScheduleNotFound
+	}
+
+	return nil
+}
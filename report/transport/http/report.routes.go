@@ -0,0 +1,107 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
+)
+
+type ReportHandler struct {
+	ReportUsecase domain.ReportUsecase
+	config        *bootstrap.Config
+}
+
+func NewReportHandler(cfg *bootstrap.Config, r *gin.RouterGroup, ru domain.ReportUsecase) {
+	handler := &ReportHandler{
+		ReportUsecase: ru,
+		config:        cfg,
+	}
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.POST("/report/schedule", handler.CreateSchedule)
+	protected.GET("/report/schedules", handler.GetSchedulesByUserID)
+	protected.DELETE("/report/schedule/:schedule_id", handler.DeleteSchedule)
+	protected.GET("/report", handler.RenderReport)
+}
+
+func (h *ReportHandler) CreateSchedule(c *gin.Context) {
+	var schedule domain.ReportSchedule
+	if err := c.ShouldBindJSON(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validator.New().Struct(&schedule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+	schedule.UserID = userID
+	res, err := h.ReportUsecase.InsertSchedule(c, &schedule)
+	if errors.Is(err, domain.ErrTargetNotAllowed) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *ReportHandler) GetSchedulesByUserID(c *gin.Context) {
+	schedules, err := h.ReportUsecase.GetSchedulesByUserID(c, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, schedules)
+}
+
+func (h *ReportHandler) DeleteSchedule(c *gin.Context) {
+	err := h.ReportUsecase.DeleteSchedule(c, c.Param("schedule_id"), c.GetString("x-user-id"))
+	if errors.Is(err, domain.ErrReportScheduleNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Report schedule deleted successfully"})
+}
+
+// RenderReport builds the caller's report for the period ending now. It answers with
+// the same HTML that is emailed when ?format=html is given or the client
+// prefers text/html, and with JSON otherwise.
+func (h *ReportHandler) RenderReport(c *gin.Context) {
+	frequency := c.DefaultQuery("frequency", domain.ReportWeekly)
+	report, err := h.ReportUsecase.Build(c, c.GetString("x-user-id"), frequency, time.Now(), c.Query("selector"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	format := c.Query("format")
+	if format == "html" || (format == "" && strings.Contains(c.GetHeader("Accept"), "text/html")) {
+		html, err := h.ReportUsecase.RenderHTML(report)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(html))
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
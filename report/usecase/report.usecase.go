@@ -0,0 +1,401 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"log"
+	"sort"
+	texttemplate "text/template"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/labels"
+	"spectator.main/internals/rabbitmq"
+	"spectator.main/internals/uptime"
+)
+
+const (
+	slowestSiteCount     = 5
+	certificateHorizon   = 30 * 24 * time.Hour
+	maxScheduleLookahead = 62
+)
+
+var reportFuncs = map[string]interface{}{
+	"percent": func(f float64) string { return fmt.Sprintf("%.3f%%", f) },
+	"date":    func(t time.Time) string { return t.Format("2006-01-02 15:04 MST") },
+	"deref":   func(t *time.Time) time.Time { return *t },
+}
+
+var reportHTML = htmltemplate.Must(htmltemplate.New("report").Funcs(reportFuncs).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Spectator {{.Frequency}} report</title></head>
+<body style="font-family:sans-serif">
+<h1>Spectator {{.Frequency}} report</h1>
+<p>{{date .From}} &ndash; {{date .To}} &middot; overall uptime <strong>{{percent .Uptime}}</strong> &middot; {{len .Incidents}} incident(s)</p>
+<h2>Sites</h2>
+<table cellpadding="4"><tr><th align="left">Site</th><th align="left">Config</th><th>Uptime</th><th>Incidents</th><th>Latency</th></tr>
+{{range .Sites}}<tr><td>{{.SiteUrl}}</td><td>{{.ConfigName}}</td><td>{{percent .Uptime}}</td><td>{{.Incidents}}</td><td>{{.Latency}} ms</td></tr>
+{{end}}</table>
+{{if .SlowestSites}}<h2>Slowest sites</h2><ol>{{range .SlowestSites}}<li>{{.SiteUrl}} &ndash; {{.Latency}} ms</li>{{end}}</ol>{{end}}
+{{if .CertificateExpiries}}<h2>Certificates expiring soon</h2><ul>{{range .CertificateExpiries}}<li>{{.SiteUrl}} &ndash; {{date (deref .CertificateExpiresAt)}}</li>{{end}}</ul>{{end}}
+{{if .Incidents}}<h2>Incidents</h2><ul>{{range .Incidents}}<li>{{.SiteUrl}} &ndash; {{.Status}} since {{date .StartedAt}}</li>{{end}}</ul>{{end}}
+</body></html>`))
+
+var reportText = texttemplate.Must(texttemplate.New("report").Funcs(reportFuncs).Parse(`Spectator {{.Frequency}} report ({{date .From}} - {{date .To}})
+Overall uptime {{percent .Uptime}}, {{len .Incidents}} incident(s)
+{{range .Sites}}- {{.SiteUrl}}: {{percent .Uptime}}, {{.Incidents}} incident(s), {{.Latency}} ms
+{{end}}{{if .CertificateExpiries}}Certificates expiring soon:
+{{range .CertificateExpiries}}- {{.SiteUrl}}: {{date (deref .CertificateExpiresAt)}}
+{{end}}{{end}}`))
+
+type reportUsecase struct {
+	scheduleRepo   domain.ReportScheduleRepository
+	configRepo     domain.ConfigRepository
+	incidentRepo   domain.IncidentRepository
+	userRepo       domain.UserRepository
+	onCallUsecase  domain.OnCallUsecase
+	notifier       rabbitmq.MQPublisher
+	contextTimeout time.Duration
+}
+
+func NewReportUsecase(s domain.ReportScheduleRepository, c domain.ConfigRepository, i domain.IncidentRepository, u domain.UserRepository, o domain.OnCallUsecase, notifier rabbitmq.MQPublisher, to time.Duration) domain.ReportUsecase {
+	return &reportUsecase{
+		scheduleRepo:   s,
+		configRepo:     c,
+		incidentRepo:   i,
+		userRepo:       u,
+		onCallUsecase:  o,
+		notifier:       notifier,
+		contextTimeout: to,
+	}
+}
+
+func (r *reportUsecase) InsertSchedule(c context.Context, schedule *domain.ReportSchedule) (*domain.ReportSchedule, error) {
+
+	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
+	defer cancel()
+
+	user, err := r.userRepo.FindOne(ctx, schedule.UserID.Hex())
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	if schedule.TimeZone != "" {
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			return nil, errors.New("invalid time zone")
+		}
+	}
+
+	for i := range schedule.Targets {
+		err = r.onCallUsecase.CheckTarget(ctx, &schedule.Targets[i], schedule.UserID.Hex())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	schedule.ID = primitive.NewObjectID()
+	schedule.CreatedAt = time.Now()
+	schedule.UpdatedAt = time.Now()
+	schedule.NextRunAt = nextRun(schedule, location(schedule, user), time.Now())
+
+	res, err := r.scheduleRepo.InsertOne(ctx, schedule)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (r *reportUsecase) GetSchedulesByUserID(c context.Context, userID string) ([]domain.ReportSchedule, error) {
+
+	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
+	defer cancel()
+
+	res, err := r.scheduleRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (r *reportUsecase) DeleteSchedule(c context.Context, id string, userID string) error {
+
+	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
+	defer cancel()
+
+	return r.scheduleRepo.DeleteOne(ctx, id, userID)
+}
+
+// Build summarizes the period of the given frequency that ends at to. With a
+// selector it covers only the sites whose labels meet it, and the incidents
+// concerning them.
+func (r *reportUsecase) Build(c context.Context, userID string, frequency string, to time.Time, selector string) (*domain.Report, error) {
+
+	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
+	defer cancel()
+
+	userHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, err
+	}
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var from time.Time
+	switch frequency {
+	case domain.ReportDaily:
+		from = to.AddDate(0, 0, -1)
+	case domain.ReportWeekly:
+		from = to.AddDate(0, 0, -7)
+	case domain.ReportMonthly:
+		from = to.AddDate(0, -1, 0)
+	default:
+		return nil, errors.New("frequency must be daily, weekly or monthly")
+	}
+
+	configs, err := r.configRepo.GetAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	incidents, err := r.incidentRepo.GetOverlapping(ctx, userHex, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sel) > 0 {
+		sites := sel.Sites(configs)
+		picked := []domain.Incident{}
+		for _, incident := range incidents {
+			for _, site := range sites {
+				if incident.AffectsSite(site.ConfigID, site.SiteUrl) {
+					picked = append(picked, incident)
+					break
+				}
+			}
+		}
+		incidents = picked
+	}
+
+	report := &domain.Report{
+		UserID:              userHex,
+		Frequency:           frequency,
+		From:                from,
+		To:                  to,
+		GeneratedAt:         time.Now(),
+		Uptime:              100,
+		Sites:               []domain.SiteReport{},
+		Incidents:           incidents,
+		SlowestSites:        []domain.SiteReport{},
+		CertificateExpiries: []domain.SiteReport{},
+	}
+
+	var total float64
+	for _, config := range configs {
+		for _, site := range config.SiteConfig {
+			if !sel.Matches(site.Labels) {
+				continue
+			}
+			siteIncidents := uptime.ForSite(incidents, config.ID.Hex(), site.SiteUrl)
+			siteReport := domain.SiteReport{
+				ConfigID:             config.ID,
+				ConfigName:           config.Name,
+				SiteUrl:              site.SiteUrl,
+				Uptime:               uptime.Percent(siteIncidents, from, to),
+				Downtime:             uptime.Downtime(siteIncidents, from, to),
+				Incidents:            len(siteIncidents),
+				Latency:              averageLatency(&site, from, to),
+				CertificateExpiresAt: site.CertificateExpiresAt,
+			}
+			total += siteReport.Uptime
+			report.Sites = append(report.Sites, siteReport)
+
+			if site.CertificateExpiresAt != nil && site.CertificateExpiresAt.Before(to.Add(certificateHorizon)) {
+				report.CertificateExpiries = append(report.CertificateExpiries, siteReport)
+			}
+			if siteReport.Latency > 0 {
+				report.SlowestSites = append(report.SlowestSites, siteReport)
+			}
+		}
+	}
+	if len(report.Sites) > 0 {
+		report.Uptime = total / float64(len(report.Sites))
+	}
+
+	sort.Slice(report.SlowestSites, func(i, j int) bool {
+		return report.SlowestSites[i].Latency > report.SlowestSites[j].Latency
+	})
+	if len(report.SlowestSites) > slowestSiteCount {
+		report.SlowestSites = report.SlowestSites[:slowestSiteCount]
+	}
+	sort.Slice(report.CertificateExpiries, func(i, j int) bool {
+		return report.CertificateExpiries[i].CertificateExpiresAt.Before(*report.CertificateExpiries[j].CertificateExpiresAt)
+	})
+
+	return report, nil
+}
+
+func (r *reportUsecase) RenderHTML(report *domain.Report) (string, error) {
+	var buf bytes.Buffer
+	err := reportHTML.Execute(&buf, report)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// SendDue delivers every schedule whose next run has passed and moves it on
+// to the following run in its time zone.
+func (r *reportUsecase) SendDue(c context.Context) error {
+
+	ctx, cancel := context.WithTimeout(c, r.contextTimeout)
+	defer cancel()
+
+	now := time.Now()
+	schedules, err := r.scheduleRepo.GetDue(ctx, now)
+	if err != nil {
+		return err
+	}
+
+	for i := range schedules {
+		schedule := &schedules[i]
+
+		user, err := r.userRepo.FindOne(ctx, schedule.UserID.Hex())
+		if err != nil {
+			log.Println("report: user not found for schedule", schedule.ID.Hex())
+			continue
+		}
+
+		err = r.send(ctx, schedule, now)
+		if err != nil {
+			log.Println("report: sending schedule", schedule.ID.Hex()+":", err)
+		}
+
+		err = r.scheduleRepo.MarkSent(ctx, schedule.ID, now, nextRun(schedule, location(schedule, user), now))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *reportUsecase) send(ctx context.Context, schedule *domain.ReportSchedule, now time.Time) error {
+	report, err := r.Build(ctx, schedule.UserID.Hex(), schedule.Frequency, schedule.NextRunAt, "")
+	if err != nil {
+		return err
+	}
+
+	html, err := r.RenderHTML(report)
+	if err != nil {
+		return err
+	}
+
+	var text bytes.Buffer
+	err = reportText.Execute(&text, report)
+	if err != nil {
+		return err
+	}
+
+	subject := fmt.Sprintf("Spectator %s report: %.3f%% uptime", report.Frequency, report.Uptime)
+	for _, target := range schedule.Targets {
+		recipient, err := r.onCallUsecase.ResolveTarget(ctx, &target, schedule.UserID.Hex(), now)
+		if err != nil {
+			log.Println("report: resolving notification target:", err)
+			continue
+		}
+
+		notification := domain.Notification{
+			ID:        primitive.NewObjectID(),
+			UserID:    schedule.UserID,
+			Recipient: *recipient,
+			Body:      text.String(),
+			CreatedAt: now,
+		}
+		if recipient.Channel == domain.ChannelEmail {
+			notification.Subject = subject
+			notification.Body = html
+		}
+
+		notificationJson, err := json.Marshal(notification)
+		if err != nil {
+			return err
+		}
+
+		err = r.notifier.Publish(notificationJson)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// averageLatency averages the latest latency of the regions that reached the
+// site within the period, as stored by alert ingest. Regions that last
+// reported before the period or found the site down are left out, so a
+// site nobody probed lately has no latency and is not among the slowest.
+func averageLatency(site *domain.SiteConfig, from time.Time, to time.Time) int64 {
+	var (
+		sum   int64
+		count int64
+	)
+	for _, region := range site.RegionDetails {
+		if region.Status && region.Latency > 0 && !region.ResponseTime.Before(from) && !region.ResponseTime.After(to) {
+			sum += region.Latency
+			count++
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+	return sum / count
+}
+
+func location(schedule *domain.ReportSchedule, user *domain.User) *time.Location {
+	for _, name := range []string{schedule.TimeZone, user.TimeZone} {
+		if name == "" {
+			continue
+		}
+		if loc, err := time.LoadLocation(name); err == nil {
+			return loc
+		}
+	}
+	return time.UTC
+}
+
+// nextRun finds the first matching local day after the given instant.
+func nextRun(schedule *domain.ReportSchedule, loc *time.Location, after time.Time) time.Time {
+	local := after.In(loc)
+	dayOfMonth := schedule.DayOfMonth
+	if dayOfMonth == 0 {
+		dayOfMonth = 1
+	}
+
+	for i := 0; i < maxScheduleLookahead; i++ {
+		t := time.Date(local.Year(), local.Month(), local.Day()+i, schedule.Hour, 0, 0, 0, loc)
+		if !t.After(after) {
+			continue
+		}
+		switch schedule.Frequency {
+		case domain.ReportWeekly:
+			if int(t.Weekday()) != schedule.Weekday {
+				continue
+			}
+		case domain.ReportMonthly:
+			if t.Day() != dayOfMonth {
+				continue
+			}
+		}
+		return t
+	}
+
+	return after.Add(24 * time.Hour)
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "status_page"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.StatusPageRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+func (m *mongoRepository) InsertOne(ctx context.Context, page *domain.StatusPage) (*domain.StatusPage, error) {
+	var (
+		err error
+	)
+
+	_, err = m.Collection.InsertOne(ctx, page)
+	if err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
+
+func (m *mongoRepository) FindOne(ctx context.Context, id string) (*domain.StatusPage, error) {
+	var (
+		page domain.StatusPage
+		err  error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &page, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&page)
+	if err != nil {
+		return &page, err
+	}
+
+	return &page, nil
+}
+
+func (m *mongoRepository) FindBySlug(ctx context.Context, slug string) (*domain.StatusPage, error) {
+	var (
+		page domain.StatusPage
+		err  error
+	)
+
+	err = m.Collection.FindOne(ctx, bson.M{"slug": slug}).Decode(&page)
+	if err != nil {
+		return &page, err
+	}
+
+	return &page, nil
+}
+
+func (m *mongoRepository) GetByUserID(ctx context.Context, userID string) ([]domain.StatusPage, error) {
+	var (
+		pages []domain.StatusPage
+		err   error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return pages, err
+	}
+
+	cursor, err := m.Collection.Find(ctx, bson.M{"user_id": idHex})
+	if err != nil {
+		return pages, err
+	}
+	if cursor == nil {
+		return pages, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &pages)
+	if err != nil {
+		return pages, err
+	}
+
+	return pages, nil
+}
+
+func (m *mongoRepository) UpdateOne(ctx context.Context, page *domain.StatusPage, id string) (*domain.StatusPage, error) {
+	var (
+		err error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return page, err
+	}
+
+	filter := bson.M{"_id": idHex}
+	update := bson.M{"$set": bson.M{
+		"slug":        page.Slug,
+		"title":       page.Title,
+		"description": page.Description,
+		"components":  page.Components,
+		"updated_at":  time.Now(),
+
+		"visibility":       page.Visibility,
+		"password_hash":    page.PasswordHash,
+		"allowed_domains":  page.AllowedDomains,
+		"allowed_user_ids": page.AllowedUserIDs,
+		"access_version":   page.AccessVersion,
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return page, err
+	}
+	if result.MatchedCount == 0 {
+		return page, errors.New("no status page found with the given id")
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(page)
+	if err != nil {
+		return page, err
+	}
+
+	return page, nil
+}
+
+func (m *mongoRepository) SetViewerToken(ctx context.Context, token string, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"viewer_token": token,
+		"updated_at":   time.Now(),
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": idHex}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no status page found with the given id")
+	}
+
+	return nil
+}
+
+func (m *mongoRepository) DeleteOne(ctx context.Context, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	count, err := m.Collection.DeleteOne(ctx, bson.M{"_id": idHex})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("no status page found with the given id")
+	}
+
+	return nil
+}
+
+// RemoveConfig takes the sites of a deleted config out of every component.
+func (m *mongoRepository) RemoveConfig(ctx context.Context, configID primitive.ObjectID) error {
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"components.sites.config_id": configID},
+		bson.M{"$pull": bson.M{"components.$[].sites": bson.M{"config_id": configID}}},
+	)
+	return err
+}
+
+// RenameSite follows a site to its new URL in every component.
+func (m *mongoRepository) RenameSite(ctx context.Context, configID primitive.ObjectID, oldUrl string, newUrl string) error {
+	site := bson.M{"config_id": configID, "site_url": oldUrl}
+	opts := options.Update().SetArrayFilters(options.ArrayFilters{
+		Filters: []interface{}{bson.M{"s.config_id": configID, "s.site_url": oldUrl}},
+	})
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"components.sites": bson.M{"$elemMatch": site}},
+		bson.M{"$set": bson.M{"components.$[].sites.$[s].site_url": newUrl}},
+		opts,
+	)
+	return err
+}
+
+// RemoveSite takes a deleted site out of every component.
+func (m *mongoRepository) RemoveSite(ctx context.Context, configID primitive.ObjectID, siteUrl string) error {
+	site := bson.M{"config_id": configID, "site_url": siteUrl}
+	_, err := m.Collection.UpdateMany(ctx,
+		bson.M{"components.sites": bson.M{"$elemMatch": site}},
+		bson.M{"$pull": bson.M{"components.$[].sites": site}},
+	)
+	return err
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type visitRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	visitCollectionName = "status_page_visit"
+)
+
+func NewVisitMongoRepository(DB mongo.Database) domain.StatusPageVisitRepository {
+	return &visitRepository{DB, DB.Collection(visitCollectionName)}
+}
+
+func (m *visitRepository) InsertOne(ctx context.Context, visit *domain.StatusPageVisit) (*domain.StatusPageVisit, error) {
+	var (
+		err error
+	)
+
+	_, err = m.Collection.InsertOne(ctx, visit)
+	if err != nil {
+		return visit, err
+	}
+
+	return visit, nil
+}
+
+// GetByPageID returns the page's most recent visits, newest first.
+func (m *visitRepository) GetByPageID(ctx context.Context, pageID primitive.ObjectID, limit int64) ([]domain.StatusPageVisit, error) {
+	var (
+		visits []domain.StatusPageVisit
+		err    error
+	)
+
+	opts := options.Find().SetSort(bson.M{"viewed_at": -1}).SetLimit(limit)
+	cursor, err := m.Collection.Find(ctx, bson.M{"page_id": pageID}, opts)
+	if err != nil {
+		return visits, err
+	}
+	if cursor == nil {
+		return visits, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &visits)
+	if err != nil {
+		return visits, err
+	}
+
+	return visits, nil
+}
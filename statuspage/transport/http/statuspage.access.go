@@ -0,0 +1,340 @@
+package http
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	tokenutil "spectator.main/internals/util"
+)
+
+const (
+	viewerCookie      = "spectator_viewer"
+	viewerTokenHeader = "X-Status-Page-Token"
+
+	// visitInterval is how often a returning viewer is written to the audit.
+	visitInterval = time.Hour
+)
+
+type viewerLogin struct {
+	Email    string `form:"email" json:"email"`
+	Password string `form:"password" json:"password" binding:"required"`
+}
+
+type loginView struct {
+	Page  *domain.StatusPage
+	Error string
+}
+
+// recentVisits remembers when each viewer was last audited so a page that
+// refreshes every minute does not write a visit every minute.
+type recentVisits struct {
+	sync.Mutex
+	seen map[string]time.Time
+}
+
+func (r *recentVisits) due(key string, now time.Time) bool {
+	r.Lock()
+	defer r.Unlock()
+
+	if last, ok := r.seen[key]; ok && now.Sub(last) < visitInterval {
+		return false
+	}
+	if len(r.seen) > 10000 {
+		for k, last := range r.seen {
+			if now.Sub(last) >= visitInterval {
+				delete(r.seen, k)
+			}
+		}
+	}
+	r.seen[key] = now
+	return true
+}
+
+// privateWriter keeps responses of private pages out of shared caches,
+// whatever Cache-Control the handler chose.
+type privateWriter struct {
+	gin.ResponseWriter
+}
+
+func (w *privateWriter) private() {
+	if !w.Written() {
+		w.Header().Set("Cache-Control", "private, no-store")
+	}
+}
+
+func (w *privateWriter) WriteHeader(code int) {
+	w.private()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *privateWriter) WriteHeaderNow() {
+	w.private()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *privateWriter) Write(data []byte) (int, error) {
+	w.private()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *privateWriter) WriteString(s string) (int, error) {
+	w.private()
+	return w.ResponseWriter.WriteString(s)
+}
+
+// viewerAccess guards every public route of a private page. Viewers get in
+// with a session cookie from the login form, the page's viewer token, or a
+// Spectator access token the page allows. Login, logout and the links mailed
+// to subscribers stay open.
+func (h *StatusPageHandler) viewerAccess(c *gin.Context) {
+	slug, path := c.Param("slug"), c.FullPath()
+	if slug == "" || strings.HasSuffix(path, "/login") || strings.HasSuffix(path, "/logout") || strings.Contains(path, "/subscription/") {
+		c.Next()
+		return
+	}
+
+	page, err := h.StatusPageUsecase.FindBySlug(c, slug)
+	if err != nil || !page.Private() {
+		c.Next()
+		return
+	}
+
+	visit, ok := h.admit(c, page)
+	if !ok {
+		h.denied(c, page)
+		c.Abort()
+		return
+	}
+
+	c.Writer = &privateWriter{c.Writer}
+	if h.visits.due(page.ID.Hex()+"|"+viewerKey(c, visit), visit.ViewedAt) {
+		h.recordVisit(c, visit)
+	}
+	c.Next()
+}
+
+// admit checks the request's credentials against page and describes the
+// visit they allow.
+func (h *StatusPageHandler) admit(c *gin.Context, page *domain.StatusPage) (*domain.StatusPageVisit, bool) {
+	visit := &domain.StatusPageVisit{
+		PageID:    page.ID,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Path:      c.Request.URL.Path,
+		ViewedAt:  time.Now(),
+	}
+
+	if cookie, err := c.Cookie(viewerCookie); err == nil {
+		claims, err := tokenutil.ParseViewerSessionToken(cookie, h.config.LinkSecret)
+		if err == nil && claims.PageID == page.ID.Hex() && claims.Version == page.AccessVersion {
+			visit.Method = claims.Method
+			if userID, err := primitive.ObjectIDFromHex(claims.UserID); err == nil {
+				visit.UserID = &userID
+			}
+			return visit, true
+		}
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		token = c.GetHeader(viewerTokenHeader)
+	}
+	if token != "" && page.ViewerToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(page.ViewerToken)) == 1 {
+		visit.Method = domain.ViewerMethodToken
+		return visit, true
+	}
+
+	t := strings.Split(c.GetHeader("Authorization"), " ")
+	if len(t) == 2 {
+		userID, err := tokenutil.ExtractIDFromToken(t[1], h.config.AccessTokenSecret)
+		if err == nil && h.StatusPageUsecase.Allows(c, page, userID) {
+			visit.Method = domain.ViewerMethodUser
+			if id, err := primitive.ObjectIDFromHex(userID); err == nil {
+				visit.UserID = &id
+			}
+			return visit, true
+		}
+	}
+
+	return nil, false
+}
+
+func (h *StatusPageHandler) denied(c *gin.Context, page *domain.StatusPage) {
+	c.Header("Cache-Control", "private, no-store")
+	if c.Request.Method == http.MethodGet && c.FullPath() == "/status/:slug" {
+		h.renderLogin(c, http.StatusUnauthorized, page, "")
+		return
+	}
+	c.JSON(http.StatusUnauthorized, gin.H{"error": "this status page is private"})
+}
+
+func (h *StatusPageHandler) renderLogin(c *gin.Context, status int, page *domain.StatusPage, message string) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Status(status)
+	err := pageTemplates.ExecuteTemplate(c.Writer, "login.html", loginView{Page: page, Error: message})
+	if err != nil {
+		c.Error(err)
+	}
+}
+
+func viewerKey(c *gin.Context, visit *domain.StatusPageVisit) string {
+	if visit.UserID != nil {
+		return visit.UserID.Hex()
+	}
+	return visit.IP + "|" + visit.UserAgent
+}
+
+func (h *StatusPageHandler) recordVisit(c *gin.Context, visit *domain.StatusPageVisit) {
+	err := h.StatusPageUsecase.RecordVisit(c, visit)
+	if err != nil {
+		log.Printf("status page %s: recording visit: %v", visit.PageID.Hex(), err)
+	}
+}
+
+func (h *StatusPageHandler) Login(c *gin.Context) {
+	page, err := h.StatusPageUsecase.FindBySlug(c, c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "status page not found"})
+		return
+	}
+	pageURL := "/status/" + page.Slug
+	wantsJSON := c.ContentType() == gin.MIMEJSON
+
+	if !page.Private() {
+		c.Redirect(http.StatusSeeOther, pageURL)
+		return
+	}
+
+	var req viewerLogin
+	if err := c.ShouldBind(&req); err != nil {
+		if wantsJSON {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.renderLogin(c, http.StatusBadRequest, page, "Enter your password.")
+		return
+	}
+
+	userID, method, err := h.StatusPageUsecase.Authenticate(c, page, strings.TrimSpace(req.Email), req.Password)
+	if err != nil {
+		if wantsJSON {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		h.renderLogin(c, http.StatusUnauthorized, page, err.Error())
+		return
+	}
+
+	claims := &domain.StatusPageViewerClaims{
+		PageID:  page.ID.Hex(),
+		Method:  method,
+		Version: page.AccessVersion,
+	}
+	if userID != nil {
+		claims.UserID = userID.Hex()
+	}
+	expiry := h.config.ViewerSessionExpiryHour
+	if expiry <= 0 {
+		expiry = 24
+	}
+	token, err := tokenutil.CreateViewerSessionToken(claims, h.config.LinkSecret, expiry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.setViewerCookie(c, page, token, expiry*3600)
+
+	h.recordVisit(c, &domain.StatusPageVisit{
+		PageID:    page.ID,
+		UserID:    userID,
+		Method:    method,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Path:      c.Request.URL.Path,
+		ViewedAt:  time.Now(),
+	})
+
+	c.Header("Cache-Control", "private, no-store")
+	if wantsJSON {
+		c.JSON(http.StatusOK, gin.H{"message": "Signed in"})
+		return
+	}
+	c.Redirect(http.StatusSeeOther, pageURL)
+}
+
+func (h *StatusPageHandler) Logout(c *gin.Context) {
+	page, err := h.StatusPageUsecase.FindBySlug(c, c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "status page not found"})
+		return
+	}
+	h.setViewerCookie(c, page, "", -1)
+	c.Redirect(http.StatusSeeOther, "/status/"+page.Slug)
+}
+
+// setViewerCookie scopes the session to the page it was issued for. A
+// negative maxAge deletes the cookie.
+func (h *StatusPageHandler) setViewerCookie(c *gin.Context, page *domain.StatusPage, value string, maxAge int) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     viewerCookie,
+		Value:    value,
+		Path:     "/status/" + page.Slug,
+		MaxAge:   maxAge,
+		Secure:   strings.HasPrefix(h.config.PublicURL, "https://"),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// owned loads the page and hides it from anyone but its owner.
+func (h *StatusPageHandler) owned(c *gin.Context) (*domain.StatusPage, bool) {
+	page, err := h.StatusPageUsecase.FindOne(c, c.Param("page_id"))
+	if err != nil || page.UserID.Hex() != c.GetString("x-user-id") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "status page not found"})
+		return nil, false
+	}
+	return page, true
+}
+
+func (h *StatusPageHandler) RotateViewerToken(c *gin.Context) {
+	page, ok := h.owned(c)
+	if !ok {
+		return
+	}
+	token, err := h.StatusPageUsecase.RotateViewerToken(c, page.ID.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, domain.ViewerTokenResponse{ViewerToken: token})
+}
+
+func (h *StatusPageHandler) GetVisits(c *gin.Context) {
+	page, ok := h.owned(c)
+	if !ok {
+		return
+	}
+	limit := int64(100)
+	if raw, ok := c.GetQuery("limit"); ok {
+		n, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || n < 1 || n > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 1000"})
+			return
+		}
+		limit = n
+	}
+	visits, err := h.StatusPageUsecase.GetVisits(c, page.ID.Hex(), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, visits)
+}
@@ -0,0 +1,197 @@
+package http
+
+import (
+	"embed"
+	"errors"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/markdown"
+	"spectator.main/internals/middleware"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+var statusTexts = map[string]string{
+	domain.ComponentOperational:      "All Systems Operational",
+	domain.ComponentUnderMaintenance: "Under Maintenance",
+	domain.ComponentPartialOutage:    "Partial Outage",
+	domain.ComponentMajorOutage:      "Major Outage",
+}
+
+var pageTemplates = template.Must(template.New("").Funcs(template.FuncMap{
+	"statusText": func(status string) string { return statusTexts[status] },
+	"join":       strings.Join,
+	"stateText": func(state string) string {
+		if state == "" {
+			return ""
+		}
+		return strings.ToUpper(state[:1]) + state[1:]
+	},
+	"markdown": func(src string) template.HTML { return template.HTML(markdown.HTML(src)) },
+	"resolved": func(incidents []domain.Incident) []domain.Incident {
+		res := []domain.Incident{}
+		for _, incident := range incidents {
+			if incident.Status == domain.IncidentResolved {
+				res = append(res, incident)
+			}
+		}
+		return res
+	},
+	"barLevel": func(uptime float64) string {
+		switch {
+		case uptime >= 99.9:
+			return ""
+		case uptime >= 99:
+			return "minor"
+		default:
+			return "major"
+		}
+	},
+}).ParseFS(templateFS, "templates/*.html"))
+
+type StatusPageHandler struct {
+	StatusPageUsecase domain.StatusPageUsecase
+	config            *bootstrap.Config
+	visits            *recentVisits
+}
+
+// NewStatusPageHandler registers the management API on r and the public
+// pages on public. It also installs the viewer access check on public, so it
+// must run before any other handler adds routes there.
+func NewStatusPageHandler(cfg *bootstrap.Config, r *gin.RouterGroup, public *gin.RouterGroup, su domain.StatusPageUsecase) {
+	handler := &StatusPageHandler{
+		StatusPageUsecase: su,
+		config:            cfg,
+		visits:            &recentVisits{seen: map[string]time.Time{}},
+	}
+	public.Use(handler.viewerAccess)
+
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.POST("/statuspage", handler.CreateStatusPage)
+	protected.GET("/statuspages", handler.GetStatusPagesByUserID)
+	protected.GET("/statuspage/:page_id", handler.GetStatusPage)
+	protected.PUT("/statuspage/:page_id", handler.UpdateStatusPage)
+	protected.DELETE("/statuspage/:page_id", handler.DeleteStatusPage)
+	protected.POST("/statuspage/:page_id/viewer-token", handler.RotateViewerToken)
+	protected.GET("/statuspage/:page_id/visits", handler.GetVisits)
+
+	public.GET("/:slug", handler.RenderStatusPage)
+	public.POST("/:slug/login", handler.Login)
+	public.GET("/:slug/logout", handler.Logout)
+	public.POST("/:slug/logout", handler.Logout)
+	handler.registerV2(public)
+}
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *StatusPageHandler) CreateStatusPage(c *gin.Context) {
+	var page domain.StatusPage
+	if err := c.ShouldBindJSON(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&page); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+	page.UserID = userID
+	res, err := h.StatusPageUsecase.InsertOne(c, &page)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, res)
+}
+
+func (h *StatusPageHandler) GetStatusPagesByUserID(c *gin.Context) {
+	pages, err := h.StatusPageUsecase.GetByUserID(c, c.GetString("x-user-id"), c.Query("selector"))
+	if errors.Is(err, domain.ErrInvalidLabel) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, pages)
+}
+
+func (h *StatusPageHandler) GetStatusPage(c *gin.Context) {
+	page, ok := h.owned(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *StatusPageHandler) UpdateStatusPage(c *gin.Context) {
+	var page domain.StatusPage
+	if err := c.ShouldBindJSON(&page); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&page); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	existing, ok := h.owned(c)
+	if !ok {
+		return
+	}
+	res, err := h.StatusPageUsecase.UpdateOne(c, &page, existing.ID.Hex())
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *StatusPageHandler) DeleteStatusPage(c *gin.Context) {
+	page, ok := h.owned(c)
+	if !ok {
+		return
+	}
+	err := h.StatusPageUsecase.DeleteOne(c, page.ID.Hex())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Status page deleted successfully"})
+}
+
+func (h *StatusPageHandler) RenderStatusPage(c *gin.Context) {
+	view, err := h.StatusPageUsecase.View(c, c.Param("slug"))
+	if err != nil {
+		c.String(http.StatusNotFound, "Status page not found")
+		return
+	}
+
+	c.Header("Content-Type", "text/html; charset=utf-8")
+	c.Header("Cache-Control", "public, max-age=30")
+	c.Status(http.StatusOK)
+	err = pageTemplates.ExecuteTemplate(c.Writer, "status.html", view)
+	if err != nil {
+		c.Error(err)
+	}
+}
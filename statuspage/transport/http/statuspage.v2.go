@@ -0,0 +1,353 @@
+package http
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"spectator.main/domain"
+)
+
+// The types below mirror the Atlassian Statuspage v2 public API so existing
+// widgets and aggregators can read Spectator pages unchanged. Fields that have
+// no Spectator equivalent are still emitted with the values Statuspage uses
+// when the feature is off.
+
+type v2Page struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	TimeZone  string    `json:"time_zone"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type v2Status struct {
+	Indicator   string `json:"indicator"`
+	Description string `json:"description"`
+}
+
+type v2Component struct {
+	ID                 string    `json:"id"`
+	Name               string    `json:"name"`
+	Status             string    `json:"status"`
+	CreatedAt          time.Time `json:"created_at"`
+	UpdatedAt          time.Time `json:"updated_at"`
+	Position           int       `json:"position"`
+	Description        *string   `json:"description"`
+	Showcase           bool      `json:"showcase"`
+	StartDate          *string   `json:"start_date"`
+	GroupID            *string   `json:"group_id"`
+	PageID             string    `json:"page_id"`
+	Group              bool      `json:"group"`
+	OnlyShowIfDegraded bool      `json:"only_show_if_degraded"`
+}
+
+type v2IncidentUpdate struct {
+	ID                 string        `json:"id"`
+	Status             string        `json:"status"`
+	Body               string        `json:"body"`
+	IncidentID         string        `json:"incident_id"`
+	CreatedAt          time.Time     `json:"created_at"`
+	UpdatedAt          time.Time     `json:"updated_at"`
+	DisplayAt          time.Time     `json:"display_at"`
+	AffectedComponents []interface{} `json:"affected_components"`
+}
+
+type v2Incident struct {
+	ID              string             `json:"id"`
+	Name            string             `json:"name"`
+	Status          string             `json:"status"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	MonitoringAt    *time.Time         `json:"monitoring_at"`
+	ResolvedAt      *time.Time         `json:"resolved_at"`
+	Impact          string             `json:"impact"`
+	Shortlink       string             `json:"shortlink"`
+	StartedAt       time.Time          `json:"started_at"`
+	PageID          string             `json:"page_id"`
+	IncidentUpdates []v2IncidentUpdate `json:"incident_updates"`
+	Components      []v2Component      `json:"components"`
+
+	PostmortemBody        *string    `json:"postmortem_body,omitempty"`
+	PostmortemPublishedAt *time.Time `json:"postmortem_published_at,omitempty"`
+}
+
+type v2Maintenance struct {
+	v2Incident
+	ScheduledFor   time.Time `json:"scheduled_for"`
+	ScheduledUntil time.Time `json:"scheduled_until"`
+}
+
+var v2Indicators = map[string]string{
+	domain.ComponentOperational:      "none",
+	domain.ComponentUnderMaintenance: "maintenance",
+	domain.ComponentPartialOutage:    "minor",
+	domain.ComponentMajorOutage:      "major",
+}
+
+func (h *StatusPageHandler) registerV2(public *gin.RouterGroup) {
+	public.GET("/:slug/api/v2/summary.json", h.v2Summary)
+	public.GET("/:slug/api/v2/status.json", h.v2StatusJSON)
+	public.GET("/:slug/api/v2/components.json", h.v2Components)
+	public.GET("/:slug/api/v2/incidents.json", h.v2Incidents)
+	public.GET("/:slug/api/v2/incidents/unresolved.json", h.v2UnresolvedIncidents)
+	public.GET("/:slug/api/v2/scheduled-maintenances.json", h.v2Maintenances)
+	public.GET("/:slug/api/v2/scheduled-maintenances/upcoming.json", h.v2UpcomingMaintenances)
+	public.GET("/:slug/api/v2/scheduled-maintenances/active.json", h.v2ActiveMaintenances)
+}
+
+func (h *StatusPageHandler) v2View(c *gin.Context) (*domain.StatusPageView, bool) {
+	view, err := h.StatusPageUsecase.View(c, c.Param("slug"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Not found"})
+		return nil, false
+	}
+	c.Header("Access-Control-Allow-Origin", "*")
+	c.Header("Cache-Control", "public, max-age=30")
+	return view, true
+}
+
+func (h *StatusPageHandler) v2Summary(c *gin.Context) {
+	view, ok := h.v2View(c)
+	if !ok {
+		return
+	}
+	maintenances := []v2Maintenance{}
+	for _, m := range h.v2MaintenanceList(view) {
+		if m.Status != domain.MaintenanceCompleted {
+			maintenances = append(maintenances, m)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"page":                   h.v2Page(view),
+		"components":             h.v2ComponentList(view),
+		"incidents":              h.v2IncidentList(view, view.Incidents),
+		"scheduled_maintenances": maintenances,
+		"status":                 h.v2Status(view),
+	})
+}
+
+func (h *StatusPageHandler) v2StatusJSON(c *gin.Context) {
+	view, ok := h.v2View(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"page": h.v2Page(view), "status": h.v2Status(view)})
+}
+
+func (h *StatusPageHandler) v2Components(c *gin.Context) {
+	view, ok := h.v2View(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"page": h.v2Page(view), "components": h.v2ComponentList(view)})
+}
+
+func (h *StatusPageHandler) v2Incidents(c *gin.Context) {
+	view, ok := h.v2View(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"page": h.v2Page(view), "incidents": h.v2IncidentList(view, view.History)})
+}
+
+func (h *StatusPageHandler) v2UnresolvedIncidents(c *gin.Context) {
+	view, ok := h.v2View(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"page": h.v2Page(view), "incidents": h.v2IncidentList(view, view.Incidents)})
+}
+
+func (h *StatusPageHandler) v2Maintenances(c *gin.Context) {
+	h.v2MaintenancesWithStatus(c, "")
+}
+
+func (h *StatusPageHandler) v2UpcomingMaintenances(c *gin.Context) {
+	h.v2MaintenancesWithStatus(c, domain.MaintenanceScheduled)
+}
+
+func (h *StatusPageHandler) v2ActiveMaintenances(c *gin.Context) {
+	h.v2MaintenancesWithStatus(c, domain.MaintenanceInProgress)
+}
+
+func (h *StatusPageHandler) v2MaintenancesWithStatus(c *gin.Context, status string) {
+	view, ok := h.v2View(c)
+	if !ok {
+		return
+	}
+	maintenances := []v2Maintenance{}
+	for _, m := range h.v2MaintenanceList(view) {
+		if status == "" || m.Status == status {
+			maintenances = append(maintenances, m)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"page": h.v2Page(view), "scheduled_maintenances": maintenances})
+}
+
+func (h *StatusPageHandler) v2Page(view *domain.StatusPageView) v2Page {
+	return v2Page{
+		ID:        view.Page.ID.Hex(),
+		Name:      view.Page.Title,
+		URL:       h.pageURL(&view.Page),
+		TimeZone:  "Etc/UTC",
+		UpdatedAt: view.Page.UpdatedAt,
+	}
+}
+
+func (h *StatusPageHandler) pageURL(page *domain.StatusPage) string {
+	return strings.TrimSuffix(h.config.PublicURL, "/") + "/status/" + page.Slug
+}
+
+func (h *StatusPageHandler) v2Status(view *domain.StatusPageView) v2Status {
+	return v2Status{Indicator: v2Indicators[view.Status], Description: statusTexts[view.Status]}
+}
+
+func (h *StatusPageHandler) v2ComponentList(view *domain.StatusPageView) []v2Component {
+	components := []v2Component{}
+	for i, component := range view.Components {
+		var description *string
+		if component.Description != "" {
+			description = &view.Components[i].Description
+		}
+		components = append(components, v2Component{
+			ID:          component.ID.Hex(),
+			Name:        component.Name,
+			Status:      component.Status,
+			CreatedAt:   view.Page.CreatedAt,
+			UpdatedAt:   view.Page.UpdatedAt,
+			Position:    i + 1,
+			Description: description,
+			PageID:      view.Page.ID.Hex(),
+		})
+	}
+	return components
+}
+
+// v2ComponentsFor lists the page components that contain any of the sites.
+func (h *StatusPageHandler) v2ComponentsFor(view *domain.StatusPageView, affects func(site domain.StatusSite) bool) []v2Component {
+	all := h.v2ComponentList(view)
+	components := []v2Component{}
+	for i, component := range view.Page.Components {
+		for _, site := range component.Sites {
+			if affects(site) {
+				components = append(components, all[i])
+				break
+			}
+		}
+	}
+	return components
+}
+
+func (h *StatusPageHandler) v2IncidentList(view *domain.StatusPageView, incidents []domain.Incident) []v2Incident {
+	res := []v2Incident{}
+	for _, incident := range incidents {
+		res = append(res, h.v2Incident(view, &incident))
+	}
+	return res
+}
+
+func (h *StatusPageHandler) v2Incident(view *domain.StatusPageView, incident *domain.Incident) v2Incident {
+	id := incident.ID.Hex()
+	impact := incident.Impact
+	if impact == "" {
+		impact = domain.IncidentImpactMajor
+	}
+
+	res := v2Incident{
+		ID:              id,
+		Name:            incident.Name(),
+		Status:          incident.CurrentState(),
+		CreatedAt:       incident.CreatedAt,
+		UpdatedAt:       incident.UpdatedAt,
+		ResolvedAt:      incident.ResolvedAt,
+		Impact:          impact,
+		Shortlink:       h.pageURL(&view.Page) + "#incident-" + id,
+		StartedAt:       incident.StartedAt,
+		PageID:          view.Page.ID.Hex(),
+		IncidentUpdates: []v2IncidentUpdate{},
+		Components:      []v2Component{},
+	}
+
+	all := h.v2ComponentList(view)
+	for _, affected := range view.Page.IncidentComponents(incident) {
+		for _, component := range all {
+			if component.ID == affected.ID.Hex() {
+				res.Components = append(res.Components, component)
+			}
+		}
+	}
+
+	timeline := incident.Timeline()
+	for i, update := range timeline {
+		updateID := update.ID.Hex()
+		if update.ID.IsZero() {
+			updateID = id + "-" + update.State
+		}
+		if update.State == domain.IncidentMonitoring && res.MonitoringAt == nil {
+			res.MonitoringAt = &timeline[i].CreatedAt
+		}
+		res.IncidentUpdates = append(res.IncidentUpdates, v2IncidentUpdate{
+			ID:         updateID,
+			Status:     update.State,
+			Body:       update.Body,
+			IncidentID: id,
+			CreatedAt:  update.CreatedAt,
+			UpdatedAt:  update.CreatedAt,
+			DisplayAt:  update.CreatedAt,
+		})
+	}
+
+	if postmortem := incident.PublishedPostmortem(); postmortem != nil {
+		res.PostmortemBody = &postmortem.Body
+		res.PostmortemPublishedAt = postmortem.PublishedAt
+	}
+
+	return res
+}
+
+func (h *StatusPageHandler) v2MaintenanceList(view *domain.StatusPageView) []v2Maintenance {
+	now := time.Now()
+	res := []v2Maintenance{}
+	for _, maintenance := range view.Maintenances {
+		m := maintenance
+		id := m.ID.Hex()
+		status := m.Status(now)
+
+		var resolvedAt *time.Time
+		if status == domain.MaintenanceCompleted {
+			resolvedAt = &m.ScheduledUntil
+		}
+
+		res = append(res, v2Maintenance{
+			v2Incident: v2Incident{
+				ID:         id,
+				Name:       m.Title,
+				Status:     status,
+				CreatedAt:  m.CreatedAt,
+				UpdatedAt:  m.UpdatedAt,
+				ResolvedAt: resolvedAt,
+				Impact:     "maintenance",
+				Shortlink:  h.pageURL(&view.Page),
+				StartedAt:  m.ScheduledFor,
+				PageID:     view.Page.ID.Hex(),
+				IncidentUpdates: []v2IncidentUpdate{{
+					ID:         id + "-scheduled",
+					Status:     domain.MaintenanceScheduled,
+					Body:       m.Description,
+					IncidentID: id,
+					CreatedAt:  m.CreatedAt,
+					UpdatedAt:  m.UpdatedAt,
+					DisplayAt:  m.CreatedAt,
+				}},
+				Components: h.v2ComponentsFor(view, func(site domain.StatusSite) bool {
+					return m.Affects(site.ConfigID, site.SiteUrl)
+				}),
+			},
+			ScheduledFor:   m.ScheduledFor,
+			ScheduledUntil: m.ScheduledUntil,
+		})
+	}
+	return res
+}
@@ -0,0 +1,199 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	_statusPageHandler "spectator.main/statuspage/transport/http"
+)
+
+var errNotFound = errors.New("status page not found")
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// statusPageUsecase serves one public page. Any other usecase method panics.
+type statusPageUsecase struct {
+	domain.StatusPageUsecase
+	view *domain.StatusPageView
+}
+
+func (s *statusPageUsecase) FindBySlug(ctx context.Context, slug string) (*domain.StatusPage, error) {
+	if slug != s.view.Page.Slug {
+		return nil, errNotFound
+	}
+	return &s.view.Page, nil
+}
+
+func (s *statusPageUsecase) View(ctx context.Context, slug string) (*domain.StatusPageView, error) {
+	if slug != s.view.Page.Slug {
+		return nil, errNotFound
+	}
+	return s.view, nil
+}
+
+func id(hex string) primitive.ObjectID {
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		panic(err)
+	}
+	return id
+}
+
+func at(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// fixture is a page with an open probe incident, a resolved manual incident
+// with a published postmortem, a finished maintenance window and one far in
+// the future, so every field of the v2 documents is exercised.
+func fixture() *domain.StatusPageView {
+	config := id("650000000000000000000001")
+	api := domain.StatusSite{ConfigID: config, SiteUrl: "https://api.example.com/health"}
+	web := domain.StatusSite{ConfigID: config, SiteUrl: "https://www.example.com"}
+
+	page := domain.StatusPage{
+		ID:        id("650000000000000000000010"),
+		UserID:    id("650000000000000000000002"),
+		CreatedAt: at("2024-01-01T00:00:00Z"),
+		UpdatedAt: at("2024-03-01T12:00:00Z"),
+		Slug:      "acme",
+		Title:     "Acme",
+		Components: []domain.StatusComponent{
+			{ID: id("650000000000000000000011"), Name: "API", Description: "Public REST API", Sites: []domain.StatusSite{api}},
+			{ID: id("650000000000000000000012"), Name: "Website", Sites: []domain.StatusSite{web}},
+		},
+	}
+
+	open := domain.Incident{
+		ID:        id("650000000000000000000020"),
+		UserID:    page.UserID,
+		ConfigID:  config,
+		SiteUrl:   api.SiteUrl,
+		CreatedAt: at("2024-03-01T10:00:00Z"),
+		UpdatedAt: at("2024-03-01T10:05:00Z"),
+		Status:    domain.IncidentOpen,
+		StartedAt: at("2024-03-01T10:00:00Z"),
+		Source:    domain.IncidentSourceProbe,
+	}
+	resolved := domain.Incident{
+		ID:         id("650000000000000000000021"),
+		UserID:     page.UserID,
+		CreatedAt:  at("2024-02-10T08:00:00Z"),
+		UpdatedAt:  at("2024-02-10T09:30:00Z"),
+		Status:     domain.IncidentResolved,
+		StartedAt:  at("2024-02-10T08:00:00Z"),
+		ResolvedAt: ptr(at("2024-02-10T09:30:00Z")),
+		Source:     domain.IncidentSourceManual,
+		Title:      "Slow page loads",
+		Impact:     domain.IncidentImpactMinor,
+		State:      domain.IncidentStateResolved,
+		Sites:      []domain.StatusSite{web},
+		Updates: []domain.IncidentUpdate{
+			{ID: id("650000000000000000000031"), State: domain.IncidentInvestigating, Body: "We are looking into slow page loads.", CreatedAt: at("2024-02-10T08:00:00Z")},
+			{ID: id("650000000000000000000032"), State: domain.IncidentMonitoring, Body: "A fix is rolling out.", CreatedAt: at("2024-02-10T09:00:00Z")},
+			{ID: id("650000000000000000000033"), State: domain.IncidentStateResolved, Body: "Page loads are back to normal.", CreatedAt: at("2024-02-10T09:30:00Z")},
+		},
+		Postmortem: &domain.Postmortem{
+			Body:        "A cache node ran out of memory.",
+			UpdatedAt:   at("2024-02-12T00:00:00Z"),
+			PublishedAt: ptr(at("2024-02-12T00:00:00Z")),
+		},
+	}
+
+	return &domain.StatusPageView{
+		Page:   page,
+		Status: domain.ComponentPartialOutage,
+		Components: []domain.ComponentView{
+			{ID: page.Components[0].ID, Name: "API", Description: "Public REST API", Status: domain.ComponentMajorOutage, Uptime: 99.5},
+			{ID: page.Components[1].ID, Name: "Website", Status: domain.ComponentOperational, Uptime: 100},
+		},
+		Incidents: []domain.Incident{open},
+		History:   []domain.Incident{open, resolved},
+		Maintenances: []domain.Maintenance{
+			{
+				ID:             id("650000000000000000000040"),
+				UserID:         page.UserID,
+				CreatedAt:      at("2024-01-20T00:00:00Z"),
+				UpdatedAt:      at("2024-01-20T00:00:00Z"),
+				Title:          "Database upgrade",
+				Description:    "The API will be read-only.",
+				Sites:          []domain.StatusSite{api},
+				ScheduledFor:   at("2024-01-27T02:00:00Z"),
+				ScheduledUntil: at("2024-01-27T04:00:00Z"),
+			},
+			{
+				ID:             id("650000000000000000000041"),
+				UserID:         page.UserID,
+				CreatedAt:      at("2024-03-01T00:00:00Z"),
+				UpdatedAt:      at("2024-03-01T00:00:00Z"),
+				Title:          "Network maintenance",
+				Description:    "Brief interruptions are expected.",
+				Sites:          []domain.StatusSite{api, web},
+				ScheduledFor:   at("2999-01-01T02:00:00Z"),
+				ScheduledUntil: at("2999-01-01T03:00:00Z"),
+			},
+		},
+		GeneratedAt: at("2024-03-01T12:00:00Z"),
+	}
+}
+
+func TestV2Golden(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	cfg := &bootstrap.Config{PublicURL: "https://spectator.example.com", AccessTokenSecret: "test-secret"}
+	_statusPageHandler.NewStatusPageHandler(cfg, router.Group("api/v1"), router.Group("status"), &statusPageUsecase{view: fixture()})
+
+	for _, name := range []string{"summary.json", "status.json", "incidents.json", "scheduled-maintenances.json"} {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/status/acme/api/v2/"+name, nil)
+			res := httptest.NewRecorder()
+			router.ServeHTTP(res, req)
+			if res.Code != http.StatusOK {
+				t.Fatalf("got %d, want %d: %s", res.Code, http.StatusOK, res.Body)
+			}
+
+			var got bytes.Buffer
+			err := json.Indent(&got, res.Body.Bytes(), "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got.WriteByte('\n')
+
+			golden := filepath.Join("testdata", "v2", name)
+			if *update {
+				err = os.WriteFile(golden, got.Bytes(), 0o644)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("%s differs from %s:\n%s", name, golden, got.String())
+			}
+		})
+	}
+}
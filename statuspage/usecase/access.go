@@ -0,0 +1,187 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
+	"spectator.main/domain"
+	tokenutil "spectator.main/internals/util"
+)
+
+// setAccess hashes a newly supplied password and bumps AccessVersion when the
+// access rules change, so sessions granted under the old rules stop working.
+// existing is nil for new pages.
+func setAccess(page *domain.StatusPage, existing *domain.StatusPage) error {
+	if page.Visibility == "" {
+		page.Visibility = domain.VisibilityPublic
+	}
+	for i, d := range page.AllowedDomains {
+		page.AllowedDomains[i] = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(d), "@"))
+	}
+	if page.AllowedDomains == nil {
+		page.AllowedDomains = []string{}
+	}
+	if page.AllowedUserIDs == nil {
+		page.AllowedUserIDs = []primitive.ObjectID{}
+	}
+
+	changed := false
+	if existing != nil {
+		page.PasswordHash = existing.PasswordHash
+		page.AccessVersion = existing.AccessVersion
+		changed = existing.Visibility != page.Visibility ||
+			strings.Join(existing.AllowedDomains, ",") != strings.Join(page.AllowedDomains, ",") ||
+			len(existing.AllowedUserIDs) != len(page.AllowedUserIDs)
+		for i := 0; !changed && i < len(page.AllowedUserIDs); i++ {
+			changed = existing.AllowedUserIDs[i] != page.AllowedUserIDs[i]
+		}
+	}
+
+	if page.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(page.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return err
+		}
+		page.PasswordHash = string(hash)
+		page.Password = ""
+		changed = true
+	}
+	if page.Visibility == domain.VisibilityPassword && page.PasswordHash == "" {
+		return errors.New("password-protected pages need a password")
+	}
+
+	if changed {
+		page.AccessVersion++
+	}
+	return nil
+}
+
+func (s *statusPageUsecase) FindBySlug(c context.Context, slug string) (*domain.StatusPage, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	return s.statusPageRepo.FindBySlug(ctx, slug)
+}
+
+// Authenticate checks viewer credentials for a private page: the page
+// password, or for restricted pages a member's Spectator email and password.
+// It returns the signed-in user, if any, and the method used.
+func (s *statusPageUsecase) Authenticate(c context.Context, page *domain.StatusPage, email string, password string) (*primitive.ObjectID, string, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	invalid := errors.New("invalid credentials")
+
+	switch page.Visibility {
+	case domain.VisibilityPassword:
+		if bcrypt.CompareHashAndPassword([]byte(page.PasswordHash), []byte(password)) != nil {
+			return nil, "", invalid
+		}
+		return nil, domain.ViewerMethodPassword, nil
+	case domain.VisibilityRestricted:
+		user, err := s.userRepo.FindByEmail(ctx, email)
+		if err != nil {
+			return nil, "", invalid
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+			return nil, "", invalid
+		}
+		if !member(page, user) {
+			return nil, "", errors.New("you do not have access to this status page")
+		}
+		return &user.ID, domain.ViewerMethodUser, nil
+	}
+
+	return nil, "", errors.New("status page is public")
+}
+
+// Allows reports whether an authenticated Spectator user may view the page.
+// Owners may always view their pages.
+func (s *statusPageUsecase) Allows(c context.Context, page *domain.StatusPage, userID string) bool {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	if page.UserID.Hex() == userID {
+		return true
+	}
+	if page.Visibility != domain.VisibilityRestricted {
+		return false
+	}
+
+	user, err := s.userRepo.FindOne(ctx, userID)
+	if err != nil {
+		return false
+	}
+	return member(page, user)
+}
+
+func member(page *domain.StatusPage, user *domain.User) bool {
+	if user.ID == page.UserID {
+		return true
+	}
+	for _, id := range page.AllowedUserIDs {
+		if id == user.ID {
+			return true
+		}
+	}
+	// Anyone can type any email into their account, so only a verified
+	// one vouches for its domain.
+	at := strings.LastIndex(user.Email, "@")
+	if at < 0 || user.EmailVerifiedAt == nil {
+		return false
+	}
+	domainPart := strings.ToLower(user.Email[at+1:])
+	for _, allowed := range page.AllowedDomains {
+		if allowed == domainPart {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *statusPageUsecase) RotateViewerToken(c context.Context, id string) (string, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	token, err := tokenutil.CreateRandomToken()
+	if err != nil {
+		return "", err
+	}
+
+	err = s.statusPageRepo.SetViewerToken(ctx, token, id)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *statusPageUsecase) RecordVisit(c context.Context, visit *domain.StatusPageVisit) error {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	visit.ID = primitive.NewObjectID()
+	_, err := s.visitRepo.InsertOne(ctx, visit)
+	return err
+}
+
+func (s *statusPageUsecase) GetVisits(c context.Context, pageID string, limit int64) ([]domain.StatusPageVisit, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	idHex, err := primitive.ObjectIDFromHex(pageID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.visitRepo.GetByPageID(ctx, idHex, limit)
+}
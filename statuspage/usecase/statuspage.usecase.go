@@ -0,0 +1,367 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/labels"
+	"spectator.main/internals/uptime"
+)
+
+const (
+	historyDays      = 90
+	historyIncidents = 50
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+type statusPageUsecase struct {
+	statusPageRepo  domain.StatusPageRepository
+	configRepo      domain.ConfigRepository
+	incidentRepo    domain.IncidentRepository
+	maintenanceRepo domain.MaintenanceRepository
+	userRepo        domain.UserRepository
+	visitRepo       domain.StatusPageVisitRepository
+	contextTimeout  time.Duration
+}
+
+func NewStatusPageUsecase(s domain.StatusPageRepository, c domain.ConfigRepository, i domain.IncidentRepository, m domain.MaintenanceRepository, u domain.UserRepository, v domain.StatusPageVisitRepository, to time.Duration) domain.StatusPageUsecase {
+	return &statusPageUsecase{
+		statusPageRepo:  s,
+		configRepo:      c,
+		incidentRepo:    i,
+		maintenanceRepo: m,
+		userRepo:        u,
+		visitRepo:       v,
+		contextTimeout:  to,
+	}
+}
+
+func (s *statusPageUsecase) InsertOne(c context.Context, page *domain.StatusPage) (*domain.StatusPage, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	_, err := s.userRepo.FindOne(ctx, page.UserID.Hex())
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	err = s.validate(ctx, page, primitive.NilObjectID)
+	if err != nil {
+		return nil, err
+	}
+	err = setAccess(page, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	page.ID = primitive.NewObjectID()
+	page.CreatedAt = time.Now()
+	page.UpdatedAt = time.Now()
+
+	res, err := s.statusPageRepo.InsertOne(ctx, page)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *statusPageUsecase) FindOne(c context.Context, id string) (*domain.StatusPage, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	res, err := s.statusPageRepo.FindOne(ctx, id)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+// GetByUserID lists the user's status pages, with a selector only those
+// showing a site whose labels meet it.
+func (s *statusPageUsecase) GetByUserID(c context.Context, userID string, selector string) ([]domain.StatusPage, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	sel, err := labels.Parse(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.statusPageRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return res, err
+	}
+	if len(sel) == 0 {
+		return res, nil
+	}
+
+	configs, err := s.configRepo.GetAllByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	sites := sel.Sites(configs)
+
+	picked := []domain.StatusPage{}
+	for _, page := range res {
+		for _, site := range sites {
+			if page.Includes(site.ConfigID, site.SiteUrl) {
+				picked = append(picked, page)
+				break
+			}
+		}
+	}
+
+	return picked, nil
+}
+
+func (s *statusPageUsecase) UpdateOne(c context.Context, page *domain.StatusPage, id string) (*domain.StatusPage, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	existing, err := s.statusPageRepo.FindOne(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	page.UserID = existing.UserID
+
+	err = s.validate(ctx, page, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+	err = setAccess(page, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := s.statusPageRepo.UpdateOne(ctx, page, id)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (s *statusPageUsecase) DeleteOne(c context.Context, id string) error {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	return s.statusPageRepo.DeleteOne(ctx, id)
+}
+
+// View computes the public state of the page: current component status from
+// open incidents and running maintenance, 90 days of daily uptime, the
+// incidents of those 90 days and recent maintenance windows.
+func (s *statusPageUsecase) View(c context.Context, slug string) (*domain.StatusPageView, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	page, err := s.statusPageRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, errors.New("status page not found")
+	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	from := today.AddDate(0, 0, -(historyDays - 1))
+
+	// Only incidents open at some point in the window are loaded; they serve
+	// both the uptime bars and the incident history.
+	incidents, err := s.incidentRepo.GetOverlapping(ctx, page.UserID, from, now)
+	if err != nil {
+		return nil, err
+	}
+
+	maintenances, err := s.maintenanceRepo.GetByUserID(ctx, page.UserID.Hex())
+	if err != nil {
+		return nil, err
+	}
+
+	// Visitors never need the page's access settings.
+	public := *page
+	public.PasswordHash, public.ViewerToken = "", ""
+	public.AllowedDomains, public.AllowedUserIDs = nil, nil
+
+	view := &domain.StatusPageView{
+		Page:         public,
+		Status:       domain.ComponentOperational,
+		Components:   []domain.ComponentView{},
+		Incidents:    []domain.Incident{},
+		History:      []domain.Incident{},
+		Maintenances: []domain.Maintenance{},
+		GeneratedAt:  now,
+	}
+
+	for _, incident := range incidents {
+		if len(view.History) < historyIncidents && len(page.IncidentComponents(&incident)) > 0 {
+			view.History = append(view.History, incident)
+		}
+	}
+
+	for _, maintenance := range maintenances {
+		// Show recurring maintenance as its current or next window.
+		maintenance.ScheduledFor, maintenance.ScheduledUntil = maintenance.Window(now)
+		if maintenance.Status(now) == domain.MaintenanceCompleted && maintenance.ScheduledUntil.Before(from) {
+			continue
+		}
+		for _, site := range maintenance.Sites {
+			if page.Includes(site.ConfigID, site.SiteUrl) {
+				view.Maintenances = append(view.Maintenances, maintenance)
+				break
+			}
+		}
+	}
+
+	active := map[primitive.ObjectID]bool{}
+	for _, component := range page.Components {
+		componentView := domain.ComponentView{
+			ID:          component.ID,
+			Name:        component.Name,
+			Description: component.Description,
+			Status:      domain.ComponentOperational,
+			Uptime:      100,
+		}
+
+		sites := [][]domain.Incident{}
+		down := 0
+		maintained := 0
+		var total float64
+		for _, site := range component.Sites {
+			siteIncidents := uptime.ForSite(incidents, site.ConfigID.Hex(), site.SiteUrl)
+			sites = append(sites, siteIncidents)
+			total += uptime.Percent(siteIncidents, from, now)
+
+			open := false
+			for _, incident := range siteIncidents {
+				if incident.Status == domain.IncidentOpen {
+					open = true
+					if !active[incident.ID] {
+						active[incident.ID] = true
+						view.Incidents = append(view.Incidents, incident)
+					}
+				}
+			}
+			if open {
+				down++
+			}
+
+			for _, maintenance := range view.Maintenances {
+				if maintenance.Status(now) == domain.MaintenanceInProgress && maintenance.Affects(site.ConfigID, site.SiteUrl) {
+					maintained++
+					break
+				}
+			}
+		}
+
+		if len(component.Sites) > 0 {
+			componentView.Uptime = total / float64(len(component.Sites))
+		}
+		switch {
+		case down == 0 && maintained > 0:
+			componentView.Status = domain.ComponentUnderMaintenance
+		case down == 0:
+		case down == len(component.Sites):
+			componentView.Status = domain.ComponentMajorOutage
+		default:
+			componentView.Status = domain.ComponentPartialOutage
+		}
+
+		// Manual incidents set the component status from their declared
+		// impact rather than from probe results.
+		for _, incident := range incidents {
+			if incident.Source != domain.IncidentSourceManual || incident.Status != domain.IncidentOpen {
+				continue
+			}
+			for _, affected := range page.IncidentComponents(&incident) {
+				if affected.ID != component.ID {
+					continue
+				}
+				if !active[incident.ID] {
+					active[incident.ID] = true
+					view.Incidents = append(view.Incidents, incident)
+				}
+				status := domain.ComponentPartialOutage
+				if incident.Impact == domain.IncidentImpactMajor {
+					status = domain.ComponentMajorOutage
+				}
+				componentView.Status = worseStatus(componentView.Status, status)
+			}
+		}
+		componentView.Days = uptime.Daily(sites, historyDays, now)
+
+		view.Status = worseStatus(view.Status, componentView.Status)
+		view.Components = append(view.Components, componentView)
+	}
+
+	return view, nil
+}
+
+// validate checks the slug is well-formed and free, and that every site on
+// the page belongs to one of the owner's configs.
+func (s *statusPageUsecase) validate(ctx context.Context, page *domain.StatusPage, id primitive.ObjectID) error {
+	if !slugPattern.MatchString(page.Slug) {
+		return errors.New("slug may only contain lowercase letters, digits and dashes")
+	}
+
+	existing, err := s.statusPageRepo.FindBySlug(ctx, page.Slug)
+	if err == nil && existing.ID != id {
+		return errors.New("slug is already taken")
+	}
+
+	configs := map[primitive.ObjectID]*domain.ConfigDetails{}
+	for i := range page.Components {
+		component := &page.Components[i]
+		if component.ID.IsZero() {
+			component.ID = primitive.NewObjectID()
+		}
+
+		for _, site := range component.Sites {
+			config, ok := configs[site.ConfigID]
+			if !ok {
+				config, err = s.configRepo.FindOne(ctx, site.ConfigID.Hex())
+				if err != nil || config.UserID != page.UserID {
+					return errors.New("config " + site.ConfigID.Hex() + " not found")
+				}
+				configs[site.ConfigID] = config
+			}
+
+			found := false
+			for _, siteConfig := range config.SiteConfig {
+				if siteConfig.SiteUrl == site.SiteUrl {
+					found = true
+				}
+			}
+			if !found {
+				return errors.New("site " + site.SiteUrl + " not found in config " + config.Name)
+			}
+		}
+	}
+
+	return nil
+}
+
+var statusSeverity = map[string]int{
+	domain.ComponentOperational:      0,
+	domain.ComponentUnderMaintenance: 1,
+	domain.ComponentPartialOutage:    2,
+	domain.ComponentMajorOutage:      3,
+}
+
+func worseStatus(a string, b string) string {
+	if statusSeverity[b] > statusSeverity[a] {
+		return b
+	}
+	return a
+}
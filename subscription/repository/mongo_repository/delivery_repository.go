@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type deliveryRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	deliveryCollectionName = "subscription_delivery"
+)
+
+func NewDeliveryMongoRepository(DB mongo.Database) domain.SubscriptionDeliveryRepository {
+	return &deliveryRepository{DB, DB.Collection(deliveryCollectionName)}
+}
+
+func (m *deliveryRepository) InsertMany(ctx context.Context, deliveries []domain.SubscriptionDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		docs = append(docs, delivery)
+	}
+
+	_, err := m.Collection.InsertMany(ctx, docs)
+	return err
+}
+
+// GetPending returns up to limit unsent opt-in messages, or unsent incident
+// messages when optIns is false, oldest first.
+func (m *deliveryRepository) GetPending(ctx context.Context, optIns bool, limit int64) ([]domain.SubscriptionDelivery, error) {
+	var (
+		deliveries []domain.SubscriptionDelivery
+		err        error
+	)
+
+	filter := bson.M{"sent_at": nil, "kind": domain.SubscriptionConfirm}
+	if !optIns {
+		filter["kind"] = bson.M{"$ne": domain.SubscriptionConfirm}
+	}
+	opts := options.Find().SetSort(bson.M{"created_at": 1}).SetLimit(limit)
+	cursor, err := m.Collection.Find(ctx, filter, opts)
+	if err != nil {
+		return deliveries, err
+	}
+	if cursor == nil {
+		return deliveries, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &deliveries)
+	if err != nil {
+		return deliveries, err
+	}
+
+	return deliveries, nil
+}
+
+// HasPendingOptIn reports whether an opt-in message to the subscription is
+// still waiting to be sent.
+func (m *deliveryRepository) HasPendingOptIn(ctx context.Context, subscriptionID primitive.ObjectID) (bool, error) {
+	count, err := m.Collection.CountDocuments(ctx, bson.M{"subscription_id": subscriptionID, "kind": domain.SubscriptionConfirm, "sent_at": nil})
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// CountOptInsTo counts the opt-in messages queued for address since then.
+func (m *deliveryRepository) CountOptInsTo(ctx context.Context, address string, since time.Time) (int64, error) {
+	return m.Collection.CountDocuments(ctx, bson.M{"recipient.address": address, "kind": domain.SubscriptionConfirm, "created_at": bson.M{"$gte": since}})
+}
+
+// CountOptInsFrom counts the opt-in messages requested from ip since then.
+func (m *deliveryRepository) CountOptInsFrom(ctx context.Context, ip string, since time.Time) (int64, error) {
+	return m.Collection.CountDocuments(ctx, bson.M{"request_ip": ip, "kind": domain.SubscriptionConfirm, "created_at": bson.M{"$gte": since}})
+}
+
+func (m *deliveryRepository) MarkSent(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"sent_at": at}})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no delivery found with the given id")
+	}
+
+	return nil
+}
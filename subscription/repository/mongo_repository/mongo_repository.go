@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "subscription"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.SubscriptionRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+func (m *mongoRepository) InsertOne(ctx context.Context, subscription *domain.Subscription) (*domain.Subscription, error) {
+	var (
+		err error
+	)
+
+	_, err = m.Collection.InsertOne(ctx, subscription)
+	if err != nil {
+		return subscription, err
+	}
+
+	return subscription, nil
+}
+
+func (m *mongoRepository) FindOne(ctx context.Context, id string) (*domain.Subscription, error) {
+	var (
+		subscription domain.Subscription
+		err          error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return &subscription, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&subscription)
+	if err != nil {
+		return &subscription, err
+	}
+
+	return &subscription, nil
+}
+
+func (m *mongoRepository) FindByAddress(ctx context.Context, pageID primitive.ObjectID, channel domain.NotificationChannel, address string) (*domain.Subscription, error) {
+	var (
+		subscription domain.Subscription
+		err          error
+	)
+
+	filter := bson.M{"page_id": pageID, "channel": channel, "address": address}
+	err = m.Collection.FindOne(ctx, filter).Decode(&subscription)
+	if err != nil {
+		return &subscription, err
+	}
+
+	return &subscription, nil
+}
+
+func (m *mongoRepository) GetByPageID(ctx context.Context, pageID primitive.ObjectID, confirmedOnly bool) ([]domain.Subscription, error) {
+	var (
+		subscriptions []domain.Subscription
+		err           error
+	)
+
+	filter := bson.M{"page_id": pageID}
+	if confirmedOnly {
+		filter["confirmed_at"] = bson.M{"$ne": nil}
+	}
+
+	cursor, err := m.Collection.Find(ctx, filter)
+	if err != nil {
+		return subscriptions, err
+	}
+	if cursor == nil {
+		return subscriptions, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &subscriptions)
+	if err != nil {
+		return subscriptions, err
+	}
+
+	return subscriptions, nil
+}
+
+func (m *mongoRepository) SetComponents(ctx context.Context, id primitive.ObjectID, componentIDs []primitive.ObjectID) error {
+	update := bson.M{"$set": bson.M{
+		"component_ids": componentIDs,
+		"updated_at":    time.Now(),
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no subscription found with the given id")
+	}
+
+	return nil
+}
+
+func (m *mongoRepository) Confirm(ctx context.Context, id primitive.ObjectID, at time.Time) error {
+	update := bson.M{"$set": bson.M{
+		"confirmed_at": at,
+		"updated_at":   time.Now(),
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no subscription found with the given id")
+	}
+
+	return nil
+}
+
+func (m *mongoRepository) DeleteOne(ctx context.Context, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	count, err := m.Collection.DeleteOne(ctx, bson.M{"_id": idHex})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return errors.New("no subscription found with the given id")
+	}
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"errors"
+	"html/template"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
+)
+
+type SubscriptionHandler struct {
+	SubscriptionUsecase domain.SubscriptionUsecase
+	config              *bootstrap.Config
+}
+
+var linkPage = template.Must(template.New("subscription").Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>Spectator</title></head>
+<body><p>{{.}}</p></body></html>`))
+
+// NewSubscriptionHandler mounts subscriber sign-up and the signed confirm and
+// unsubscribe links on public, next to the status page itself, and subscriber
+// management on r, behind the page owner's token.
+func NewSubscriptionHandler(cfg *bootstrap.Config, r *gin.RouterGroup, public *gin.RouterGroup, su domain.SubscriptionUsecase) {
+	handler := &SubscriptionHandler{
+		SubscriptionUsecase: su,
+		config:              cfg,
+	}
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.GET("/statuspage/:page_id/subscriptions", handler.GetSubscriptions)
+	protected.DELETE("/statuspage/:page_id/subscription/:subscription_id", handler.DeleteSubscription)
+
+	public.POST("/:slug/subscribe", handler.Subscribe)
+	public.GET("/:slug/subscription/confirm", handler.Confirm)
+	public.GET("/:slug/subscription/unsubscribe", handler.Unsubscribe)
+	// One-click unsubscribe (RFC 8058) posts to the same link.
+	public.POST("/:slug/subscription/unsubscribe", handler.Unsubscribe)
+}
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *SubscriptionHandler) Subscribe(c *gin.Context) {
+	var subscription domain.Subscription
+	if err := c.ShouldBindJSON(&subscription); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&subscription); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_, err := h.SubscriptionUsecase.Subscribe(c, c.Param("slug"), &subscription, c.ClientIP())
+	if errors.Is(err, domain.ErrTooManyOptIns) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "Confirmation sent, follow the link in it to start receiving updates"})
+}
+
+func (h *SubscriptionHandler) Confirm(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+
+	_, err := h.SubscriptionUsecase.Confirm(c, c.Query("token"))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		linkPage.Execute(c.Writer, err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+	linkPage.Execute(c.Writer, "Subscription confirmed. You will be notified about incidents on this page.")
+}
+
+func (h *SubscriptionHandler) Unsubscribe(c *gin.Context) {
+	c.Header("Content-Type", "text/html; charset=utf-8")
+
+	_, err := h.SubscriptionUsecase.Unsubscribe(c, c.Query("token"))
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		linkPage.Execute(c.Writer, err.Error())
+		return
+	}
+	c.Status(http.StatusOK)
+	linkPage.Execute(c.Writer, "You have been unsubscribed and will not receive further updates.")
+}
+
+func (h *SubscriptionHandler) GetSubscriptions(c *gin.Context) {
+	res, err := h.SubscriptionUsecase.GetByPageID(c, c.Param("page_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *SubscriptionHandler) DeleteSubscription(c *gin.Context) {
+	err := h.SubscriptionUsecase.DeleteOne(c, c.Param("page_id"), c.Param("subscription_id"), c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Subscription deleted successfully"})
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"spectator.main/domain"
+)
+
+// Webhook subscribers receive these as the JSON body; email subscribers get
+// the plain text built alongside.
+
+type webhookPage struct {
+	ID    string `json:"id"`
+	Slug  string `json:"slug"`
+	Title string `json:"title"`
+	URL   string `json:"url"`
+}
+
+type webhookComponent struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type webhookIncident struct {
+	ID         string                 `json:"id"`
+	Name       string                 `json:"name"`
+	Status     string                 `json:"status"`
+	Impact     string                 `json:"impact"`
+	SiteUrl    string                 `json:"site_url,omitempty"`
+	Regions    []string               `json:"regions,omitempty"`
+	StartedAt  time.Time              `json:"started_at"`
+	ResolvedAt *time.Time             `json:"resolved_at"`
+	Update     *domain.IncidentUpdate `json:"update"`
+}
+
+type webhookPayload struct {
+	Type           string             `json:"type"`
+	Page           webhookPage        `json:"page"`
+	Incident       *webhookIncident   `json:"incident,omitempty"`
+	Components     []webhookComponent `json:"components,omitempty"`
+	ConfirmURL     string             `json:"confirm_url,omitempty"`
+	UnsubscribeURL string             `json:"unsubscribe_url,omitempty"`
+	SentAt         time.Time          `json:"sent_at"`
+}
+
+func newWebhookPage(page *domain.StatusPage, pageURL string) webhookPage {
+	return webhookPage{ID: page.ID.Hex(), Slug: page.Slug, Title: page.Title, URL: pageURL}
+}
+
+func marshal(payload *webhookPayload) (string, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func confirmMessage(page *domain.StatusPage, subscription *domain.Subscription, pageURL string, confirmURL string) (string, string, error) {
+	subject := fmt.Sprintf("Confirm your subscription to %s", page.Title)
+
+	if subscription.Channel == domain.ChannelWebhook {
+		body, err := marshal(&webhookPayload{
+			Type:       domain.SubscriptionConfirm,
+			Page:       newWebhookPage(page, pageURL),
+			ConfirmURL: confirmURL,
+			SentAt:     time.Now(),
+		})
+		return subject, body, err
+	}
+
+	body := fmt.Sprintf("Someone, hopefully you, asked to be notified about incidents on %s (%s).\n\n"+
+		"Confirm the subscription by opening this link:\n%s\n\n"+
+		"If this wasn't you, ignore this message and nothing will be sent.\n",
+		page.Title, pageURL, confirmURL)
+	return subject, body, nil
+}
+
+func incidentMessage(page *domain.StatusPage, subscription *domain.Subscription, incident *domain.Incident, event string, components []domain.StatusComponent, pageURL string, unsubscribeURL string) (string, string, error) {
+	update := incident.Timeline()[0]
+	if incident.Source != domain.IncidentSourceManual && event == domain.SubscriptionIncidentUpdated {
+		update.Body = fmt.Sprintf("%s is still unavailable from %s.", incident.SiteUrl, strings.Join(incident.Regions, ", "))
+		update.CreatedAt = incident.UpdatedAt
+	}
+
+	if subscription.Channel == domain.ChannelWebhook {
+		impact := incident.Impact
+		if impact == "" {
+			impact = domain.IncidentImpactMajor
+		}
+		payload := &webhookPayload{
+			Type: event,
+			Page: newWebhookPage(page, pageURL),
+			Incident: &webhookIncident{
+				ID:         incident.ID.Hex(),
+				Name:       incident.Name(),
+				Status:     incident.CurrentState(),
+				Impact:     impact,
+				SiteUrl:    incident.SiteUrl,
+				Regions:    incident.Regions,
+				StartedAt:  incident.StartedAt,
+				ResolvedAt: incident.ResolvedAt,
+				Update:     &update,
+			},
+			UnsubscribeURL: unsubscribeURL,
+			SentAt:         time.Now(),
+		}
+		for _, component := range components {
+			payload.Components = append(payload.Components, webhookComponent{ID: component.ID.Hex(), Name: component.Name})
+		}
+		body, err := marshal(payload)
+		return "", body, err
+	}
+
+	names := []string{}
+	for _, component := range components {
+		names = append(names, component.Name)
+	}
+
+	subject := fmt.Sprintf("[%s] %s: %s", page.Title, stateTitle(update.State), incident.Name())
+	body := fmt.Sprintf("%s\n\n%s\n\nAffected: %s\n\nCurrent status: %s\n\n--\nUnsubscribe: %s\n",
+		update.CreatedAt.UTC().Format("Jan 2, 15:04 MST"), update.Body, strings.Join(names, ", "), pageURL, unsubscribeURL)
+	return subject, body, nil
+}
+
+func stateTitle(state string) string {
+	if state == "" {
+		return ""
+	}
+	return strings.ToUpper(state[:1]) + state[1:]
+}
@@ -0,0 +1,442 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/mail"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/rabbitmq"
+	tokenutil "spectator.main/internals/util"
+)
+
+const (
+	actionConfirm     = "confirm"
+	actionUnsubscribe = "unsubscribe"
+
+	defaultRatePerMinute = 60
+
+	// At most this many opt-in messages go to one address, or are asked for
+	// from one IP address, per optInWindow.
+	optInsPerAddress = 3
+	optInsPerIP      = 20
+	optInWindow      = time.Hour
+)
+
+type subscriptionUsecase struct {
+	subscriptionRepo domain.SubscriptionRepository
+	deliveryRepo     domain.SubscriptionDeliveryRepository
+	statusPageRepo   domain.StatusPageRepository
+	notifier         rabbitmq.MQPublisher
+	contextTimeout   time.Duration
+	publicURL        string
+	linkSecret       string
+	linkExpiry       int
+
+	// The sender is a token bucket holding up to one minute of sends.
+	mu         sync.Mutex
+	rate       float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func NewSubscriptionUsecase(s domain.SubscriptionRepository, d domain.SubscriptionDeliveryRepository, p domain.StatusPageRepository, notifier rabbitmq.MQPublisher, to time.Duration, publicURL string, linkSecret string, linkExpiry int, ratePerMinute int) domain.SubscriptionUsecase {
+	if ratePerMinute <= 0 {
+		ratePerMinute = defaultRatePerMinute
+	}
+	return &subscriptionUsecase{
+		subscriptionRepo: s,
+		deliveryRepo:     d,
+		statusPageRepo:   p,
+		notifier:         notifier,
+		contextTimeout:   to,
+		publicURL:        strings.TrimSuffix(publicURL, "/"),
+		linkSecret:       linkSecret,
+		linkExpiry:       linkExpiry,
+		rate:             float64(ratePerMinute),
+		tokens:           float64(ratePerMinute),
+		lastRefill:       time.Now(),
+	}
+}
+
+func validateAddress(channel domain.NotificationChannel, address string) error {
+	switch channel {
+	case domain.ChannelEmail:
+		if _, err := mail.ParseAddress(address); err != nil {
+			return errors.New("invalid email address")
+		}
+	case domain.ChannelWebhook:
+		u, err := url.Parse(address)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook address must be an http(s) URL")
+		}
+	default:
+		return errors.New("subscriptions support email and webhook channels only")
+	}
+	return nil
+}
+
+// Subscribe records the subscription unconfirmed and queues the opt-in
+// message. Subscribing an address again only re-sends the opt-in, unless one
+// is still waiting to go out; the new component choice is applied once that
+// link is followed. Anyone may subscribe any address, so opt-ins are limited
+// per address and per requesting ip.
+func (s *subscriptionUsecase) Subscribe(c context.Context, slug string, subscription *domain.Subscription, ip string) (*domain.Subscription, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	page, err := s.statusPageRepo.FindBySlug(ctx, slug)
+	if err != nil {
+		return nil, errors.New("status page not found")
+	}
+
+	subscription.Address = strings.TrimSpace(subscription.Address)
+	if subscription.Channel == domain.ChannelEmail {
+		subscription.Address = strings.ToLower(subscription.Address)
+	}
+	if err := validateAddress(subscription.Channel, subscription.Address); err != nil {
+		return nil, err
+	}
+
+	componentIDs := []string{}
+	for _, id := range subscription.ComponentIDs {
+		found := false
+		for _, component := range page.Components {
+			if component.ID == id {
+				found = true
+			}
+		}
+		if !found {
+			return nil, errors.New("component " + id.Hex() + " is not on this status page")
+		}
+		componentIDs = append(componentIDs, id.Hex())
+	}
+
+	since := time.Now().Add(-optInWindow)
+	sent, err := s.deliveryRepo.CountOptInsTo(ctx, subscription.Address, since)
+	if err != nil {
+		return nil, err
+	}
+	if sent >= optInsPerAddress {
+		return nil, domain.ErrTooManyOptIns
+	}
+	if ip != "" {
+		sent, err = s.deliveryRepo.CountOptInsFrom(ctx, ip, since)
+		if err != nil {
+			return nil, err
+		}
+		if sent >= optInsPerIP {
+			return nil, domain.ErrTooManyOptIns
+		}
+	}
+
+	res, err := s.subscriptionRepo.FindByAddress(ctx, page.ID, subscription.Channel, subscription.Address)
+	if err == nil {
+		pending, err := s.deliveryRepo.HasPendingOptIn(ctx, res.ID)
+		if err != nil {
+			return nil, err
+		}
+		if pending {
+			return res, nil
+		}
+	} else {
+		subscription.ID = primitive.NewObjectID()
+		subscription.PageID = page.ID
+		subscription.CreatedAt = time.Now()
+		subscription.UpdatedAt = time.Now()
+		subscription.ConfirmedAt = nil
+		if subscription.ComponentIDs == nil {
+			subscription.ComponentIDs = []primitive.ObjectID{}
+		}
+		res, err = s.subscriptionRepo.InsertOne(ctx, subscription)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	token, err := tokenutil.CreateSubscriptionToken(&domain.SubscriptionClaims{
+		SubscriptionID: res.ID.Hex(),
+		Action:         actionConfirm,
+		ComponentIDs:   componentIDs,
+	}, s.linkSecret, s.linkExpiry)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := s.delivery(page, res, domain.SubscriptionConfirm)
+	delivery.RequestIP = ip
+	delivery.Subject, delivery.Body, err = confirmMessage(page, res, s.pageURL(page), s.link(page, "confirm", token))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.deliveryRepo.InsertMany(ctx, []domain.SubscriptionDelivery{delivery})
+	if err != nil {
+		return nil, err
+	}
+
+	return res, nil
+}
+
+func (s *subscriptionUsecase) parse(token string, action string) (*domain.SubscriptionClaims, error) {
+	claims, err := tokenutil.ParseSubscriptionToken(token, s.linkSecret)
+	if err != nil || claims.Action != action {
+		return nil, errors.New("invalid or expired link")
+	}
+	return claims, nil
+}
+
+func (s *subscriptionUsecase) Confirm(c context.Context, token string) (*domain.Subscription, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	claims, err := s.parse(token, actionConfirm)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.subscriptionRepo.FindOne(ctx, claims.SubscriptionID)
+	if err != nil {
+		return nil, errors.New("subscription not found")
+	}
+
+	componentIDs := []primitive.ObjectID{}
+	for _, id := range claims.ComponentIDs {
+		idHex, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, errors.New("invalid or expired link")
+		}
+		componentIDs = append(componentIDs, idHex)
+	}
+
+	err = s.subscriptionRepo.SetComponents(ctx, subscription.ID, componentIDs)
+	if err != nil {
+		return nil, err
+	}
+	subscription.ComponentIDs = componentIDs
+
+	if subscription.ConfirmedAt == nil {
+		now := time.Now()
+		err = s.subscriptionRepo.Confirm(ctx, subscription.ID, now)
+		if err != nil {
+			return nil, err
+		}
+		subscription.ConfirmedAt = &now
+	}
+
+	return subscription, nil
+}
+
+func (s *subscriptionUsecase) Unsubscribe(c context.Context, token string) (*domain.Subscription, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	claims, err := s.parse(token, actionUnsubscribe)
+	if err != nil {
+		return nil, err
+	}
+
+	subscription, err := s.subscriptionRepo.FindOne(ctx, claims.SubscriptionID)
+	if err != nil {
+		return nil, errors.New("subscription not found")
+	}
+
+	err = s.subscriptionRepo.DeleteOne(ctx, claims.SubscriptionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return subscription, nil
+}
+
+// ownedPage loads a status page on behalf of userID. Pages of other users
+// are reported as missing.
+func (s *subscriptionUsecase) ownedPage(ctx context.Context, pageID string, userID string) (*domain.StatusPage, error) {
+	page, err := s.statusPageRepo.FindOne(ctx, pageID)
+	if err != nil || page.UserID.Hex() != userID {
+		return nil, errors.New("status page not found")
+	}
+	return page, nil
+}
+
+func (s *subscriptionUsecase) GetByPageID(c context.Context, pageID string, userID string) ([]domain.Subscription, error) {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	page, err := s.ownedPage(ctx, pageID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.subscriptionRepo.GetByPageID(ctx, page.ID, false)
+}
+
+func (s *subscriptionUsecase) DeleteOne(c context.Context, pageID string, id string, userID string) error {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	_, err := s.ownedPage(ctx, pageID, userID)
+	if err != nil {
+		return err
+	}
+	subscription, err := s.subscriptionRepo.FindOne(ctx, id)
+	if err != nil || subscription.PageID.Hex() != pageID {
+		return errors.New("no subscription found with the given id")
+	}
+
+	return s.subscriptionRepo.DeleteOne(ctx, id)
+}
+
+// NotifyIncident queues a message for every confirmed subscriber of every
+// page showing the incident, limited to the components they follow.
+func (s *subscriptionUsecase) NotifyIncident(c context.Context, incident *domain.Incident, event string) error {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	pages, err := s.statusPageRepo.GetByUserID(ctx, incident.UserID.Hex())
+	if err != nil {
+		return err
+	}
+
+	deliveries := []domain.SubscriptionDelivery{}
+	for i := range pages {
+		page := &pages[i]
+		subscriptions, err := s.subscriptionRepo.GetByPageID(ctx, page.ID, true)
+		if err != nil {
+			return err
+		}
+
+		for j := range subscriptions {
+			subscription := &subscriptions[j]
+			components := subscription.Components(page, incident)
+			if len(components) == 0 {
+				continue
+			}
+
+			token, err := tokenutil.CreateSubscriptionToken(&domain.SubscriptionClaims{
+				SubscriptionID: subscription.ID.Hex(),
+				Action:         actionUnsubscribe,
+			}, s.linkSecret, 0)
+			if err != nil {
+				return err
+			}
+
+			delivery := s.delivery(page, subscription, event)
+			delivery.Subject, delivery.Body, err = incidentMessage(page, subscription, incident, event, components, s.pageURL(page), s.link(page, "unsubscribe", token))
+			if err != nil {
+				return err
+			}
+			deliveries = append(deliveries, delivery)
+		}
+	}
+
+	return s.deliveryRepo.InsertMany(ctx, deliveries)
+}
+
+// SendQueued hands as many queued deliveries to the notifier as the rate
+// allows, oldest first. Incident messages go before opt-ins, so requests
+// for opt-ins never hold up news of an incident. Whatever is left waits for
+// the next run.
+func (s *subscriptionUsecase) SendQueued(c context.Context) error {
+
+	ctx, cancel := context.WithTimeout(c, s.contextTimeout)
+	defer cancel()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.tokens += now.Sub(s.lastRefill).Minutes() * s.rate
+	if s.tokens > s.rate {
+		s.tokens = s.rate
+	}
+	s.lastRefill = now
+	if s.tokens < 1 {
+		return nil
+	}
+
+	for _, optIns := range []bool{false, true} {
+		if s.tokens < 1 {
+			break
+		}
+		deliveries, err := s.deliveryRepo.GetPending(ctx, optIns, int64(s.tokens))
+		if err != nil {
+			return err
+		}
+
+		for _, delivery := range deliveries {
+			err = s.send(ctx, &delivery)
+			if err != nil {
+				return err
+			}
+			s.tokens--
+		}
+	}
+
+	return nil
+}
+
+func (s *subscriptionUsecase) send(ctx context.Context, delivery *domain.SubscriptionDelivery) error {
+	notification := domain.Notification{
+		ID:         delivery.ID,
+		UserID:     delivery.UserID,
+		Recipient:  delivery.Recipient,
+		Subject:    delivery.Subject,
+		Body:       delivery.Body,
+		EventCount: 1,
+		CreatedAt:  delivery.CreatedAt,
+	}
+
+	notificationJson, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	err = s.notifier.Publish(notificationJson)
+	if err != nil {
+		return err
+	}
+
+	err = s.deliveryRepo.MarkSent(ctx, delivery.ID, time.Now())
+	if err != nil {
+		log.Println("subscription delivery", delivery.ID.Hex(), "sent but not marked:", err)
+	}
+
+	return nil
+}
+
+func (s *subscriptionUsecase) delivery(page *domain.StatusPage, subscription *domain.Subscription, kind string) domain.SubscriptionDelivery {
+	return domain.SubscriptionDelivery{
+		ID:             primitive.NewObjectID(),
+		SubscriptionID: subscription.ID,
+		PageID:         page.ID,
+		UserID:         page.UserID,
+		Kind:           kind,
+		Recipient: domain.Recipient{
+			Channel: subscription.Channel,
+			Address: subscription.Address,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+func (s *subscriptionUsecase) pageURL(page *domain.StatusPage) string {
+	return s.publicURL + "/status/" + page.Slug
+}
+
+func (s *subscriptionUsecase) link(page *domain.StatusPage, action string, token string) string {
+	return s.pageURL(page) + "/subscription/" + action + "?token=" + url.QueryEscape(token)
+}
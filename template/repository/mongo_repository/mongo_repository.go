@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"spectator.main/domain"
+	"spectator.main/internals/mongo"
+)
+
+type mongoRepository struct {
+	DB         mongo.Database
+	Collection mongo.Collection
+}
+
+const (
+	collectionName = "alert_template"
+)
+
+func NewMongoRepository(DB mongo.Database) domain.AlertTemplateRepository {
+	return &mongoRepository{DB, DB.Collection(collectionName)}
+}
+
+// Upsert keeps a single template per user and channel.
+func (m *mongoRepository) Upsert(ctx context.Context, template *domain.AlertTemplate) (*domain.AlertTemplate, error) {
+	var (
+		err error
+	)
+
+	filter := bson.M{"user_id": template.UserID, "channel": template.Channel}
+	update := bson.M{
+		"$set": bson.M{
+			"subject":    template.Subject,
+			"body":       template.Body,
+			"updated_at": template.UpdatedAt,
+		},
+		"$setOnInsert": bson.M{
+			"_id":        template.ID,
+			"created_at": template.CreatedAt,
+		},
+	}
+
+	_, err = m.Collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return template, err
+	}
+
+	err = m.Collection.FindOne(ctx, filter).Decode(template)
+	if err != nil {
+		return template, err
+	}
+
+	return template, nil
+}
+
+func (m *mongoRepository) FindByUserAndChannel(ctx context.Context, userID string, channel domain.NotificationChannel) (*domain.AlertTemplate, error) {
+	var (
+		template domain.AlertTemplate
+		err      error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return &template, err
+	}
+
+	err = m.Collection.FindOne(ctx, bson.M{"user_id": idHex, "channel": channel}).Decode(&template)
+	if err != nil {
+		return &template, err
+	}
+
+	return &template, nil
+}
+
+func (m *mongoRepository) GetByUserID(ctx context.Context, userID string) ([]domain.AlertTemplate, error) {
+	var (
+		templates []domain.AlertTemplate
+		err       error
+	)
+
+	idHex, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return templates, err
+	}
+
+	cursor, err := m.Collection.Find(ctx, bson.M{"user_id": idHex})
+	if err != nil {
+		return templates, err
+	}
+	if cursor == nil {
+		return templates, fmt.Errorf("nil cursor value")
+	}
+
+	err = cursor.All(ctx, &templates)
+	if err != nil {
+		return templates, err
+	}
+
+	return templates, nil
+}
+
+// DeleteOne removes the template only when userID owns it.
+func (m *mongoRepository) DeleteOne(ctx context.Context, id string, userID string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrTemplateNotFound
+	}
+	owner, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return domain.ErrTemplateNotFound
+	}
+
+	count, err := m.Collection.DeleteOne(ctx, bson.M{"_id": idHex, "user_id": owner})
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		return domain.ErrTemplateNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,117 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
+)
+
+type AlertTemplateHandler struct {
+	AlertTemplateUsecase domain.AlertTemplateUsecase
+	config               *bootstrap.Config
+}
+
+func NewAlertTemplateHandler(cfg *bootstrap.Config, r *gin.RouterGroup, tu domain.AlertTemplateUsecase) {
+	handler := &AlertTemplateHandler{
+		AlertTemplateUsecase: tu,
+		config:               cfg,
+	}
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.PUT("/template", handler.UpsertTemplate)
+	protected.GET("/templates", handler.GetTemplatesByUserID)
+	protected.DELETE("/template/:template_id", handler.DeleteTemplate)
+	protected.POST("/template/preview", handler.PreviewTemplate)
+	protected.GET("/template/sample", handler.SampleData)
+}
+
+// maxPreviewSize caps the body of a preview request, data included.
+const maxPreviewSize = 64 << 10
+
+func isRequestValid(m interface{}) (bool, error) {
+	validate := validator.New()
+	err := validate.Struct(m)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (h *AlertTemplateHandler) UpsertTemplate(c *gin.Context) {
+	var template domain.AlertTemplate
+	if err := c.ShouldBindJSON(&template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&template); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, err := primitive.ObjectIDFromHex(c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authorized"})
+		return
+	}
+	template.UserID = userID
+	res, err := h.AlertTemplateUsecase.Upsert(c, &template)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *AlertTemplateHandler) GetTemplatesByUserID(c *gin.Context) {
+	templates, err := h.AlertTemplateUsecase.GetByUserID(c, c.GetString("x-user-id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, templates)
+}
+
+func (h *AlertTemplateHandler) DeleteTemplate(c *gin.Context) {
+	err := h.AlertTemplateUsecase.DeleteOne(c, c.Param("template_id"), c.GetString("x-user-id"))
+	if errors.Is(err, domain.ErrTemplateNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Template deleted successfully"})
+}
+
+func (h *AlertTemplateHandler) PreviewTemplate(c *gin.Context) {
+	var request domain.TemplatePreviewRequest
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPreviewSize)
+	if err := c.ShouldBindJSON(&request); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if ok, err := isRequestValid(&request); !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	res, err := h.AlertTemplateUsecase.Preview(c, &request)
+	if err != nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, res)
+}
+
+func (h *AlertTemplateHandler) SampleData(c *gin.Context) {
+	c.JSON(http.StatusOK, domain.SampleAlertTemplateData())
+}
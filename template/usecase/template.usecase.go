@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/render"
+)
+
+type alertTemplateUsecase struct {
+	templateRepo   domain.AlertTemplateRepository
+	userRepo       domain.UserRepository
+	contextTimeout time.Duration
+}
+
+// defaultTemplates are used for any channel the user has not overridden.
+var defaultTemplates = map[domain.NotificationChannel]domain.AlertTemplate{
+	domain.ChannelEmail: {
+		Subject: `[Spectator] {{.Site.URL}} is {{.Status}}`,
+		Body: `<p><strong>{{.Site.URL}}</strong> ({{.Config.Name}}) is <strong>{{.Status}}</strong> since {{.OccurredAt.Format "2006-01-02 15:04:05 MST"}} ({{.Duration}}).</p>
+{{if .Error}}<p>Error: {{.Error}}</p>{{end}}
+<ul>{{range .Regions}}<li>{{.Region}}: {{if .Status}}up{{else}}down{{end}}</li>{{end}}</ul>
+{{if .IncidentLink}}<p><a href="{{.IncidentLink}}">View incident</a>{{if .AcknowledgeLink}} &middot; <a href="{{.AcknowledgeLink}}">Acknowledge</a>{{end}}{{if .SnoozeLink}} &middot; <a href="{{.SnoozeLink}}">Snooze 1h</a>{{end}}</p>{{end}}
+{{if .Events}}<p>{{len .Events}} sites changed state together:</p>
+<ul>{{range .Events}}<li>{{.Site.URL}} is {{.Status}}{{if .Error}}: {{.Error}}{{end}}</li>{{end}}</ul>{{end}}`,
+	},
+	domain.ChannelSlack: {
+		Body: `:rotating_light: *{{.Site.URL}}* ({{.Config.Name}}) is *{{.Status}}* for {{.Duration}}{{if .Error}}: {{.Error}}{{end}}{{if .IncidentLink}} <{{.IncidentLink}}|incident>{{end}}{{if .AcknowledgeLink}} <{{.AcknowledgeLink}}|acknowledge>{{end}}{{if .Events}} ({{len .Events}} sites affected){{end}}`,
+	},
+	domain.ChannelWebhook: {
+		Body: `{{.Site.URL}} is {{.Status}} for {{.Duration}}{{if .Error}}: {{.Error}}{{end}}{{if .Events}} ({{len .Events}} sites affected){{end}}`,
+	},
+}
+
+func NewAlertTemplateUsecase(t domain.AlertTemplateRepository, u domain.UserRepository, to time.Duration) domain.AlertTemplateUsecase {
+	return &alertTemplateUsecase{
+		templateRepo:   t,
+		userRepo:       u,
+		contextTimeout: to,
+	}
+}
+
+func (a *alertTemplateUsecase) Upsert(c context.Context, template *domain.AlertTemplate) (*domain.AlertTemplate, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	_, err := a.userRepo.FindOne(ctx, template.UserID.Hex())
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+
+	// Refuse templates that would fail at alert time.
+	_, err = render.Message(ctx, template.Channel, template.Subject, template.Body, domain.SampleAlertTemplateData())
+	if err != nil {
+		return nil, err
+	}
+
+	template.ID = primitive.NewObjectID()
+	template.CreatedAt = time.Now()
+	template.UpdatedAt = time.Now()
+
+	res, err := a.templateRepo.Upsert(ctx, template)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (a *alertTemplateUsecase) GetByUserID(c context.Context, userID string) ([]domain.AlertTemplate, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	res, err := a.templateRepo.GetByUserID(ctx, userID)
+	if err != nil {
+		return res, err
+	}
+
+	return res, nil
+}
+
+func (a *alertTemplateUsecase) DeleteOne(c context.Context, id string, userID string) error {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	return a.templateRepo.DeleteOne(ctx, id, userID)
+}
+
+// Preview data comes from the caller, so it is held to the shape of a real
+// alert: a grouped alert's events are never grouped themselves.
+const (
+	maxPreviewRegions = 32
+	maxPreviewEvents  = 100
+)
+
+func checkPreviewData(data *domain.AlertTemplateData, nested bool) error {
+	if len(data.Regions) > maxPreviewRegions {
+		return fmt.Errorf("preview data may have at most %d regions", maxPreviewRegions)
+	}
+	if nested && len(data.Events) > 0 {
+		return errors.New("preview events may not have events of their own")
+	}
+	if len(data.Events) > maxPreviewEvents {
+		return fmt.Errorf("preview data may have at most %d events", maxPreviewEvents)
+	}
+	for i := range data.Events {
+		err := checkPreviewData(&data.Events[i], true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *alertTemplateUsecase) Preview(c context.Context, request *domain.TemplatePreviewRequest) (*domain.TemplatePreviewResponse, error) {
+
+	data := domain.SampleAlertTemplateData()
+	if request.Data != nil {
+		err := checkPreviewData(request.Data, false)
+		if err != nil {
+			return nil, err
+		}
+		data = *request.Data
+	}
+
+	message, err := render.Message(c, request.Channel, request.Subject, request.Body, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.TemplatePreviewResponse{Data: data, Message: *message}, nil
+}
+
+// Render uses the user's override for the channel when there is one and the
+// built-in template otherwise.
+func (a *alertTemplateUsecase) Render(c context.Context, userID string, channel domain.NotificationChannel, data *domain.AlertTemplateData) (*domain.RenderedMessage, error) {
+
+	ctx, cancel := context.WithTimeout(c, a.contextTimeout)
+	defer cancel()
+
+	template, ok := defaultTemplates[channel]
+	if !ok {
+		return nil, errors.New("unknown notification channel")
+	}
+
+	override, err := a.templateRepo.FindByUserAndChannel(ctx, userID, channel)
+	if err == nil {
+		template.Body = override.Body
+		if override.Subject != "" {
+			template.Subject = override.Subject
+		}
+	}
+
+	return render.Message(ctx, channel, template.Subject, template.Body, *data)
+}
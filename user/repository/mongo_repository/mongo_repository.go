@@ -2,11 +2,13 @@ package mongorepository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"spectator.main/domain"
 	"spectator.main/internals/mongo"
@@ -32,6 +34,9 @@ func (m *mongoRepository) InsertOne(ctx context.Context, user *domain.User) (*do
 	)
 
 	_, err = m.Collection.InsertOne(ctx, user)
+	if mongodriver.IsDuplicateKeyError(err) {
+		return user, domain.ErrEmailTaken
+	}
 	if err != nil {
 		return user, err
 	}
@@ -72,6 +77,42 @@ func (m *mongoRepository) FindByEmail(ctx context.Context, email string) (*domai
 	return &user, nil
 }
 
+func (m *mongoRepository) FindByCalendarToken(ctx context.Context, token string) (*domain.User, error) {
+	var (
+		user domain.User
+		err  error
+	)
+
+	err = m.Collection.FindOne(ctx, bson.M{"calendar_token": token}).Decode(&user)
+	if err != nil {
+		return &user, err
+	}
+
+	return &user, nil
+}
+
+func (m *mongoRepository) SetCalendarToken(ctx context.Context, token string, id string) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return err
+	}
+
+	update := bson.M{"$set": bson.M{
+		"calendar_token": token,
+		"updated_at":     time.Now(),
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": idHex}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return errors.New("no user found with the given id")
+	}
+
+	return nil
+}
+
 func (m *mongoRepository) GetAllWithPage(ctx context.Context, rp int64, p int64, filter interface{}, setsort interface{}) ([]domain.User, int64, error) {
 
 	var (
@@ -113,34 +154,54 @@ func (m *mongoRepository) GetAllWithPage(ctx context.Context, rp int64, p int64,
 	return user, count, err
 }
 
-func (m *mongoRepository) UpdateOne(ctx context.Context, user *domain.User, id string) (*domain.User, error) {
+// UpdateOne sets the fields the request sends and leaves the rest alone. A
+// new email is unverified.
+func (m *mongoRepository) UpdateOne(ctx context.Context, request *domain.UpdateUserRequest, id string) (*domain.User, error) {
 	var (
-		err error
+		user domain.User
+		err  error
 	)
 
 	idHex, err := primitive.ObjectIDFromHex(id)
 	if err != nil {
-		return user, err
+		return &user, err
 	}
 
 	filter := bson.M{"_id": idHex}
-	update := bson.M{"$set": bson.M{
-		"name":       user.Name,
-		"email":      user.Email,
-		"password":   user.Password,
+	set := bson.M{
 		"updated_at": time.Now(),
-	}}
+	}
+	if request.Name != nil {
+		set["name"] = *request.Name
+	}
+	if request.Email != nil {
+		set["email"] = *request.Email
+	}
+	if request.Password != nil {
+		set["password"] = *request.Password
+	}
+	if request.TimeZone != nil {
+		set["time_zone"] = *request.TimeZone
+	}
+	update := bson.M{"$set": set}
+	if request.Email != nil {
+		// Only the email the user proved they own counts as verified.
+		update["$unset"] = bson.M{"email_verified_at": ""}
+	}
 
 	_, err = m.Collection.UpdateOne(ctx, filter, update)
+	if mongodriver.IsDuplicateKeyError(err) {
+		return &user, domain.ErrEmailTaken
+	}
 	if err != nil {
-		return user, err
+		return &user, err
 	}
 
-	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(user)
+	err = m.Collection.FindOne(ctx, bson.M{"_id": idHex}).Decode(&user)
 	if err != nil {
-		return user, err
+		return &user, err
 	}
-	return user, nil
+	return &user, nil
 }
 
 func (m *mongoRepository) GetByCredential(ctx context.Context, email string, password string) (*domain.User, error) {
@@ -161,3 +222,36 @@ func (m *mongoRepository) GetByCredential(ctx context.Context, email string, pas
 
 	return &user, nil
 }
+
+// VerifyEmail marks the user's email verified, as long as it is still the
+// email the link was sent to.
+func (m *mongoRepository) VerifyEmail(ctx context.Context, id string, email string, at time.Time) error {
+	idHex, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrInvalidVerificationLink
+	}
+
+	update := bson.M{"$set": bson.M{
+		"email_verified_at": at,
+		"updated_at":        at,
+	}}
+
+	result, err := m.Collection.UpdateOne(ctx, bson.M{"_id": idHex, "email": email}, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount == 0 {
+		return domain.ErrInvalidVerificationLink
+	}
+
+	return nil
+}
+
+// EnsureIndexes makes emails unique, which backs the check the usecase makes
+// before an email changes.
+func (m *mongoRepository) EnsureIndexes(ctx context.Context) error {
+	_, err := m.Collection.CreateIndexes(ctx, []mongodriver.IndexModel{
+		{Keys: bson.D{{Key: "email", Value: 1}}, Options: options.Index().SetUnique(true)},
+	})
+	return err
+}
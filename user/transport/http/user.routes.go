@@ -1,15 +1,14 @@
 package http
 
 import (
-	"math"
+	"errors"
 	"net/http"
-	"strconv"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
-	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
+	"golang.org/x/crypto/bcrypt"
 	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	"spectator.main/internals/middleware"
 )
 
 type ResponseError struct {
@@ -20,54 +19,35 @@ type UserHandler struct {
 	UsrUsecase domain.UserUsecase
 }
 
-func NewUserHandler(r *gin.RouterGroup, uu domain.UserUsecase, ) {
+func NewUserHandler(cfg *bootstrap.Config, r *gin.RouterGroup, uu domain.UserUsecase) {
 	handler := &UserHandler{
 		UsrUsecase: uu,
 	}
-	r.POST("/user", handler.InsertOne)
-	r.GET("/user", handler.FindOne)
-	r.GET("/users", handler.GetAll)
-	r.PUT("/user", handler.UpdateOne)
-}
+	r.GET("/user/verify-email", handler.VerifyEmail)
 
-func isRequestValid(m *domain.User) (bool, error) {
-	validate := validator.New()
-	err := validate.Struct(m)
-	if err != nil {
-		return false, err
-	}
-	return true, nil
+	protected := r.Group("", middleware.JwtAuthMiddleware(cfg.AccessTokenSecret))
+	protected.GET("/user", handler.FindOne)
+	protected.PUT("/user", handler.UpdateOne)
+	protected.POST("/user/verify-email", handler.SendVerification)
 }
 
-func (user *UserHandler) InsertOne(ctx *gin.Context) {
-	var (
-		usr domain.User
-	)
-
-	err := ctx.Bind(&usr)
-	if err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, err.Error())
-		return
-	}
-
-	var ok bool
-	if ok, err = isRequestValid(&usr); !ok {
-		ctx.JSON(http.StatusBadRequest, err.Error())
-		return
+// self returns the caller's own ID. Users may only read and change their
+// own account; asking for anyone else's is reported as not found.
+func self(ctx *gin.Context) (string, bool) {
+	userID := ctx.GetString("x-user-id")
+	if id, ok := ctx.GetQuery("id"); ok && id != userID {
+		ctx.JSON(http.StatusNotFound, ResponseError{Message: "user not found"})
+		return "", false
 	}
-
-	result, err := user.UsrUsecase.InsertOne(ctx, &usr)
-	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	ctx.JSON(http.StatusOK, result)
+	return userID, true
 }
 
 func (user *UserHandler) FindOne(ctx *gin.Context) {
 
-	id, _ := ctx.GetQuery("id")
+	id, ok := self(ctx)
+	if !ok {
+		return
+	}
 
 	result, err := user.UsrUsecase.FindOne(ctx, id)
 	if err != nil {
@@ -78,76 +58,69 @@ func (user *UserHandler) FindOne(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, result)
 }
 
-func (user *UserHandler) GetAll(ctx *gin.Context) {
+func (user *UserHandler) UpdateOne(ctx *gin.Context) {
 
-	type Response struct {
-		Total       int64         `json:"total"`
-		PerPage     int64         `json:"per_page"`
-		CurrentPage int64         `json:"current_page"`
-		LastPage    int64         `json:"last_page"`
-		From        int64         `json:"from"`
-		To          int64         `json:"to"`
-		User        []domain.User `json:"users"`
+	id, ok := self(ctx)
+	if !ok {
+		return
 	}
 
 	var (
-		res   []domain.User
-		count int64
+		request domain.UpdateUserRequest
+		err     error
 	)
-	rp_ctx, _ := ctx.GetQuery("rp")
-	rp, err := strconv.ParseInt(rp_ctx, 10, 64)
-	if err != nil {
-		rp = 25
-	}
 
-	p_ctx, _ := ctx.GetQuery("p")
-	page, err := strconv.ParseInt(p_ctx, 10, 64)
+	err = ctx.Bind(&request)
 	if err != nil {
-		page = 1
+		ctx.JSON(http.StatusUnprocessableEntity, err.Error())
+		return
 	}
 
-	name_ctx, _ := ctx.GetQuery("name")
-	filters := bson.D{{Key: "name", Value: primitive.Regex{Pattern: ".*" + name_ctx + ".*", Options: "i"}}}
+	if request.Password != nil {
+		encryptedPassword, err := bcrypt.GenerateFromPassword([]byte(*request.Password), bcrypt.DefaultCost)
+		if err != nil {
+			ctx.JSON(http.StatusInternalServerError, err.Error())
+			return
+		}
+		hash := string(encryptedPassword)
+		request.Password = &hash
+	}
 
-	res, count, err = user.UsrUsecase.GetAllWithPage(ctx, rp, page, filters, nil)
+	result, err := user.UsrUsecase.UpdateOne(ctx, &request, id)
+	if errors.Is(err, domain.ErrEmailTaken) {
+		ctx.JSON(http.StatusConflict, ResponseError{Message: err.Error()})
+		return
+	}
 	if err != nil {
 		ctx.JSON(http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	result := Response{
-		Total:       count,
-		PerPage:     rp,
-		CurrentPage: page,
-		LastPage:    int64(math.Ceil(float64(count) / float64(rp))),
-		From:        page*rp - rp + 1,
-		To:          page * rp,
-		User:        res,
-	}
-
 	ctx.JSON(http.StatusOK, result)
 }
 
-func (user *UserHandler) UpdateOne(ctx *gin.Context) {
-
-	id, _ := ctx.GetQuery("id")
-
-	var (
-		usr domain.User
-		err error
-	)
-
-	err = ctx.Bind(&usr)
+// SendVerification mails the caller a new link to verify their email.
+func (user *UserHandler) SendVerification(ctx *gin.Context) {
+	err := user.UsrUsecase.SendVerification(ctx, ctx.GetString("x-user-id"))
 	if err != nil {
-		ctx.JSON(http.StatusUnprocessableEntity, err.Error())
+		ctx.JSON(http.StatusInternalServerError, ResponseError{Message: err.Error()})
 		return
 	}
 
-	result, err := user.UsrUsecase.UpdateOne(ctx, &usr, id)
+	ctx.JSON(http.StatusAccepted, gin.H{"message": "Verification email sent"})
+}
+
+// VerifyEmail is where the link in a verification email leads.
+func (user *UserHandler) VerifyEmail(ctx *gin.Context) {
+	result, err := user.UsrUsecase.VerifyEmail(ctx, ctx.Query("token"))
+	if errors.Is(err, domain.ErrInvalidVerificationLink) {
+		ctx.JSON(http.StatusBadRequest, ResponseError{Message: err.Error()})
+		return
+	}
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, err.Error())
+		ctx.JSON(http.StatusInternalServerError, ResponseError{Message: err.Error()})
 		return
 	}
 
-	ctx.JSON(http.StatusOK, result)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Email verified", "email": result.Email})
 }
@@ -0,0 +1,151 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"spectator.main/domain"
+	"spectator.main/internals/bootstrap"
+	tokenutil "spectator.main/internals/util"
+	_userHandler "spectator.main/user/transport/http"
+)
+
+const secret = "test-secret"
+
+type userUsecase struct {
+	domain.UserUsecase
+	users map[string]domain.User
+}
+
+func (u *userUsecase) FindOne(ctx context.Context, id string) (*domain.User, error) {
+	user := u.users[id]
+	return &user, nil
+}
+
+func (u *userUsecase) UpdateOne(ctx context.Context, request *domain.UpdateUserRequest, id string) (*domain.User, error) {
+	user := u.users[id]
+	if request.Email != nil {
+		for other, taken := range u.users {
+			if other != id && taken.Email == *request.Email {
+				return nil, domain.ErrEmailTaken
+			}
+		}
+		user.Email = *request.Email
+	}
+	if request.Name != nil {
+		user.Name = *request.Name
+	}
+	u.users[id] = user
+	return &user, nil
+}
+
+func setup(t *testing.T) (*gin.Engine, *userUsecase, domain.User, string) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	user := domain.User{ID: primitive.NewObjectID(), Name: "Ada", Email: "ada@example.com", Password: "$2a$10$hash"}
+	usecase := &userUsecase{users: map[string]domain.User{user.ID.Hex(): user}}
+	router := gin.New()
+	_userHandler.NewUserHandler(&bootstrap.Config{AccessTokenSecret: secret}, router.Group("api/v1"), usecase)
+
+	token, err := tokenutil.CreateAccessToken(&user, secret, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return router, usecase, user, token
+}
+
+func serve(router *gin.Engine, method string, path string, body string, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	res := httptest.NewRecorder()
+	router.ServeHTTP(res, req)
+	return res
+}
+
+func TestOtherUserIsNotFound(t *testing.T) {
+	router, usecase, user, token := setup(t)
+	other := primitive.NewObjectID().Hex()
+
+	res := serve(router, http.MethodGet, "/api/v1/user?id="+other, "", token)
+	if res.Code != http.StatusNotFound {
+		t.Errorf("GET: got %d, want %d", res.Code, http.StatusNotFound)
+	}
+
+	res = serve(router, http.MethodPut, "/api/v1/user?id="+other, `{"name":"Mallory"}`, token)
+	if res.Code != http.StatusNotFound {
+		t.Errorf("PUT: got %d, want %d", res.Code, http.StatusNotFound)
+	}
+	if _, ok := usecase.users[other]; ok {
+		t.Error("PUT changed another user's account")
+	}
+	if usecase.users[user.ID.Hex()].Name != user.Name {
+		t.Error("PUT for another user changed the caller's account")
+	}
+}
+
+func TestOwnAccountHidesPassword(t *testing.T) {
+	router, _, user, token := setup(t)
+
+	res := serve(router, http.MethodGet, "/api/v1/user?id="+user.ID.Hex(), "", token)
+	if res.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d", res.Code, http.StatusOK)
+	}
+	var body map[string]interface{}
+	err := json.Unmarshal(res.Body.Bytes(), &body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := body["password"]; ok {
+		t.Error("the response carries the password")
+	}
+}
+
+func TestUserListingIsGone(t *testing.T) {
+	router, _, _, token := setup(t)
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		path := "/api/v1/users"
+		if method == http.MethodPost {
+			path = "/api/v1/user"
+		}
+		res := serve(router, method, path, `{}`, token)
+		if res.Code != http.StatusNotFound && res.Code != http.StatusMethodNotAllowed {
+			t.Errorf("%s %s: got %d, want it unrouted", method, path, res.Code)
+		}
+	}
+}
+
+func TestEmailOfAnotherUserIsRefused(t *testing.T) {
+	router, usecase, user, token := setup(t)
+	other := domain.User{ID: primitive.NewObjectID(), Name: "Grace", Email: "grace@example.com"}
+	usecase.users[other.ID.Hex()] = other
+
+	res := serve(router, http.MethodPut, "/api/v1/user", `{"email":"grace@example.com"}`, token)
+	if res.Code != http.StatusConflict {
+		t.Errorf("got %d, want %d", res.Code, http.StatusConflict)
+	}
+	if usecase.users[user.ID.Hex()].Email != user.Email {
+		t.Error("the caller's email changed")
+	}
+}
+
+func TestUnsentFieldsAreKept(t *testing.T) {
+	router, usecase, user, token := setup(t)
+
+	res := serve(router, http.MethodPut, "/api/v1/user", `{"name":"Ada Lovelace"}`, token)
+	if res.Code != http.StatusOK {
+		t.Fatalf("got %d, want %d: %s", res.Code, http.StatusOK, res.Body)
+	}
+	got := usecase.users[user.ID.Hex()]
+	if got.Name != "Ada Lovelace" || got.Email != user.Email || got.Password != user.Password {
+		t.Errorf("got %+v, want only the name changed", got)
+	}
+}
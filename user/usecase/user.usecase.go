@@ -2,21 +2,41 @@ package usecase
 
 import (
 	"context"
+	"encoding/json"
+	"net/url"
+	"strings"
 	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"spectator.main/domain"
+	"spectator.main/internals/rabbitmq"
+	tokenutil "spectator.main/internals/util"
 )
 
+// defaultLinkExpiry is how many hours a verification link stays valid when
+// no link expiry is configured.
+const defaultLinkExpiry = 24
+
 type userUsecase struct {
 	userRepo       domain.UserRepository
+	notifier       rabbitmq.MQPublisher
 	contextTimeout time.Duration
+	publicURL      string
+	linkSecret     string
+	linkExpiry     int
 }
 
-func NewUserUsecase(u domain.UserRepository, to time.Duration) domain.UserUsecase {
+func NewUserUsecase(u domain.UserRepository, notifier rabbitmq.MQPublisher, to time.Duration, publicURL string, linkSecret string, linkExpiry int) domain.UserUsecase {
+	if linkExpiry <= 0 {
+		linkExpiry = defaultLinkExpiry
+	}
 	return &userUsecase{
 		userRepo:       u,
+		notifier:       notifier,
 		contextTimeout: to,
+		publicURL:      strings.TrimSuffix(publicURL, "/"),
+		linkSecret:     linkSecret,
+		linkExpiry:     linkExpiry,
 	}
 }
 
@@ -63,15 +83,109 @@ func (user *userUsecase) GetAllWithPage(c context.Context, rp int64, p int64, fi
 	return res, count, nil
 }
 
-func (user *userUsecase) UpdateOne(c context.Context, m *domain.User, id string) (*domain.User, error) {
+// UpdateOne changes the fields the request sends. An email another account
+// already has is refused with ErrEmailTaken. A new email has to be verified
+// again, so a link is mailed to it.
+func (user *userUsecase) UpdateOne(c context.Context, request *domain.UpdateUserRequest, id string) (*domain.User, error) {
 
 	ctx, cancel := context.WithTimeout(c, user.contextTimeout)
 	defer cancel()
 
-	res, err := user.userRepo.UpdateOne(ctx, m, id)
+	if request.Email != nil {
+		current, err := user.userRepo.FindOne(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if *request.Email == current.Email {
+			request.Email = nil
+		}
+	}
+	if request.Email != nil {
+		other, err := user.userRepo.FindByEmail(ctx, *request.Email)
+		if err == nil && other.ID.Hex() != id {
+			return nil, domain.ErrEmailTaken
+		}
+	}
+
+	res, err := user.userRepo.UpdateOne(ctx, request, id)
 	if err != nil {
 		return res, err
 	}
 
+	if request.Email != nil {
+		err = user.sendVerification(res)
+		if err != nil {
+			return res, err
+		}
+	}
+
 	return res, nil
 }
+
+// SendVerification mails the user a link to verify their current email.
+func (user *userUsecase) SendVerification(c context.Context, id string) error {
+
+	ctx, cancel := context.WithTimeout(c, user.contextTimeout)
+	defer cancel()
+
+	res, err := user.userRepo.FindOne(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return user.sendVerification(res)
+}
+
+func (user *userUsecase) sendVerification(u *domain.User) error {
+	token, err := tokenutil.CreateEmailVerificationToken(&domain.EmailVerificationClaims{
+		UserID: u.ID.Hex(),
+		Email:  u.Email,
+	}, user.linkSecret, user.linkExpiry)
+	if err != nil {
+		return err
+	}
+
+	userID := u.ID
+	link := user.publicURL + "/api/v1/user/verify-email?token=" + url.QueryEscape(token)
+	notification := domain.Notification{
+		ID:     primitive.NewObjectID(),
+		UserID: u.ID,
+		Recipient: domain.Recipient{
+			Channel: domain.ChannelEmail,
+			UserID:  &userID,
+			Name:    u.Name,
+			Address: u.Email,
+		},
+		Subject:    "Verify your Spectator email",
+		Body:       "Follow this link to verify " + u.Email + " for your Spectator account:\n\n" + link + "\n\nIf you did not ask for this, ignore this message.",
+		EventCount: 1,
+		CreatedAt:  time.Now(),
+	}
+
+	notificationJson, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	return user.notifier.Publish(notificationJson)
+}
+
+// VerifyEmail marks the email a verification link was sent to verified,
+// unless the account has changed its email since.
+func (user *userUsecase) VerifyEmail(c context.Context, token string) (*domain.User, error) {
+
+	ctx, cancel := context.WithTimeout(c, user.contextTimeout)
+	defer cancel()
+
+	claims, err := tokenutil.ParseEmailVerificationToken(token, user.linkSecret)
+	if err != nil {
+		return nil, domain.ErrInvalidVerificationLink
+	}
+
+	err = user.userRepo.VerifyEmail(ctx, claims.UserID, claims.Email, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return user.userRepo.FindOne(ctx, claims.UserID)
+}